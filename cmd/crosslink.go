@@ -0,0 +1,58 @@
+// Package cmd Crosslink top level command
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+	"philosopher/lib/xlk"
+
+	"github.com/spf13/cobra"
+)
+
+// crosslinkCmd represents the crosslink command
+var crosslinkCmd = &cobra.Command{
+	Use:   "crosslink",
+	Short: "Crosslinked peptide identification import and FDR",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		m.FunctionInitCheckUp()
+
+		if len(m.Crosslink.Csv) == 0 && len(m.Crosslink.Mzid) == 0 {
+			msg.InputNotFound(errors.New("Provide a crosslink CSV or mzIdentML file"), "fatal")
+		}
+
+		msg.Executing("Crosslink ", Version)
+
+		m = xlk.Run(m)
+
+		// store parameters on meta data
+		m.Serialize()
+
+		// clean tmp
+		met.CleanTemp(m.Temp)
+
+		msg.Done()
+		return
+	},
+}
+
+func init() {
+
+	if len(os.Args) > 1 && os.Args[1] == "crosslink" {
+
+		m.Restore(sys.Meta())
+
+		crosslinkCmd.Flags().StringVarP(&m.Crosslink.Csv, "csv", "", "", "comma or tab separated file with the crosslinked spectrum matches")
+		crosslinkCmd.Flags().StringVarP(&m.Crosslink.Mzid, "mzid", "", "", "mzIdentML 1.2 file with the crosslinked spectrum matches")
+		crosslinkCmd.Flags().StringVarP(&m.Crosslink.Tag, "tag", "", "", "decoy tag, the database tag is used when not informed")
+		crosslinkCmd.Flags().StringVarP(&m.Crosslink.Score, "score", "", "", "name or accession of the mzIdentML score, the first score is used when not informed")
+		crosslinkCmd.Flags().Float64VarP(&m.Crosslink.FDR, "fdr", "", 0.01, "FDR for the crosslinked spectrum matches and residue pairs")
+		crosslinkCmd.Flags().BoolVarP(&m.Crosslink.LowScore, "lowscore", "", false, "lower scores are better, such as e-values")
+	}
+
+	RootCmd.AddCommand(crosslinkCmd)
+}
@@ -38,14 +38,30 @@ func init() {
 
 		databaseCmd.Flags().StringVarP(&m.Database.ID, "id", "", "", "UniProt proteome ID")
 		databaseCmd.Flags().StringVarP(&m.Database.Annot, "annotate", "", "", "process a ready-to-use database")
-		databaseCmd.Flags().StringVarP(&m.Database.Enz, "enzyme", "", "trypsin", "enzyme for digestion (trypsin, lys_c, lys_n, glu_c, chymotrypsin)")
+		databaseCmd.Flags().StringVarP(&m.Database.Enz, "enzyme", "", "trypsin", "enzyme for digestion (trypsin, trypsin/p, lys_c, lys_c/p, lys_n, arg_c, asp_n, glu_c, chymotrypsin, pepsin, proteinase_k, nonspecific)")
+		databaseCmd.Flags().StringVarP(&m.Database.EnzymeRules, "enzymerules", "", "", "YAML file with custom enzyme rules")
 		databaseCmd.Flags().StringVarP(&m.Database.Tag, "prefix", "", "rev_", "define a decoy prefix")
+		databaseCmd.Flags().StringVarP(&m.Database.Decoy, "decoy", "", "reverse", "decoy generation method (reverse, pseudo, shuffle, debruijn)")
+		databaseCmd.Flags().Int64VarP(&m.Database.Seed, "seed", "", 1, "random seed for the shuffled decoys")
 		databaseCmd.Flags().StringVarP(&m.Database.Add, "add", "", "", "add custom sequences (UniProt FASTA format only)")
+		databaseCmd.Flags().StringVarP(&m.Database.Variants, "variants", "", "", "add variant sequences from a tab-separated table (accession, position, reference, alternative)")
 		databaseCmd.Flags().StringVarP(&m.Database.Custom, "custom", "", "", "use a pre-formatted custom database")
+		databaseCmd.Flags().StringVarP(&m.Database.Translate, "translate", "", "", "translate a nucleotide FASTA file into the protein database")
+		databaseCmd.Flags().StringVarP(&m.Database.Frames, "translation", "", "6frame", "translation mode for nucleotide sequences (3frame, 6frame, orf)")
+		databaseCmd.Flags().IntVarP(&m.Database.GeneticCode, "geneticcode", "", 1, "NCBI genetic code table used for translation (1, 2, 3, 4, 5, 6, 9, 10, 11, 12, 13, 14)")
+		databaseCmd.Flags().IntVarP(&m.Database.MinORF, "minorf", "", 30, "minimum length of the translated sequences")
 		databaseCmd.Flags().BoolVarP(&m.Database.Crap, "contam", "", false, "add common contaminants")
 		databaseCmd.Flags().BoolVarP(&m.Database.Rev, "reviewed", "", false, "use only reviwed sequences from Swiss-Prot")
 		databaseCmd.Flags().BoolVarP(&m.Database.Iso, "isoform", "", false, "add isoform sequences")
 		databaseCmd.Flags().BoolVarP(&m.Database.NoD, "nodecoys", "", false, "don't add decoys to the database")
+		databaseCmd.Flags().BoolVarP(&m.Database.Cache, "cache", "", false, "reuse a cached copy of the proteome when available")
+		databaseCmd.Flags().BoolVarP(&m.Database.List, "list", "", false, "list the cached databases")
+		databaseCmd.Flags().StringVarP(&m.Database.CacheDir, "cachedir", "", "", "location of the database cache (default: ~/.philosopher/databases)")
+		databaseCmd.Flags().StringVarP(&m.Database.URL, "url", "", dat.DefaultURL, "address used to fetch the proteomes")
+		databaseCmd.Flags().StringVarP(&m.Database.Header.ID, "idregex", "", "", "regular expression capturing the protein ID from custom headers")
+		databaseCmd.Flags().StringVarP(&m.Database.Header.Gene, "generegex", "", "", "regular expression capturing the gene name from custom headers")
+		databaseCmd.Flags().StringVarP(&m.Database.Header.Description, "descregex", "", "", "regular expression capturing the description from custom headers")
+		databaseCmd.Flags().StringVarP(&m.Database.Header.Organism, "orgregex", "", "", "regular expression capturing the organism from custom headers")
 	}
 
 	RootCmd.AddCommand(databaseCmd)
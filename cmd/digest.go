@@ -0,0 +1,50 @@
+// Package cmd Digest top level command
+package cmd
+
+import (
+	"os"
+
+	"philosopher/lib/dig"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+
+	"github.com/spf13/cobra"
+)
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "In-silico digestion and peptide index",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		m.FunctionInitCheckUp()
+
+		msg.Executing("Digest ", Version)
+
+		m = dig.Run(m)
+
+		// store parameters on meta data
+		m.Serialize()
+
+		msg.Done()
+		return
+	},
+}
+
+func init() {
+
+	if len(os.Args) > 1 && os.Args[1] == "digest" {
+
+		m.Restore(sys.Meta())
+
+		digestCmd.Flags().StringVarP(&m.Digest.Enzyme, "enzyme", "", "", "enzyme for digestion (default: the database enzyme)")
+		digestCmd.Flags().StringVarP(&m.Database.EnzymeRules, "enzymerules", "", m.Database.EnzymeRules, "YAML file with custom enzyme rules")
+		digestCmd.Flags().StringVarP(&m.Digest.Specificity, "specificity", "", "specific", "digestion specificity (specific, semi, nonspecific)")
+		digestCmd.Flags().IntVarP(&m.Digest.MissedCleavages, "missed", "", 2, "maximum number of missed cleavages")
+		digestCmd.Flags().IntVarP(&m.Digest.MinLength, "minlength", "", 7, "minimum peptide length")
+		digestCmd.Flags().IntVarP(&m.Digest.MaxLength, "maxlength", "", 50, "maximum peptide length")
+		digestCmd.Flags().Float64VarP(&m.Digest.MinMass, "minmass", "", 500, "minimum peptide mass")
+		digestCmd.Flags().Float64VarP(&m.Digest.MaxMass, "maxmass", "", 5000, "maximum peptide mass")
+	}
+
+	RootCmd.AddCommand(digestCmd)
+}
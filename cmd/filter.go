@@ -31,6 +31,7 @@ var filterCmd = &cobra.Command{
 		os.RemoveAll(sys.EvPSMBin())
 		os.RemoveAll(sys.EvPeptideBin())
 		os.RemoveAll(sys.EvProteinBin())
+		os.RemoveAll(sys.EvGeneBin())
 		os.RemoveAll(sys.PsmBin())
 		os.RemoveAll(sys.IonBin())
 		os.RemoveAll(sys.PepBin())
@@ -39,8 +40,8 @@ var filterCmd = &cobra.Command{
 		os.RemoveAll(sys.ProtxmlBin())
 
 		// check file existence
-		if len(m.Filter.Pex) < 1 {
-			msg.InputNotFound(errors.New("You must provide a pepXML file or a folder with one or more files, Run 'philosopher filter --help' for more information"), "fatal")
+		if len(m.Filter.Pex) < 1 && len(m.Filter.Mzid) < 1 && len(m.Filter.Tsv) < 1 && len(m.Filter.Combine) < 1 {
+			msg.InputNotFound(errors.New("You must provide a pepXML, mzIdentML or MSFragger TSV file or a folder with one or more files, Run 'philosopher filter --help' for more information"), "fatal")
 		}
 
 		if len(m.Filter.Pox) == 0 && m.Filter.Razor == true {
@@ -48,6 +49,11 @@ var filterCmd = &cobra.Command{
 			m.Filter.Razor = false
 		}
 
+		if len(m.Filter.Pox) == 0 && m.Filter.Inference == false && m.Filter.GeneFDR > 0 {
+			msg.Custom(errors.New("Gene FDR will be ignored because there is no protein inference data"), "warning")
+			m.Filter.GeneFDR = 0
+		}
+
 		m := fil.Run(m)
 
 		m.Serialize()
@@ -68,12 +74,18 @@ func init() {
 
 		filterCmd.Flags().StringVarP(&m.Filter.Pex, "pepxml", "", "", "pepXML file or directory containing a set of pepXML files")
 		filterCmd.Flags().StringVarP(&m.Filter.Pox, "protxml", "", "", "protXML file path")
+		filterCmd.Flags().StringVarP(&m.Filter.Mzid, "mzid", "", "", "mzIdentML file or directory containing a set of mzIdentML files")
+		filterCmd.Flags().StringVarP(&m.Filter.Tsv, "tsv", "", "", "MSFragger TSV file or directory containing a set of MSFragger TSV files")
+		filterCmd.Flags().StringVarP(&m.Filter.Combine, "combine", "", "", "combine pepXML sets from different search engines, informed as engine=path pairs separated by commas")
 		filterCmd.Flags().StringVarP(&m.Filter.Tag, "tag", "", "rev_", "decoy tag")
 		filterCmd.Flags().StringVarP(&m.Filter.Mods, "mods", "", "", "list of modifications for a stratified FDR filtering")
+		filterCmd.Flags().IntVarP(&m.Filter.Ranks, "ranks", "", 1, "maximum search hit rank kept for each spectrum, lower ranked peptides are accepted as chimeric PSMs")
 		filterCmd.Flags().Float64VarP(&m.Filter.IonFDR, "ion", "", 0.01, "peptide ion FDR level")
 		filterCmd.Flags().Float64VarP(&m.Filter.PepFDR, "pep", "", 0.01, "peptide FDR level")
+		filterCmd.Flags().Float64VarP(&m.Filter.ModPepFDR, "modpep", "", 0.01, "modified peptide FDR level")
 		filterCmd.Flags().Float64VarP(&m.Filter.PsmFDR, "psm", "", 0.01, "psm FDR level")
 		filterCmd.Flags().Float64VarP(&m.Filter.PtFDR, "prot", "", 0.01, "protein FDR level")
+		filterCmd.Flags().Float64VarP(&m.Filter.GeneFDR, "gene", "", 0, "gene FDR level, genes are only reported when the level is informed")
 		filterCmd.Flags().Float64VarP(&m.Filter.PepProb, "pepProb", "", 0.7, "top peptide probability threshold for the FDR filtering")
 		filterCmd.Flags().Float64VarP(&m.Filter.ProtProb, "protProb", "", 0.5, "protein probability threshold for the FDR filtering (not used with the razor algorithm)")
 		filterCmd.Flags().Float64VarP(&m.Filter.Weight, "weight", "", 1, "threshold for defining peptide uniqueness")
@@ -83,6 +95,10 @@ func init() {
 		filterCmd.Flags().BoolVarP(&m.Filter.Razor, "razor", "", false, "use razor peptides for protein FDR scoring")
 		filterCmd.Flags().BoolVarP(&m.Filter.Picked, "picked", "", false, "apply the picked FDR algorithm before the protein scoring")
 		filterCmd.Flags().BoolVarP(&m.Filter.Mapmods, "mapmods", "", false, "map modifications")
+		filterCmd.Flags().StringVarP(&m.Filter.Glycan, "glycan", "", "", "glycan composition database used to annotate N-glycopeptides")
+		filterCmd.Flags().StringVarP(&m.Filter.GlycoDir, "glycospectra", "", "", "directory with the mzML files used to check the oxonium ions of glycopeptides")
+		filterCmd.Flags().Float64VarP(&m.Filter.GlycanTol, "glycantol", "", 20, "glycan composition and oxonium ion tolerance in ppm")
+		filterCmd.Flags().BoolVarP(&m.Filter.Bayes, "bayes", "", false, "score the proteins from the native inference with a Bayesian model and apply the protein FDR on the posteriors")
 		filterCmd.Flags().BoolVarP(&m.Filter.Inference, "inference", "", false, "extremely fast and efficient protein inference compatible with 2D and Sequential filters")
 		filterCmd.Flags().BoolVarP(&m.Filter.Fo, "fo", "", false, "")
 		filterCmd.Flags().MarkHidden("fo")
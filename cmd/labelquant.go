@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"philosopher/lib/met"
+	"philosopher/lib/mod"
 	"philosopher/lib/msg"
 	"philosopher/lib/qua"
 	"philosopher/lib/sys"
@@ -43,7 +44,7 @@ var labelquantCmd = &cobra.Command{
 			msg.InputNotFound(errors.New("Unknown file format"), "fatal")
 		}
 
-		m.Quantify = qua.RunIsobaricLabelQuantification(m.Quantify, m.Filter.Mapmods)
+		m.Quantify = qua.RunIsobaricLabelQuantification(m.Quantify, m.Filter.Mapmods, mod.PTMProphetKeys(m.PTMProphet.Mods))
 
 		// store parameters on meta data
 		m.Serialize()
@@ -65,6 +65,7 @@ func init() {
 		proprophCmd.Flags().IntVarP(&m.ProteinProphet.Mufactor, "mufactor", "", 1, "fudge factor to scale MU calculation")
 		proprophCmd.Flags().BoolVarP(&m.ProteinProphet.Unmapped, "unmapped", "", false, "report results for UNMAPPED proteins")
 		proprophCmd.Flags().StringVarP(&m.ProteinProphet.Output, "output", "", "interact", "Output name")
+		proprophCmd.Flags().BoolVarP(&m.ProteinProphet.Native, "native", "", false, "use the native protein validation instead of the ProteinProphet binary")
 		proprophCmd.Flags().MarkHidden("accuracy")
 		proprophCmd.Flags().MarkHidden("allpeps")
 		proprophCmd.Flags().MarkHidden("confem")
@@ -6,6 +6,7 @@ import (
 
 	"philosopher/lib/met"
 	"philosopher/lib/msg"
+	"philosopher/lib/qcm"
 	"philosopher/lib/rep"
 	"philosopher/lib/sys"
 
@@ -24,6 +25,10 @@ var reportCmd = &cobra.Command{
 
 		rep.Run(m)
 
+		if m.Report.QC == true {
+			qcm.Run()
+		}
+
 		// store parameters on meta data
 		m.Serialize()
 
@@ -44,6 +49,7 @@ func init() {
 		reportCmd.Flags().BoolVarP(&m.Report.Decoys, "decoys", "", false, "add decoy observations to reports")
 		reportCmd.Flags().BoolVarP(&m.Report.MSstats, "msstats", "", false, "create an output compatible with MSstats")
 		reportCmd.Flags().BoolVarP(&m.Report.MZID, "mzid", "", false, "create a mzID output")
+		reportCmd.Flags().BoolVarP(&m.Report.QC, "qc", "", false, "create the labeling efficiency and digestion quality control reports")
 	}
 
 	RootCmd.AddCommand(reportCmd)
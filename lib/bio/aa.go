@@ -36,9 +36,9 @@ func New(name string) AminoAcid {
 	case "Cysteine":
 		aa = AminoAcid{Code: "C", ShortName: "Cys", Name: "Cysteine", MonoIsotopeMass: 103.009184505, AverageMass: 103.1429}
 	case "Glutamine":
-		aa = AminoAcid{Code: "E", ShortName: "Glu", Name: "Glutamine", MonoIsotopeMass: 129.042593135, AverageMass: 129.11398}
+		aa = AminoAcid{Code: "Q", ShortName: "Gln", Name: "Glutamine", MonoIsotopeMass: 128.058577540, AverageMass: 128.12922}
 	case "Glutamic Acid":
-		aa = AminoAcid{Code: "Q", ShortName: "Gln", Name: "Glutamic Acid", MonoIsotopeMass: 128.058577540, AverageMass: 128.12922}
+		aa = AminoAcid{Code: "E", ShortName: "Glu", Name: "Glutamic Acid", MonoIsotopeMass: 129.042593135, AverageMass: 129.11398}
 	case "Glycine":
 		aa = AminoAcid{Code: "G", ShortName: "Gly", Name: "Glycine", MonoIsotopeMass: 57.021463735, AverageMass: 57.05132}
 	case "Histidine":
@@ -72,3 +72,42 @@ func New(name string) AminoAcid {
 
 	return aa
 }
+
+// aminoAcidNames maps the one-letter codes to the amino acid names
+var aminoAcidNames = map[string]string{
+	"A": "Alanine",
+	"R": "Arginine",
+	"N": "Asparagine",
+	"D": "Aspartic Acid",
+	"C": "Cysteine",
+	"Q": "Glutamine",
+	"E": "Glutamic Acid",
+	"G": "Glycine",
+	"H": "Histidine",
+	"I": "Isoleucine",
+	"L": "Leucine",
+	"K": "Lysine",
+	"M": "Methionine",
+	"F": "Phenylalanine",
+	"P": "Proline",
+	"S": "Serine",
+	"T": "Threonine",
+	"W": "Tryptophan",
+	"Y": "Tyrosine",
+	"V": "Valine",
+}
+
+// aminoAcidCodes holds the amino acid information indexed by the one-letter code
+var aminoAcidCodes = func() map[string]AminoAcid {
+	codes := make(map[string]AminoAcid, len(aminoAcidNames))
+	for k, v := range aminoAcidNames {
+		codes[k] = New(v)
+	}
+	return codes
+}()
+
+// NewFromCode return the correct information for the given one-letter amino acid code,
+// unknown codes return an empty amino acid
+func NewFromCode(code string) AminoAcid {
+	return aminoAcidCodes[code]
+}
@@ -28,3 +28,52 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFromCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want AminoAcid
+	}{
+		{
+			name: "Testing Alanine",
+			code: "A",
+			want: AminoAcid{Code: "A", ShortName: "Ala", Name: "Alanine", MonoIsotopeMass: 71.037113805, AverageMass: 71.0779},
+		},
+		{
+			name: "Testing unknown code",
+			code: "X",
+			want: AminoAcid{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewFromCode(tt.code); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewFromCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGlutamine(t *testing.T) {
+	tests := []struct {
+		name string
+		want AminoAcid
+	}{
+		{
+			name: "Glutamine",
+			want: AminoAcid{Code: "Q", ShortName: "Gln", Name: "Glutamine", MonoIsotopeMass: 128.058577540, AverageMass: 128.12922},
+		},
+		{
+			name: "Glutamic Acid",
+			want: AminoAcid{Code: "E", ShortName: "Glu", Name: "Glutamic Acid", MonoIsotopeMass: 129.042593135, AverageMass: 129.11398},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package bio_test
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	. "philosopher/lib/bio"
 	"philosopher/lib/tes"
+	"reflect"
 	"testing"
 )
 
@@ -145,3 +148,59 @@ func TestEnzymes(t *testing.T) {
 		t.Errorf("Enzyme is incorrect, got %s, want %s", e.Name, "glu_c")
 	}
 }
+
+func TestEnzyme_Sites(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		enzyme string
+		seq    string
+		want   []int
+	}{
+		{name: "Testing trypsin proline rule", enzyme: "trypsin", seq: "AAKPBBRCC", want: []int{0, 7, 9}},
+		{name: "Testing trypsin/p", enzyme: "Trypsin/P", seq: "AAKPBBRCC", want: []int{0, 3, 7, 9}},
+		{name: "Testing N-terminal cleavage", enzyme: "asp_n", seq: "AADBBDCC", want: []int{0, 2, 5, 8}},
+		{name: "Testing non-specific", enzyme: "nonspecific", seq: "AAKBB", want: []int{0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := LookupEnzyme(tt.enzyme)
+			if !ok {
+				t.Fatalf("LookupEnzyme() %s not found", tt.enzyme)
+			}
+			if got := e.Sites(tt.seq); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sites() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEnzymes(t *testing.T) {
+
+	f := filepath.Join(t.TempDir(), "enzymes.yml")
+
+	rules := "enzymes:\n  - name: Custom-Enz\n    cut: m\n    restrict: p\n    specificity: Semi\n"
+	if e := ioutil.WriteFile(f, []byte(rules), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	enzymes := LoadEnzymes(f)
+
+	e, ok := enzymes.Lookup("custom_enz")
+	if !ok {
+		t.Fatalf("Lookup() custom rule not found")
+	}
+
+	if e.Cut != "M" || e.Sense != "C" || e.Specificity != Semi || e.Pattern != "M[^P]" {
+		t.Errorf("LoadEnzymes() got = %+v, want a semi-specific C-terminal rule on M", e)
+	}
+
+	if _, ok := enzymes.Lookup("trypsin"); !ok {
+		t.Errorf("LoadEnzymes() standard enzymes not found")
+	}
+
+	// the standard registry is not changed by the custom rules
+	if _, ok := LookupEnzyme("custom_enz"); ok {
+		t.Errorf("LookupEnzyme() custom rule found on the standard registry")
+	}
+}
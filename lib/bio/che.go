@@ -3,4 +3,13 @@ package bio
 const (
 	// Proton mass
 	Proton = 1.007276467
+
+	// Hydrogen monoisotopic mass
+	Hydrogen = 1.007825035
+
+	// Water monoisotopic mass
+	Water = 18.010564684
+
+	// C13 is the mass difference between the carbon isotopes
+	C13 = 1.003354835
 )
@@ -2,44 +2,231 @@ package bio
 
 import (
 	"errors"
+	"io/ioutil"
+	"sort"
 	"strings"
 
 	"philosopher/lib/msg"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Enzyme digestion specificities
+const (
+	Specific    = "specific"
+	Semi        = "semi"
+	NonSpecific = "nonspecific"
 )
 
 // Enzyme struct
 type Enzyme struct {
-	Name    string
-	Pattern string
-	Join    string
+	Name        string `yaml:"name"`
+	Pattern     string `yaml:"-"`
+	Join        string `yaml:"-"`
+	Cut         string `yaml:"cut"`
+	Restrict    string `yaml:"restrict"`
+	Sense       string `yaml:"sense"`
+	Specificity string `yaml:"specificity"`
+	TPP         string `yaml:"tpp"`
+}
+
+// EnzymeRules is the layout of the custom enzyme rules file
+type EnzymeRules struct {
+	Enzymes []Enzyme `yaml:"enzymes"`
 }
 
-// Synth is an enzyme builder
+// EnzymeRegistry holds the known enzymes by their normalized name
+type EnzymeRegistry map[string]Enzyme
+
+// registry holds the standard enzymes
+var registry = EnzymeRegistry{
+	"trypsin":      {Name: "trypsin", Cut: "KR", Restrict: "P", Sense: "C", TPP: "trypsin"},
+	"trypsin/p":    {Name: "trypsin/p", Cut: "KR", Sense: "C", TPP: "stricttrypsin"},
+	"lys_c":        {Name: "lys_c", Cut: "K", Restrict: "P", Sense: "C", TPP: "lysc"},
+	"lys_c/p":      {Name: "lys_c/p", Cut: "K", Sense: "C", TPP: "lysc-p"},
+	"lys_n":        {Name: "lys_n", Cut: "K", Sense: "N", TPP: "lysn"},
+	"arg_c":        {Name: "arg_c", Cut: "R", Restrict: "P", Sense: "C", TPP: "argc"},
+	"asp_n":        {Name: "asp_n", Cut: "D", Sense: "N", TPP: "aspn"},
+	"glu_c":        {Name: "glu_c", Cut: "DE", Restrict: "P", Sense: "C", TPP: "gluc"},
+	"chymotrypsin": {Name: "chymotrypsin", Cut: "FWYL", Restrict: "P", Sense: "C", TPP: "chymotrypsin"},
+	"pepsin":       {Name: "pepsin", Cut: "FL", Sense: "C", TPP: "pepsina"},
+	"proteinase_k": {Name: "proteinase_k", Cut: "AEFILTVWY", Sense: "C", TPP: "nonspecific"},
+	"nonspecific":  {Name: "nonspecific", Sense: "C", Specificity: NonSpecific, TPP: "nonspecific"},
+}
+
+// Synth is an enzyme builder using the standard enzymes
 func (e *Enzyme) Synth(t string) {
 
-	if strings.EqualFold(strings.ToLower(t), "trypsin") {
-		e.Name = "trypsin"
-		e.Pattern = "KR[^P]"
-		e.Join = "KR"
-	} else if strings.EqualFold(strings.ToLower(t), "lys_c") {
-		e.Name = "lys_c"
-		e.Pattern = "K[^P]"
-		e.Join = "K"
-	} else if strings.EqualFold(strings.ToLower(t), "lys_n") {
-		e.Name = "lys_n"
-		e.Pattern = "K"
-		e.Join = "K"
-	} else if strings.EqualFold(strings.ToLower(t), "chymotrypsin") {
-		e.Name = "chymotrypsin"
-		e.Pattern = "FWYL[^P]"
-		e.Join = "K"
-	} else if strings.EqualFold(strings.ToLower(t), "glu_c") {
-		e.Name = "glu_c"
-		e.Pattern = "DE[^P]"
-		e.Join = "K"
-	} else {
+	enz, ok := registry.Lookup(t)
+	if !ok {
 		msg.Custom(errors.New("Enzyme not supported"), "warning")
+		return
 	}
 
+	*e = enz
+
 	return
 }
+
+// Enzyme returns the enzyme registered with the given name, unknown names return an empty rule
+func (r EnzymeRegistry) Enzyme(t string) Enzyme {
+
+	enz, ok := r.Lookup(t)
+	if !ok {
+		msg.Custom(errors.New("Enzyme not supported"), "warning")
+	}
+
+	return enz
+}
+
+// Lookup returns the enzyme rule registered with the given name
+func (r EnzymeRegistry) Lookup(t string) (Enzyme, bool) {
+
+	enz, ok := r[normalizeEnzymeName(t)]
+	if !ok {
+		return enz, false
+	}
+
+	enz.complete()
+
+	return enz, true
+}
+
+// LookupEnzyme returns the standard enzyme rule registered with the given name
+func LookupEnzyme(t string) (Enzyme, bool) {
+	return registry.Lookup(t)
+}
+
+// Enzymes lists the names of the registered enzymes
+func Enzymes() []string {
+
+	var names []string
+	for k := range registry {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Registry returns the standard enzymes extended with the rules from the given YAML file, the
+// standard enzymes are returned when no file is informed
+func Registry(f string) EnzymeRegistry {
+
+	if len(f) == 0 {
+		return registry
+	}
+
+	return LoadEnzymes(f)
+}
+
+// LoadEnzymes reads the rules from a YAML file and returns a new registry with the standard enzymes
+// and the custom rules, custom rules replace the standard enzymes with the same name
+func LoadEnzymes(f string) EnzymeRegistry {
+
+	b, e := ioutil.ReadFile(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	var rules EnzymeRules
+
+	e = yaml.Unmarshal(b, &rules)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	var enzymes = make(EnzymeRegistry)
+	for k, v := range registry {
+		enzymes[k] = v
+	}
+
+	for _, i := range rules.Enzymes {
+
+		if len(i.Name) == 0 {
+			msg.Custom(errors.New("Enzyme rules need a name"), "fatal")
+		}
+
+		i.Name = normalizeEnzymeName(i.Name)
+		i.Cut = strings.ToUpper(i.Cut)
+		i.Restrict = strings.ToUpper(i.Restrict)
+		i.Sense = strings.ToUpper(i.Sense)
+		i.Specificity = strings.ToLower(i.Specificity)
+
+		if len(i.Sense) == 0 {
+			i.Sense = "C"
+		}
+
+		if i.Sense != "C" && i.Sense != "N" {
+			msg.Custom(errors.New("Enzyme sense must be C or N"), "fatal")
+		}
+
+		if len(i.Specificity) > 0 && i.Specificity != Specific && i.Specificity != Semi && i.Specificity != NonSpecific {
+			msg.Custom(errors.New("Enzyme specificity must be specific, semi or nonspecific"), "fatal")
+		}
+
+		if len(i.Cut) == 0 && len(i.Specificity) == 0 {
+			i.Specificity = NonSpecific
+		}
+
+		if len(i.TPP) == 0 {
+			i.TPP = i.Name
+		}
+
+		enzymes[i.Name] = i
+	}
+
+	return enzymes
+}
+
+// Sites returns the peptide boundaries on a sequence, including both protein termini
+func (e Enzyme) Sites(seq string) []int {
+
+	var sites = []int{0}
+
+	for i := range seq {
+
+		if !strings.ContainsRune(e.Cut, rune(seq[i])) {
+			continue
+		}
+
+		if e.Sense == "N" {
+			if i > 0 && !strings.ContainsRune(e.Restrict, rune(seq[i-1])) {
+				sites = append(sites, i)
+			}
+			continue
+		}
+
+		if i == len(seq)-1 || strings.ContainsRune(e.Restrict, rune(seq[i+1])) {
+			continue
+		}
+
+		sites = append(sites, i+1)
+	}
+
+	sites = append(sites, len(seq))
+
+	return sites
+}
+
+// complete fills the derived fields of a rule
+func (e *Enzyme) complete() {
+
+	if len(e.Specificity) == 0 {
+		e.Specificity = Specific
+	}
+
+	e.Join = e.Cut
+	e.Pattern = e.Cut
+	if len(e.Restrict) > 0 {
+		e.Pattern = e.Cut + "[^" + e.Restrict + "]"
+	}
+
+	return
+}
+
+// normalizeEnzymeName makes the enzyme names case insensitive and accepts dashes as separators
+func normalizeEnzymeName(t string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(t)), "-", "_", -1)
+}
@@ -0,0 +1,178 @@
+package dat
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultURL is the UniProt address used to download proteomes
+const DefaultURL = "http://www.uniprot.org/uniprot/"
+
+// CacheEntry is the provenance of a downloaded database
+type CacheEntry struct {
+	ID       string `yaml:"id"`
+	File     string `yaml:"file"`
+	Hash     string `yaml:"sha256"`
+	Date     string `yaml:"date"`
+	Reviewed bool   `yaml:"reviewed"`
+	Isoforms bool   `yaml:"isoforms"`
+	URL      string `yaml:"url"`
+}
+
+// Cache is the local collection of downloaded databases
+type Cache struct {
+	Dir     string       `yaml:"-"`
+	Entries []CacheEntry `yaml:"databases"`
+}
+
+// NewCache loads the cache index from the given directory, the default location is
+// the .philosopher folder on the user's home
+func NewCache(dir string) Cache {
+
+	var c Cache
+
+	if len(dir) == 0 {
+		home, e := os.UserHomeDir()
+		if e != nil {
+			msg.Custom(errors.New("Cannot locate the user home directory for the database cache"), "fatal")
+		}
+		dir = filepath.Join(home, ".philosopher", "databases")
+	}
+
+	c.Dir = dir
+
+	b, e := ioutil.ReadFile(c.index())
+	if e != nil {
+		return c
+	}
+
+	e = yaml.Unmarshal(b, &c)
+	if e != nil {
+		msg.ReadFile(e, "warning")
+	}
+
+	return c
+}
+
+// Find returns the most recent cached download for the proteome and query options
+func (c Cache) Find(id string, rev, iso bool) (CacheEntry, bool) {
+
+	var found CacheEntry
+	var ok bool
+
+	for _, i := range c.Entries {
+		if i.ID == id && i.Reviewed == rev && i.Isoforms == iso && i.Date >= found.Date {
+			if _, e := os.Stat(filepath.Join(c.Dir, i.File)); e == nil {
+				found = i
+				ok = true
+			}
+		}
+	}
+
+	return found, ok
+}
+
+// Store copies a downloaded database to the cache and records its provenance
+func (c *Cache) Store(file, id, url string, rev, iso bool) CacheEntry {
+
+	e := os.MkdirAll(c.Dir, sys.FilePermission())
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	var entry CacheEntry
+
+	entry.ID = id
+	entry.Hash = FileHash(file)
+	entry.Date = time.Now().Format("2006.01.02 15:04:05")
+	entry.Reviewed = rev
+	entry.Isoforms = iso
+	entry.URL = url
+	entry.File = fmt.Sprintf("%s-%s.fas", id, entry.Hash[:12])
+
+	sys.CopyFile(file, filepath.Join(c.Dir, entry.File))
+
+	// the same content is only listed once
+	var entries []CacheEntry
+	for _, i := range c.Entries {
+		if i.File != entry.File {
+			entries = append(entries, i)
+		}
+	}
+	c.Entries = append(entries, entry)
+
+	b, e := yaml.Marshal(c)
+	if e != nil {
+		msg.MarshalFile(e, "fatal")
+	}
+
+	e = ioutil.WriteFile(c.index(), b, sys.FilePermission())
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	return entry
+}
+
+// Path returns the location of a cached database
+func (c Cache) Path(entry CacheEntry) string {
+	return filepath.Join(c.Dir, entry.File)
+}
+
+// List prints the cached databases
+func (c Cache) List() {
+
+	if len(c.Entries) == 0 {
+		logrus.Info("No databases cached on ", c.Dir)
+		return
+	}
+
+	entries := c.Entries
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ID == entries[j].ID {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	fmt.Printf("%-14s %-20s %-9s %-9s %-64s %s\n", "Proteome", "Date", "Reviewed", "Isoforms", "SHA-256", "File")
+	for _, i := range entries {
+		fmt.Printf("%-14s %-20s %-9t %-9t %-64s %s\n", i.ID, i.Date, i.Reviewed, i.Isoforms, i.Hash, i.File)
+	}
+
+	return
+}
+
+func (c Cache) index() string {
+	return filepath.Join(c.Dir, "index.yml")
+}
+
+// FileHash returns the SHA-256 checksum of a file
+func FileHash(f string) string {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, e := io.Copy(h, file); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -0,0 +1,56 @@
+package dat
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+
+	fasta := ">sp|P00001|TEST_HUMAN Test protein OS=Homo sapiens GN=TST PE=1 SV=1\nPEPTIDEK\n"
+
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.RawQuery, "proteome:UP000000001") {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, fasta)
+	}))
+	defer stub.Close()
+
+	temp, _ := ioutil.TempDir("", "philosopher-cache")
+	defer os.RemoveAll(temp)
+
+	var d = New()
+	d.URL = stub.URL + "/"
+	d.Fetch("UP000000001", temp, false, true)
+
+	c := NewCache(temp + string(os.PathSeparator) + "cache")
+	entry := c.Store(d.UniProtDB, "UP000000001", d.URL, true, false)
+
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte(fasta))); entry.Hash != want {
+		t.Errorf("Store() hash = %v, want %v", entry.Hash, want)
+	}
+
+	reloaded := NewCache(c.Dir)
+
+	found, ok := reloaded.Find("UP000000001", true, false)
+	if !ok || found.Hash != entry.Hash {
+		t.Fatalf("Find() = %v %v, want the stored entry", found, ok)
+	}
+
+	if _, ok := reloaded.Find("UP000000001", false, false); ok {
+		t.Errorf("Find() matched an entry with different query options")
+	}
+
+	b, _ := ioutil.ReadFile(reloaded.Path(found))
+	if string(b) != fasta || FileHash(reloaded.Path(found)) != entry.Hash {
+		t.Errorf("Store() cached content does not match the download")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"philosopher/lib/bio"
 	"philosopher/lib/msg"
 
 	"philosopher/lib/fas"
@@ -27,10 +28,12 @@ import (
 // Base main structure
 type Base struct {
 	FileName  string
+	URL       string
 	UniProtDB string
 	CrapDB    string
 	TaDeDB    map[string]string
 	Records   []Record
+	template  *headerTemplate
 }
 
 // New constructor
@@ -49,7 +52,20 @@ func Run(m met.Data) met.Data {
 
 	var db = New()
 
-	if len(m.Database.ID) == 0 && (len(m.Database.Annot) == 0 || m.Database.Annot == "--contam" || m.Database.Annot == "--prefix") && (len(m.Database.Custom) == 0 || m.Database.Custom == "--contam" || m.Database.Custom == "--prefix") {
+	if m.Database.List {
+		NewCache(m.Database.CacheDir).List()
+		return m
+	}
+
+	db.UseHeaderTemplate(m.Database.Header)
+
+	// the decoy method is stored normalized so the workspace description matches the generated decoys
+	m.Database.Decoy = strings.ToLower(m.Database.Decoy)
+	if len(m.Database.Decoy) == 0 {
+		m.Database.Decoy = Reverse
+	}
+
+	if len(m.Database.ID) == 0 && (len(m.Database.Annot) == 0 || m.Database.Annot == "--contam" || m.Database.Annot == "--prefix") && (len(m.Database.Custom) == 0 || m.Database.Custom == "--contam" || m.Database.Custom == "--prefix") && len(m.Database.Translate) == 0 {
 		msg.InputNotFound(errors.New("Provide a protein FASTA file or Proteome ID"), "fatal")
 	}
 
@@ -59,12 +75,23 @@ func Run(m met.Data) met.Data {
 
 		db.ProcessDB(m.Database.Annot, m.Database.Tag)
 
+		m.Database.Hash = FileHash(m.Database.Annot)
+
 		db.Serialize()
 
 		return m
 	}
 
-	if len(m.Database.ID) < 1 && len(m.Database.Custom) < 1 {
+	// the translated sequences are kept apart so the custom database is not replaced
+	var translated string
+	if len(m.Database.Translate) > 0 {
+
+		logrus.Info("Translating nucleotide sequences")
+
+		translated = TranslateFile(m.Database.Translate, m.Temp, m.Database.Frames, m.Database.GeneticCode, m.Database.MinORF)
+	}
+
+	if len(m.Database.ID) < 1 && len(m.Database.Custom) < 1 && len(translated) < 1 {
 		msg.InputNotFound(errors.New("You need to provide a taxon ID or a custom FASTA file"), "fatal")
 	}
 
@@ -72,21 +99,54 @@ func Run(m met.Data) met.Data {
 		msg.Custom(errors.New("Contaminants are not going to be added to database"), "warning")
 	}
 
-	if len(m.Database.Custom) < 1 {
+	if len(m.Database.Custom) < 1 && len(translated) < 1 {
+
+		cache := NewCache(m.Database.CacheDir)
+		entry, ok := cache.Find(m.Database.ID, m.Database.Rev, m.Database.Iso)
 
-		logrus.Info("Fetching database")
+		if m.Database.Cache && ok {
 
-		currentTime := time.Now()
-		m.Database.TimeStamp = fmt.Sprintf("%s", currentTime.Format("2006.01.02 15:04:05"))
+			logrus.Info("Using the cached database from ", entry.Date)
 
-		db.Fetch(m.Database.ID, m.Temp, m.Database.Iso, m.Database.Rev)
+			db.UniProtDB = fmt.Sprintf("%s%s%s.fas", m.Temp, string(filepath.Separator), m.Database.ID)
+			sys.CopyFile(cache.Path(entry), db.UniProtDB)
 
+		} else {
+
+			logrus.Info("Fetching database")
+
+			db.URL = m.Database.URL
+			db.Fetch(m.Database.ID, m.Temp, m.Database.Iso, m.Database.Rev)
+
+			// the downloaded database is only kept in the cache when it was requested
+			if m.Database.Cache {
+				entry = cache.Store(db.UniProtDB, m.Database.ID, db.URL, m.Database.Rev, m.Database.Iso)
+			} else {
+				entry.Date = time.Now().Format("2006.01.02 15:04:05")
+				entry.Hash = FileHash(db.UniProtDB)
+			}
+		}
+
+		m.Database.TimeStamp = entry.Date
+		m.Database.Hash = entry.Hash
+
+	} else if len(translated) > 0 && len(m.Database.Custom) > 0 {
+		db.UniProtDB = MergeFASTA(fmt.Sprintf("%s%scustom.translated.fas", m.Temp, string(filepath.Separator)), m.Database.Custom, translated)
+		m.Database.Hash = FileHash(db.UniProtDB)
+	} else if len(translated) > 0 {
+		db.UniProtDB = translated
+		m.Database.Hash = FileHash(m.Database.Translate)
 	} else {
 		db.UniProtDB = m.Database.Custom
+		m.Database.Hash = FileHash(m.Database.Custom)
 	}
 
+	logrus.Info("Database checksum (SHA-256) ", m.Database.Hash)
+
+	enzyme := bio.Registry(m.Database.EnzymeRules).Enzyme(m.Database.Enz)
+
 	logrus.Info("Processing decoys")
-	db.Create(m.Temp, m.Database.Add, m.Database.Enz, m.Database.Tag, m.Database.Crap, m.Database.NoD)
+	db.Create(m.Temp, m.Database.Add, m.Database.Variants, enzyme, m.Database.Tag, m.Database.Decoy, m.Database.Seed, m.Database.Crap, m.Database.NoD)
 
 	logrus.Info("Creating file")
 	customDB := db.Save(m.Home, m.Temp, m.Database.Tag, m.Database.Rev, m.Database.Iso, m.Database.NoD, m.Database.Crap)
@@ -94,7 +154,7 @@ func Run(m met.Data) met.Data {
 	db.ProcessDB(customDB, m.Database.Tag)
 
 	logrus.Info("Processing decoys")
-	db.Create(m.Temp, m.Database.Add, m.Database.Enz, m.Database.Tag, m.Database.Crap, m.Database.NoD)
+	db.Create(m.Temp, m.Database.Add, m.Database.Variants, enzyme, m.Database.Tag, m.Database.Decoy, m.Database.Seed, m.Database.Crap, m.Database.NoD)
 
 	logrus.Info("Creating file")
 	db.Save(m.Home, m.Temp, m.Database.Tag, m.Database.Rev, m.Database.Iso, m.Database.NoD, m.Database.Crap)
@@ -112,39 +172,55 @@ func (d *Base) ProcessDB(file, decoyTag string) {
 
 	for k, v := range fastaMap {
 
-		class := Classify(k, decoyTag)
-
-		if class == "uniprot" {
-
-			db := ProcessUniProtKB(k, v, decoyTag)
-			d.Records = append(d.Records, db)
-
-		} else if class == "ncbi" {
-
-			db := ProcessNCBI(k, v, decoyTag)
-			d.Records = append(d.Records, db)
-
-		} else if class == "ensembl" {
+		d.Records = append(d.Records, d.processRecord(k, v, decoyTag))
+	}
 
-			db := ProcessENSEMBL(k, v, decoyTag)
-			d.Records = append(d.Records, db)
+	return
+}
 
-		} else if class == "generic" {
+// processRecord parses a FASTA entry with the template or with the parser for its header format
+func (d *Base) processRecord(k, v, decoyTag string) Record {
 
-			db := ProcessGeneric(k, v, decoyTag)
-			d.Records = append(d.Records, db)
+	if strings.HasPrefix(strings.TrimPrefix(k, decoyTag), "var|") {
+		return d.processVariant(k, v, decoyTag)
+	}
 
-		} else if class == "uniref" {
+	class := Classify(k, decoyTag)
 
-			db := ProcessUniRef(k, v, decoyTag)
-			d.Records = append(d.Records, db)
+	// the template is only used for the headers the standard parsers do not recognize
+	if class == "generic" && d.template.matches(k) {
+		return processTemplate(k, v, decoyTag, d.template)
+	}
 
-		} else {
-			msg.ParsingFASTA(errors.New(""), "fatal")
-		}
+	if class == "uniprot" {
+		return ProcessUniProtKB(k, v, decoyTag)
+	} else if class == "ncbi" {
+		return ProcessNCBI(k, v, decoyTag)
+	} else if class == "ensembl" {
+		return ProcessENSEMBL(k, v, decoyTag)
+	} else if class == "generic" {
+		return ProcessGeneric(k, v, decoyTag)
+	} else if class == "uniref" {
+		return ProcessUniRef(k, v, decoyTag)
+	} else if class == "refseq" {
+		return ProcessRefSeq(k, v, decoyTag)
+	} else if class == "gencode" {
+		return ProcessGENCODE(k, v, decoyTag)
+	} else if class == "ensemblfull" {
+		return ProcessEnsemblFull(k, v, decoyTag)
+	} else if class == "tair" {
+		return ProcessTAIR(k, v, decoyTag)
+	} else if class == "sgd" {
+		return ProcessSGD(k, v, decoyTag)
+	} else if class == "flybase" {
+		return ProcessFlyBase(k, v, decoyTag)
+	} else if class == "orf" {
+		return ProcessORF(k, v, decoyTag)
 	}
 
-	return
+	msg.ParsingFASTA(errors.New(""), "fatal")
+
+	return Record{}
 }
 
 // Fetch downloads a database file from UniProt
@@ -154,10 +230,14 @@ func (d *Base) Fetch(id, temp string, iso, rev bool) {
 
 	d.UniProtDB = fmt.Sprintf("%s%s%s.fas", temp, string(filepath.Separator), id)
 
+	if len(d.URL) == 0 {
+		d.URL = DefaultURL
+	}
+
 	if rev == true {
-		query = fmt.Sprintf("%s%s%s", "http://www.uniprot.org/uniprot/?query=reviewed:yes+AND+proteome:", id, "&format=fasta")
+		query = fmt.Sprintf("%s%s%s%s", d.URL, "?query=reviewed:yes+AND+proteome:", id, "&format=fasta")
 	} else {
-		query = fmt.Sprintf("%s%s%s", "http://www.uniprot.org/uniprot/?query=proteome:", id, "&format=fasta")
+		query = fmt.Sprintf("%s%s%s%s", d.URL, "?query=proteome:", id, "&format=fasta")
 	}
 
 	if iso == true {
@@ -179,7 +259,7 @@ func (d *Base) Fetch(id, temp string, iso, rev bool) {
 		msg.Custom(errors.New("UniProt query failed, please check your connection"), "fatal")
 	}
 
-	if response.ContentLength != -1 {
+	if response.StatusCode != http.StatusOK || response.ContentLength == 0 {
 		msg.Custom(errors.New("No sequences downloaded, check your proteome ID and parameters"), "fatal")
 	}
 	defer response.Body.Close()
@@ -194,7 +274,7 @@ func (d *Base) Fetch(id, temp string, iso, rev bool) {
 }
 
 // Create processes the given fasta file and add decoy sequences
-func (d *Base) Create(temp, add, enz, tag string, crap, noD bool) {
+func (d *Base) Create(temp, add, variants string, enz bio.Enzyme, tag, decoy string, seed int64, crap, noD bool) {
 
 	d.TaDeDB = make(map[string]string)
 
@@ -229,14 +309,33 @@ func (d *Base) Create(temp, add, enz, tag string, crap, noD bool) {
 
 	}
 
-	for h, s := range db {
+	// variants are added before the decoys so they get their own decoy entries
+	if len(variants) > 0 {
+		d.addVariants(db, variants, tag)
+	}
+
+	// sorted headers keep the seeded decoys reproducible
+	var headers []string
+	for h := range db {
+		headers = append(headers, h)
+	}
+	sort.Strings(headers)
+
+	builder := newDecoyBuilder(decoy, enz, seed)
+	for _, h := range headers {
+		builder.addTargets(db[h])
+	}
+
+	for _, h := range headers {
+
+		s := db[h]
 
 		th := ">" + h
 		d.TaDeDB[th] = s
 
 		if noD == false {
 			dh := ">" + tag + h
-			d.TaDeDB[dh] = reverseSeq(s)
+			d.TaDeDB[dh] = builder.build(s)
 		}
 
 	}
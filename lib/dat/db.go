@@ -25,6 +25,9 @@ type Record struct {
 	Length           int
 	IsDecoy          bool
 	IsContaminant    bool
+	Variant          string
+	VariantStart     int
+	VariantEnd       int
 }
 
 // ProcessENSEMBL parses ENSEMBL like FASTA records
@@ -416,14 +419,32 @@ func Classify(s, decoyTag string) string {
 	seq := strings.Replace(s, decoyTag, "", -1)
 	seq = strings.Replace(seq, "con_", "", -1)
 
-	if strings.HasPrefix(seq, "sp|") || strings.HasPrefix(seq, "tr|") || strings.HasPrefix(seq, "db|") {
+	if strings.HasPrefix(seq, "var|") {
+		return "variant"
+	} else if strings.HasPrefix(seq, "orf|") {
+		return "orf"
+	} else if strings.HasPrefix(seq, "sp|") || strings.HasPrefix(seq, "tr|") || strings.HasPrefix(seq, "db|") {
 		return "uniprot"
 	} else if strings.HasPrefix(seq, "AP_") || strings.HasPrefix(seq, "NP_") || strings.HasPrefix(seq, "YP_") || strings.HasPrefix(seq, "XP_") || strings.HasPrefix(seq, "ZP") || strings.HasPrefix(seq, "WP_") {
+		if !strings.Contains(seq, "GN=") && strings.HasSuffix(strings.TrimSpace(seq), "]") {
+			return "refseq"
+		}
 		return "ncbi"
 	} else if strings.HasPrefix(seq, "ENSP") {
+		if strings.Contains(strings.Split(seq, " ")[0], "|") {
+			return "gencode"
+		} else if strings.Contains(seq, " gene_symbol:") || strings.Contains(seq, " description:") {
+			return "ensemblfull"
+		}
 		return "ensembl"
 	} else if strings.HasPrefix(seq, "UniRef") {
 		return "uniref"
+	} else if tairReg.MatchString(seq) {
+		return "tair"
+	} else if sgdReg.MatchString(seq) {
+		return "sgd"
+	} else if flybaseReg.MatchString(seq) {
+		return "flybase"
 	}
 
 	return "generic"
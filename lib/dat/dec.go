@@ -0,0 +1,229 @@
+package dat
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/msg"
+)
+
+// Decoy generation methods
+const (
+	Reverse       = "reverse"
+	PseudoReverse = "pseudo"
+	Shuffle       = "shuffle"
+	DeBruijn      = "debruijn"
+)
+
+// maxDecoyAttempts is the number of times a shuffled peptide is redone when it matches a target peptide
+const maxDecoyAttempts = 10
+
+// segment is a digested peptide split between the residues that can be moved and the cleavage site
+type segment struct {
+	body string
+	site string
+}
+
+// decoyBuilder creates decoy sequences that keep the enzyme cleavage sites in place
+type decoyBuilder struct {
+	method  string
+	enzyme  bio.Enzyme
+	rand    *rand.Rand
+	targets map[string]uint8
+}
+
+// newDecoyBuilder is the constructor for the decoy builder
+func newDecoyBuilder(method string, enz bio.Enzyme, seed int64) decoyBuilder {
+
+	var b decoyBuilder
+
+	b.method = method
+	if len(b.method) == 0 {
+		b.method = Reverse
+	}
+
+	if b.method != Reverse && b.method != PseudoReverse && b.method != Shuffle && b.method != DeBruijn {
+		msg.Custom(errors.New("Decoy method not supported, use reverse, pseudo, shuffle or debruijn"), "fatal")
+	}
+
+	b.enzyme = enz
+	b.rand = rand.New(rand.NewSource(seed))
+	b.targets = make(map[string]uint8)
+
+	return b
+}
+
+// addTargets registers the target peptides, shuffled decoys matching them are discarded
+func (b *decoyBuilder) addTargets(seq string) {
+
+	for _, i := range splitSites(seq, b.enzyme) {
+		b.targets[i.peptide(b.enzyme)] = 0
+	}
+
+	return
+}
+
+// build returns the decoy version of a protein sequence
+func (b *decoyBuilder) build(seq string) string {
+
+	if b.method == Reverse {
+		return reverseSeq(seq)
+	}
+
+	var decoy strings.Builder
+
+	for _, i := range splitSites(seq, b.enzyme) {
+
+		var body string
+
+		switch b.method {
+		case PseudoReverse:
+			body = reverseSeq(i.body)
+		case Shuffle:
+			body = b.retry(i, b.shuffle)
+		case DeBruijn:
+			body = b.retry(i, b.deBruijn)
+		}
+
+		decoy.WriteString(segment{body: body, site: i.site}.peptide(b.enzyme))
+	}
+
+	return decoy.String()
+}
+
+// retry applies the shuffling function until the decoy peptide differs from all target peptides,
+// the reversed peptide is used when no valid shuffle is found
+func (b *decoyBuilder) retry(s segment, shuffle func(string) string) string {
+
+	if len(s.body) < 2 {
+		return s.body
+	}
+
+	for i := 0; i < maxDecoyAttempts; i++ {
+		body := shuffle(s.body)
+		if _, ok := b.targets[segment{body: body, site: s.site}.peptide(b.enzyme)]; !ok {
+			return body
+		}
+	}
+
+	return reverseSeq(s.body)
+}
+
+// shuffle randomly permutes the residues
+func (b *decoyBuilder) shuffle(s string) string {
+
+	r := []rune(s)
+	b.rand.Shuffle(len(r), func(i, j int) {
+		r[i], r[j] = r[j], r[i]
+	})
+
+	return string(r)
+}
+
+// deBruijn walks a random Eulerian path on the de Bruijn graph of the residues, keeping the
+// dipeptide composition of the original sequence
+func (b *decoyBuilder) deBruijn(s string) string {
+
+	r := []rune(s)
+
+	var edges = make(map[rune][]rune)
+	for i := 0; i < len(r)-1; i++ {
+		edges[r[i]] = append(edges[r[i]], r[i+1])
+	}
+
+	for _, v := range edges {
+		b.rand.Shuffle(len(v), func(i, j int) {
+			v[i], v[j] = v[j], v[i]
+		})
+	}
+
+	// Hierholzer's algorithm starting from the first residue
+	var path []rune
+	var stack = []rune{r[0]}
+
+	for len(stack) > 0 {
+
+		v := stack[len(stack)-1]
+
+		if len(edges[v]) > 0 {
+			next := edges[v][len(edges[v])-1]
+			edges[v] = edges[v][:len(edges[v])-1]
+			stack = append(stack, next)
+		} else {
+			path = append(path, v)
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return reverseSeq(string(path))
+}
+
+// peptide assembles the segment with the cleavage site on the enzyme side
+func (s segment) peptide(e bio.Enzyme) string {
+
+	if e.Sense == "N" {
+		return s.site + s.body
+	}
+
+	return s.body + s.site
+}
+
+// splitSites breaks a protein sequence after (or before) every cleavage residue, residues followed
+// (or preceded) by a restricted residue are not cleavage sites
+func splitSites(seq string, e bio.Enzyme) []segment {
+
+	var segments []segment
+	var body strings.Builder
+
+	r := []rune(seq)
+
+	for x, i := range r {
+
+		if !isCleavageSite(r, x, e) {
+			body.WriteRune(i)
+			continue
+		}
+
+		if e.Sense == "N" {
+			if len(segments) == 0 {
+				segments = append(segments, segment{body: body.String()})
+			} else {
+				segments[len(segments)-1].body = body.String()
+			}
+			segments = append(segments, segment{site: string(i)})
+		} else {
+			segments = append(segments, segment{body: body.String(), site: string(i)})
+		}
+
+		body.Reset()
+	}
+
+	if e.Sense == "N" && len(segments) > 0 {
+		segments[len(segments)-1].body = body.String()
+	} else if body.Len() > 0 || len(segments) == 0 {
+		segments = append(segments, segment{body: body.String()})
+	}
+
+	return segments
+}
+
+// isCleavageSite checks the residue on the given position against the enzyme cleavage and
+// restriction rules
+func isCleavageSite(seq []rune, i int, e bio.Enzyme) bool {
+
+	if !strings.ContainsRune(e.Cut, seq[i]) {
+		return false
+	}
+
+	if len(e.Restrict) == 0 {
+		return true
+	}
+
+	if e.Sense == "N" {
+		return i == 0 || !strings.ContainsRune(e.Restrict, seq[i-1])
+	}
+
+	return i == len(seq)-1 || !strings.ContainsRune(e.Restrict, seq[i+1])
+}
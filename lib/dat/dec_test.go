@@ -0,0 +1,96 @@
+package dat
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"philosopher/lib/bio"
+)
+
+func Test_decoyBuilder_build(t *testing.T) {
+
+	target := "MPEPTIDEKAAGGCCRLLKPEPR"
+
+	tests := []struct {
+		name   string
+		method string
+		enzyme string
+		want   string
+	}{
+		{name: "Testing full reversal", method: Reverse, enzyme: "trypsin", want: "RPEPKLLRCCGGAAKEDITPEPM"},
+		{name: "Testing tryptic pseudo-reversal", method: PseudoReverse, enzyme: "trypsin", want: "EDITPEPMKCCGGAARPEPKLLR"},
+		{name: "Testing tryptic/P pseudo-reversal", method: PseudoReverse, enzyme: "trypsin/p", want: "EDITPEPMKCCGGAARLLKPEPR"},
+		{name: "Testing Lys-N pseudo-reversal", method: PseudoReverse, enzyme: "lys_n", want: "EDITPEPMKLLRCCGGAAKRPEP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newDecoyBuilder(tt.method, bio.Registry("").Enzyme(tt.enzyme), 1)
+			if got := b.build(target); got != tt.want {
+				t.Errorf("build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decoyBuilder_shuffle(t *testing.T) {
+
+	target := "MPEPTIDEKAAGGCCRLLKPEPR"
+
+	for _, method := range []string{Shuffle, DeBruijn} {
+
+		b := newDecoyBuilder(method, bio.Registry("").Enzyme("trypsin"), 7)
+		b.addTargets(target)
+		got := b.build(target)
+
+		again := newDecoyBuilder(method, bio.Registry("").Enzyme("trypsin"), 7)
+		again.addTargets(target)
+		if again.build(target) != got {
+			t.Errorf("build() %s is not reproducible with the same seed", method)
+		}
+
+		if len(got) != len(target) || got[8] != 'K' || got[15] != 'R' || got[22] != 'R' {
+			t.Errorf("build() %s = %v, want the cleavage sites in place", method, got)
+		}
+
+		if sortedResidues(got) != sortedResidues(target) {
+			t.Errorf("build() %s = %v, want the same composition as %v", method, got, target)
+		}
+
+		// palindromic peptides cannot be changed by any method
+		for _, i := range splitSites(got, b.enzyme) {
+			if _, ok := b.targets[i.peptide(b.enzyme)]; ok && reverseSeq(i.body) != i.body {
+				t.Errorf("build() %s peptide %v matches a target peptide", method, i.peptide(b.enzyme))
+			}
+		}
+	}
+}
+
+func sortedResidues(s string) string {
+	r := strings.Split(s, "")
+	sort.Strings(r)
+	return strings.Join(r, "")
+}
+
+func Test_splitSites(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		enzyme string
+		seq    string
+		want   []segment
+	}{
+		{name: "Testing trypsin", enzyme: "trypsin", seq: "AKPRGKL", want: []segment{{body: "AKP", site: "R"}, {body: "G", site: "K"}, {body: "L"}}},
+		{name: "Testing trypsin/p", enzyme: "trypsin/p", seq: "AKPRGKL", want: []segment{{body: "A", site: "K"}, {body: "P", site: "R"}, {body: "G", site: "K"}, {body: "L"}}},
+		{name: "Testing Lys-N", enzyme: "lys_n", seq: "AKPKG", want: []segment{{body: "A"}, {site: "K", body: "P"}, {site: "K", body: "G"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newDecoyBuilder(Reverse, bio.Registry("").Enzyme(tt.enzyme), 1)
+			if got := splitSites(tt.seq, b.enzyme); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSites() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package dat
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+)
+
+// headerTemplate holds the compiled user-defined header expressions
+type headerTemplate struct {
+	id          *regexp.Regexp
+	gene        *regexp.Regexp
+	description *regexp.Regexp
+	organism    *regexp.Regexp
+}
+
+var (
+	tairReg    = regexp.MustCompile(`^AT[1-5CM]G\d{5}(\.\d+)?`)
+	sgdReg     = regexp.MustCompile(`SGDID:S\d+`)
+	flybaseReg = regexp.MustCompile(`^FBpp\d+`)
+)
+
+// UseHeaderTemplate sets the regular expressions used to parse in-house database headers, each
+// expression must have one capturing group and only the ID expression is mandatory
+func (d *Base) UseHeaderTemplate(t met.HeaderTemplate) {
+
+	if len(t.ID) == 0 {
+		return
+	}
+
+	compile := func(expr string) *regexp.Regexp {
+		if len(expr) == 0 {
+			return nil
+		}
+		re, e := regexp.Compile(expr)
+		if e != nil {
+			msg.Custom(errors.New("Invalid header template expression: "+expr), "fatal")
+		}
+		if re.NumSubexp() < 1 {
+			msg.Custom(errors.New("Header template expressions need a capturing group: "+expr), "fatal")
+		}
+		return re
+	}
+
+	d.template = &headerTemplate{
+		id:          compile(t.ID),
+		gene:        compile(t.Gene),
+		description: compile(t.Description),
+		organism:    compile(t.Organism),
+	}
+
+	return
+}
+
+// matches reports if the template ID expression can be found on the header
+func (t *headerTemplate) matches(k string) bool {
+
+	if t == nil {
+		return false
+	}
+
+	return t.id.MatchString(k)
+}
+
+// processTemplate parses FASTA records using the user-defined header template
+func processTemplate(k, v, decoyTag string, t *headerTemplate) Record {
+
+	var e Record
+
+	find := func(re *regexp.Regexp) string {
+		if re == nil {
+			return ""
+		}
+		m := re.FindStringSubmatch(k)
+		if m == nil {
+			return ""
+		}
+		return strings.TrimSpace(m[1])
+	}
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+
+	e.ID = find(t.id)
+	if len(e.ID) == 0 {
+		e.ID = e.PartHeader
+	}
+
+	e.EntryName = e.ID
+	e.GeneNames = find(t.gene)
+	e.Description = find(t.description)
+	e.ProteinName = e.Description
+	e.Organism = find(t.organism)
+
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
+
+// ProcessGENCODE parses the pipe-separated GENCODE translation headers
+func ProcessGENCODE(k, v, decoyTag string) Record {
+
+	var e Record
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+
+	// ENSP|ENST|ENSG|OTTHUMG|OTTHUMT|transcript name|gene name|length
+	fields := strings.Split(e.PartHeader, "|")
+
+	id := strings.TrimPrefix(fields[0], decoyTag)
+	e.ID = strings.Split(id, ".")[0]
+	e.EntryName = id
+
+	if len(strings.Split(id, ".")) > 1 {
+		e.SequenceVersion = strings.Split(id, ".")[1]
+	}
+
+	if len(fields) > 6 {
+		e.ProteinName = fields[5]
+		e.GeneNames = fields[6]
+		e.Description = fields[5]
+	}
+
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
+
+// ProcessEnsemblFull parses the full Ensembl headers with key:value annotations
+func ProcessEnsemblFull(k, v, decoyTag string) Record {
+
+	var e Record
+
+	symReg := regexp.MustCompile(`\sgene_symbol:(\S+)`)
+	desReg := regexp.MustCompile(`\sdescription:(.+?)(\s\[Source.+)?$`)
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+
+	id := strings.TrimPrefix(e.PartHeader, decoyTag)
+	e.ID = strings.Split(id, ".")[0]
+	e.EntryName = id
+
+	if len(strings.Split(id, ".")) > 1 {
+		e.SequenceVersion = strings.Split(id, ".")[1]
+	}
+
+	sym := symReg.FindStringSubmatch(k)
+	if sym != nil {
+		e.GeneNames = sym[1]
+	}
+
+	desc := desReg.FindStringSubmatch(k)
+	if desc != nil {
+		e.Description = desc[1]
+		e.ProteinName = desc[1]
+	}
+
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
+
+// ProcessRefSeq parses RefSeq headers with the organism between brackets
+func ProcessRefSeq(k, v, decoyTag string) Record {
+
+	var e Record
+
+	orReg := regexp.MustCompile(`\[([^\[\]]+)\]\s*$`)
+	genReg := regexp.MustCompile(`\sGN=(\S+)`)
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+
+	id := strings.TrimPrefix(e.PartHeader, decoyTag)
+	e.ID = strings.Split(id, ".")[0]
+	e.EntryName = id
+
+	if len(strings.Split(id, ".")) > 1 {
+		e.SequenceVersion = strings.Split(id, ".")[1]
+	}
+
+	desc := strings.TrimSpace(strings.TrimPrefix(k, e.PartHeader))
+
+	orgn := orReg.FindStringSubmatch(desc)
+	if orgn != nil {
+		e.Organism = orgn[1]
+		desc = strings.TrimSpace(orReg.ReplaceAllString(desc, ""))
+	}
+
+	gene := genReg.FindStringSubmatch(k)
+	if gene != nil {
+		e.GeneNames = gene[1]
+		desc = strings.TrimSpace(genReg.ReplaceAllString(desc, ""))
+	}
+
+	e.Description = desc
+	e.ProteinName = desc
+
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
+
+// ProcessTAIR parses Arabidopsis headers from TAIR and Araport
+func ProcessTAIR(k, v, decoyTag string) Record {
+
+	var e Record
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+	e.Organism = "Arabidopsis thaliana"
+
+	// AGI | Symbols: ... | description | location | release
+	fields := strings.Split(k, "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	id := strings.TrimPrefix(fields[0], decoyTag)
+	e.ID = id
+	e.EntryName = id
+	e.GeneNames = strings.Split(id, ".")[0]
+
+	var rest []string
+	for _, i := range fields[1:] {
+		if strings.HasPrefix(i, "Symbols:") {
+			symbols := strings.Split(strings.TrimSpace(strings.TrimPrefix(i, "Symbols:")), ",")
+			if len(strings.TrimSpace(symbols[0])) > 0 {
+				e.GeneNames = strings.TrimSpace(symbols[0])
+			}
+			continue
+		}
+		rest = append(rest, i)
+	}
+
+	if len(rest) > 0 {
+		e.Description = rest[0]
+		e.ProteinName = rest[0]
+	}
+
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
+
+// ProcessSGD parses Saccharomyces Genome Database headers
+func ProcessSGD(k, v, decoyTag string) Record {
+
+	var e Record
+
+	desReg := regexp.MustCompile(`"(.+)"`)
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+	e.Organism = "Saccharomyces cerevisiae"
+
+	// systematic name, standard name and SGDID
+	fields := strings.Fields(k)
+
+	e.ID = strings.TrimPrefix(fields[0], decoyTag)
+	e.EntryName = e.ID
+	e.GeneNames = e.ID
+
+	if len(fields) > 1 && !strings.HasPrefix(fields[1], "SGDID:") {
+		e.GeneNames = fields[1]
+	}
+
+	desc := desReg.FindStringSubmatch(k)
+	if desc != nil {
+		e.Description = desc[1]
+		e.ProteinName = desc[1]
+	}
+
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
+
+// ProcessFlyBase parses FlyBase translation headers
+func ProcessFlyBase(k, v, decoyTag string) Record {
+
+	var e Record
+
+	nameReg := regexp.MustCompile(`\sname=(.+?);`)
+	parentReg := regexp.MustCompile(`\sparent=(FBgn\d+)`)
+	speciesReg := regexp.MustCompile(`\sspecies=(.+?);`)
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+
+	e.ID = strings.TrimPrefix(e.PartHeader, decoyTag)
+
+	name := nameReg.FindStringSubmatch(k)
+	if name != nil {
+		e.EntryName = name[1]
+		e.ProteinName = name[1]
+		e.Description = name[1]
+
+		// the gene symbol is the name without the isoform suffix
+		gene := name[1]
+		if i := strings.LastIndex(gene, "-P"); i > 0 {
+			gene = gene[:i]
+		}
+		e.GeneNames = gene
+	} else {
+		parent := parentReg.FindStringSubmatch(k)
+		if parent != nil {
+			e.GeneNames = parent[1]
+		}
+	}
+
+	species := speciesReg.FindStringSubmatch(k)
+	if species != nil {
+		if species[1] == "Dmel" {
+			e.Organism = "Drosophila melanogaster"
+		} else {
+			e.Organism = species[1]
+		}
+	}
+
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
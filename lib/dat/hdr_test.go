@@ -0,0 +1,82 @@
+package dat
+
+import (
+	"testing"
+
+	"philosopher/lib/met"
+)
+
+func TestClassifyExtendedHeaders(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "Testing RefSeq", header: "NP_000005.3 alpha-2-macroglobulin isoform a precursor [Homo sapiens]", want: "refseq"},
+		{name: "Testing GENCODE", header: "ENSP00000493376.2|ENST00000641515.2|ENSG00000186092.7|OTTHUMG00000001094.4|OTTHUMT00000003223.4|OR4F5-201|OR4F5|326", want: "gencode"},
+		{name: "Testing full Ensembl", header: "ENSP00000493376.2 pep chromosome:GRCh38:1:65565:71585:1 gene_symbol:OR4F5 description:olfactory receptor family 4 subfamily F member 5 [Source:HGNC Symbol;Acc:HGNC:14825]", want: "ensemblfull"},
+		{name: "Testing TAIR", header: "AT1G01010.1 | Symbols: NAC001, ANAC001 | NAC domain containing protein 1 | chr1:3631-5899 FORWARD LENGTH=429 | 201606", want: "tair"},
+		{name: "Testing SGD", header: `YAL001C TFC3 SGDID:S000000001, Chr I from 151006-147594, Genome Release 64-3-1, reverse complement, Verified ORF, "Subunit of RNA polymerase III transcription initiation factor complex"`, want: "sgd"},
+		{name: "Testing FlyBase", header: "FBpp0070000 type=protein; loc=X:19961297..19961845; ID=FBpp0070000; name=CG3038-PA; parent=FBgn0031081,FBtr0070000; species=Dmel;", want: "flybase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.header, "rev_"); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendedHeaderParsers(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		record   Record
+		id       string
+		gene     string
+		organism string
+	}{
+		{name: "Testing RefSeq", record: ProcessRefSeq("NP_000005.3 alpha-2-macroglobulin isoform a precursor [Homo sapiens]", "AAA", "rev_"), id: "NP_000005", organism: "Homo sapiens"},
+		{name: "Testing GENCODE", record: ProcessGENCODE("rev_ENSP00000493376.2|ENST00000641515.2|ENSG00000186092.7|OTTHUMG00000001094.4|OTTHUMT00000003223.4|OR4F5-201|OR4F5|326", "AAA", "rev_"), id: "ENSP00000493376", gene: "OR4F5"},
+		{name: "Testing full Ensembl", record: ProcessEnsemblFull("ENSP00000493376.2 pep chromosome:GRCh38:1:65565:71585:1 gene_symbol:OR4F5 description:olfactory receptor [Source:HGNC Symbol;Acc:HGNC:14825]", "AAA", "rev_"), id: "ENSP00000493376", gene: "OR4F5"},
+		{name: "Testing TAIR", record: ProcessTAIR("AT1G01010.1 | Symbols: NAC001, ANAC001 | NAC domain containing protein 1 | chr1:3631-5899 FORWARD LENGTH=429", "AAA", "rev_"), id: "AT1G01010.1", gene: "NAC001", organism: "Arabidopsis thaliana"},
+		{name: "Testing SGD", record: ProcessSGD(`YAL001C TFC3 SGDID:S000000001, Chr I from 151006-147594, Verified ORF, "Subunit of RNA polymerase III"`, "AAA", "rev_"), id: "YAL001C", gene: "TFC3", organism: "Saccharomyces cerevisiae"},
+		{name: "Testing FlyBase", record: ProcessFlyBase("FBpp0070000 type=protein; ID=FBpp0070000; name=CG3038-PA; parent=FBgn0031081,FBtr0070000; species=Dmel;", "AAA", "rev_"), id: "FBpp0070000", gene: "CG3038", organism: "Drosophila melanogaster"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.record.ID != tt.id || tt.record.GeneNames != tt.gene || tt.record.Organism != tt.organism {
+				t.Errorf("got = %v %v %v, want %v %v %v", tt.record.ID, tt.record.GeneNames, tt.record.Organism, tt.id, tt.gene, tt.organism)
+			}
+		})
+	}
+
+	if r := ProcessGENCODE("rev_ENSP00000493376.2|ENST00000641515.2|ENSG00000186092.7|OTTHUMG|OTTHUMT|OR4F5-201|OR4F5|326", "AAA", "rev_"); !r.IsDecoy {
+		t.Errorf("ProcessGENCODE() decoy entry not flagged")
+	}
+}
+
+func TestHeaderTemplate(t *testing.T) {
+
+	var d = New()
+	d.UseHeaderTemplate(met.HeaderTemplate{ID: `^(\w+)\|`, Gene: `gene=(\S+)`, Description: `desc="(.+?)"`, Organism: `org=(\w+)`})
+
+	r := processTemplate(`LAB0001|v2 gene=ABC1 desc="in-house protein" org=mouse`, "AAA", "rev_", d.template)
+
+	if r.ID != "LAB0001" || r.GeneNames != "ABC1" || r.Description != "in-house protein" || r.Organism != "mouse" {
+		t.Errorf("processTemplate() got = %+v", r)
+	}
+
+	// headers recognized by the standard parsers are not parsed with the template
+	r = d.processRecord("sp|P12345|ABC1_HUMAN Protein ABC1 OS=Homo sapiens OX=9606 GN=ABC1 PE=1 SV=1", "AAA", "rev_")
+	if r.ID != "P12345" || r.GeneNames != "ABC1" {
+		t.Errorf("processRecord() got = %+v", r)
+	}
+
+	r = d.processRecord(`LAB0002|v1 gene=XYZ2 desc="another protein" org=mouse`, "AAA", "rev_")
+	if r.ID != "LAB0002" || r.GeneNames != "XYZ2" {
+		t.Errorf("processRecord() got = %+v", r)
+	}
+}
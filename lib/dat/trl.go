@@ -0,0 +1,309 @@
+package dat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/fas"
+	"philosopher/lib/msg"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Nucleotide translation modes
+const (
+	ThreeFrame = "3frame"
+	SixFrame   = "6frame"
+	LongestORF = "orf"
+)
+
+// geneticCodes has the NCBI translation tables, amino acids follow the TCAG codon order
+var geneticCodes = map[int]string{
+	1:  "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	2:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIMMTTTTNNKKSS**VVVVAAAADDEEGGGG",
+	3:  "FFLLSSSSYY**CCWWTTTTPPPPHHQQRRRRIIMMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	4:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	5:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIMMTTTTNNKKSSSSVVVVAAAADDEEGGGG",
+	6:  "FFLLSSSSYYQQCC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	9:  "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIIMTTTTNNNKSSSSVVVVAAAADDEEGGGG",
+	10: "FFLLSSSSYY**CCCWLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	11: "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	12: "FFLLSSSSYY**CC*WLLLSPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG",
+	13: "FFLLSSSSYY**CCWWLLLLPPPPHHQQRRRRIIMMTTTTNNKKSSGGVVVVAAAADDEEGGGG",
+	14: "FFLLSSSSYYY*CCWWLLLLPPPPHHQQRRRRIIIMTTTTNNNKSSSSVVVVAAAADDEEGGGG",
+}
+
+// ORF is a translated segment of a nucleotide sequence, the coordinates are 1-based on the
+// forward strand and the frame is negative for the reverse strand
+type ORF struct {
+	Frame    int
+	Start    int
+	End      int
+	Sequence string
+}
+
+// GeneticCode returns the codon table for the given NCBI translation table number
+func GeneticCode(table int) (map[string]byte, error) {
+
+	aas, ok := geneticCodes[table]
+	if !ok {
+		return nil, fmt.Errorf("genetic code %d is not supported", table)
+	}
+
+	var code = make(map[string]byte)
+	var bases = "TCAG"
+
+	for i := 0; i < 64; i++ {
+		codon := string([]byte{bases[i/16], bases[(i/4)%4], bases[i%4]})
+		code[codon] = aas[i]
+	}
+
+	return code, nil
+}
+
+// Translate converts a nucleotide sequence to amino acids, incomplete and ambiguous codons
+// are translated to X and stops to *
+func Translate(seq string, code map[string]byte) string {
+
+	var protein = make([]byte, 0, len(seq)/3)
+
+	for i := 0; i+3 <= len(seq); i += 3 {
+		aa, ok := code[seq[i:i+3]]
+		if !ok {
+			aa = 'X'
+		}
+		protein = append(protein, aa)
+	}
+
+	return string(protein)
+}
+
+// reverseComplement returns the opposite strand of a nucleotide sequence
+func reverseComplement(seq string) string {
+
+	var pairs = map[byte]byte{'A': 'T', 'T': 'A', 'C': 'G', 'G': 'C'}
+
+	rc := make([]byte, len(seq))
+	for i := range seq {
+		b, ok := pairs[seq[i]]
+		if !ok {
+			b = 'N'
+		}
+		rc[len(seq)-1-i] = b
+	}
+
+	return string(rc)
+}
+
+// ORFs translates the nucleotide sequence with the given mode. The frame modes report every
+// stop-to-stop segment with the minimum length, the ORF mode reports the longest segment starting
+// with a methionine on either strand, an ORF can run to the end of a partial transcript
+func ORFs(seq, mode string, code map[string]byte, minLength int) []ORF {
+
+	seq = strings.Replace(strings.ToUpper(seq), "U", "T", -1)
+
+	strands := []int{1}
+	if mode != ThreeFrame {
+		strands = append(strands, -1)
+	}
+
+	var orfs []ORF
+	var longest ORF
+
+	for _, s := range strands {
+
+		nt := seq
+		if s < 0 {
+			nt = reverseComplement(seq)
+		}
+
+		for f := 0; f < 3; f++ {
+
+			protein := Translate(nt[f:], code)
+
+			offset := 0
+			for _, segment := range strings.Split(protein, "*") {
+
+				begin := offset
+				offset += len(segment) + 1
+
+				if mode == LongestORF {
+					m := strings.IndexByte(segment, 'M')
+					if m < 0 {
+						continue
+					}
+					segment = segment[m:]
+					begin += m
+				}
+
+				if len(segment) < minLength {
+					continue
+				}
+
+				// nucleotide span of the segment on the translated strand
+				a := f + begin*3 + 1
+				b := f + (begin+len(segment))*3
+
+				orf := ORF{Frame: s * (f + 1), Start: a, End: b, Sequence: segment}
+				if s < 0 {
+					orf.Start = len(nt) - b + 1
+					orf.End = len(nt) - a + 1
+				}
+
+				if mode == LongestORF {
+					if len(segment) > len(longest.Sequence) {
+						longest = orf
+					}
+					continue
+				}
+
+				orfs = append(orfs, orf)
+			}
+		}
+	}
+
+	if mode == LongestORF && len(longest.Sequence) > 0 {
+		orfs = append(orfs, longest)
+	}
+
+	return orfs
+}
+
+// MergeFASTA concatenates the given FASTA files into the output file
+func MergeFASTA(output string, files ...string) string {
+
+	out, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+	defer out.Close()
+
+	for _, i := range files {
+
+		b, e := ioutil.ReadFile(i)
+		if e != nil {
+			msg.ReadFile(e, "fatal")
+		}
+
+		// files without a trailing new line would merge the last sequence with the next header
+		if len(b) > 0 && b[len(b)-1] != '\n' {
+			b = append(b, '\n')
+		}
+
+		_, e = out.Write(b)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	return output
+}
+
+// TranslateFile translates a nucleotide FASTA file and writes the protein sequences to the
+// temporary folder, the headers keep the transcript ID, frame and coordinates of each ORF
+func TranslateFile(f, temp, mode string, table, minLength int) string {
+
+	if mode != ThreeFrame && mode != SixFrame && mode != LongestORF {
+		msg.Custom(errors.New("Translation mode must be 3frame, 6frame or orf"), "fatal")
+	}
+
+	code, e := GeneticCode(table)
+	if e != nil {
+		msg.Custom(e, "fatal")
+	}
+
+	transcripts := fas.ParseFile(f)
+
+	var headers []string
+	for k := range transcripts {
+		headers = append(headers, k)
+	}
+	sort.Strings(headers)
+
+	base := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+	output := fmt.Sprintf("%s%s%s.translated.fas", temp, string(filepath.Separator), base)
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+	defer file.Close()
+
+	var total int
+
+	for _, k := range headers {
+
+		transcript := strings.Split(k, " ")[0]
+		description := strings.TrimSpace(strings.TrimPrefix(k, transcript))
+
+		for n, i := range ORFs(transcripts[k], mode, code, minLength) {
+
+			id := fmt.Sprintf("%s_%s%d_%d", transcript, frameStrand(i.Frame), abs(i.Frame), n+1)
+
+			header := fmt.Sprintf(">orf|%s|%s frame=%+d start=%d end=%d", id, transcript, i.Frame, i.Start, i.End)
+			if len(description) > 0 {
+				header += " " + description
+			}
+
+			_, e = io.WriteString(file, header+"\n"+i.Sequence+"\n")
+			if e != nil {
+				msg.WriteFile(e, "fatal")
+			}
+			total++
+		}
+	}
+
+	logrus.Info("Translated ", len(headers), " nucleotide sequences into ", total, " protein sequences")
+
+	return output
+}
+
+// ProcessORF parses the headers of the translated nucleotide sequences
+func ProcessORF(k, v, decoyTag string) Record {
+
+	var e Record
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+
+	// orf|transcript_frame_n|transcript frame=+1 start=1 end=300 description
+	fields := strings.Split(strings.TrimPrefix(e.PartHeader, decoyTag), "|")
+	if len(fields) > 2 {
+		e.ID = fields[1]
+		e.EntryName = fields[1]
+		e.GeneNames = fields[2]
+	} else {
+		e.ID = strings.TrimPrefix(e.PartHeader, decoyTag)
+		e.EntryName = e.ID
+	}
+
+	desc := strings.TrimSpace(strings.TrimPrefix(k, e.PartHeader))
+	e.Description = desc
+	e.ProteinName = desc
+
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
+
+func frameStrand(frame int) string {
+	if frame < 0 {
+		return "R"
+	}
+	return "F"
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
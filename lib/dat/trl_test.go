@@ -0,0 +1,142 @@
+package dat
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"philosopher/lib/fas"
+)
+
+func TestTranslate(t *testing.T) {
+
+	standard, _ := GeneticCode(1)
+	mito, _ := GeneticCode(2)
+
+	tests := []struct {
+		name string
+		seq  string
+		code map[string]byte
+		want string
+	}{
+		{name: "Testing standard code", seq: "ATGGCCTGATGGAAA", code: standard, want: "MA*WK"},
+		{name: "Testing vertebrate mitochondrial code", seq: "ATGGCCTGATGGAGA", code: mito, want: "MAWW*"},
+		{name: "Testing ambiguous and incomplete codons", seq: "ATGNNNGC", code: standard, want: "MX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Translate(tt.seq, tt.code); got != tt.want {
+				t.Errorf("Translate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, e := GeneticCode(7); e == nil {
+		t.Errorf("GeneticCode() should reject unknown tables")
+	}
+}
+
+func TestORFs(t *testing.T) {
+
+	code, _ := GeneticCode(1)
+
+	// MAKR* on the forward strand, MPK on the reverse strand
+	seq := "CCATGGCCAAACGCTAAGG" + "TTTGGGCATCC"
+
+	tests := []struct {
+		name  string
+		mode  string
+		min   int
+		check func([]ORF) bool
+	}{
+		{name: "Testing 3-frame", mode: ThreeFrame, min: 1, check: func(o []ORF) bool {
+			for _, i := range o {
+				if i.Frame < 0 {
+					return false
+				}
+			}
+			return len(o) > 0
+		}},
+		{name: "Testing 6-frame", mode: SixFrame, min: 1, check: func(o []ORF) bool {
+			var reverse bool
+			for _, i := range o {
+				if i.Frame < 0 {
+					reverse = true
+				}
+			}
+			return reverse
+		}},
+		{name: "Testing longest ORF", mode: LongestORF, min: 3, check: func(o []ORF) bool {
+			return len(o) == 1 && o[0].Sequence == "MAKR" && o[0].Frame == 3 && o[0].Start == 3 && o[0].End == 14
+		}},
+		{name: "Testing minimum length", mode: LongestORF, min: 10, check: func(o []ORF) bool {
+			return len(o) == 0
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ORFs(seq, tt.mode, code, tt.min); !tt.check(got) {
+				t.Errorf("ORFs() = %v", got)
+			}
+		})
+	}
+}
+
+func TestORFs_reverseCoordinates(t *testing.T) {
+
+	code, _ := GeneticCode(1)
+
+	// the reverse complement starts with ATGCCCAAA
+	seq := "TTTGGGCAT"
+
+	got := ORFs(seq, SixFrame, code, 3)
+
+	var found bool
+	for _, i := range got {
+		if i.Frame == -1 && i.Sequence == "MPK" && i.Start == 1 && i.End == 9 {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("ORFs() = %v, want MPK on frame -1", got)
+	}
+}
+
+func TestProcessORF(t *testing.T) {
+
+	header := "orf|TRINITY_DN1_c0_g1_i1_F3_1|TRINITY_DN1_c0_g1_i1 frame=+3 start=3 end=14 len=30"
+
+	target := ProcessORF(header, "MAKR", "rev_")
+	if target.ID != "TRINITY_DN1_c0_g1_i1_F3_1" || target.GeneNames != "TRINITY_DN1_c0_g1_i1" || target.IsDecoy {
+		t.Errorf("ProcessORF() = %v %v %v", target.ID, target.GeneNames, target.IsDecoy)
+	}
+
+	decoy := ProcessORF("rev_"+header, "RKAM", "rev_")
+	if decoy.ID != "TRINITY_DN1_c0_g1_i1_F3_1" || !decoy.IsDecoy {
+		t.Errorf("ProcessORF() decoy = %v %v", decoy.ID, decoy.IsDecoy)
+	}
+
+	if Classify(header, "rev_") != "orf" {
+		t.Errorf("Classify() = %v, want orf", Classify(header, "rev_"))
+	}
+}
+
+func TestMergeFASTA(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "dat")
+	defer os.RemoveAll(dir)
+
+	custom := filepath.Join(dir, "custom.fas")
+	translated := filepath.Join(dir, "translated.fas")
+	ioutil.WriteFile(custom, []byte(">sp|P1|A\nPEPTIDE"), 0644)
+	ioutil.WriteFile(translated, []byte(">orf|T1_f1_1|T1\nMKR\n"), 0644)
+
+	merged := MergeFASTA(filepath.Join(dir, "merged.fas"), custom, translated)
+
+	db := fas.ParseFile(merged)
+	if len(db) != 2 || db["sp|P1|A"] != "PEPTIDE" || db["orf|T1_f1_1|T1"] != "MKR" {
+		t.Errorf("MergeFASTA() = %v", db)
+	}
+}
@@ -0,0 +1,222 @@
+package dat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Variant is a sequence change described on the reference protein coordinates
+type Variant struct {
+	Accession   string
+	Position    int
+	Reference   string
+	Alternative string
+}
+
+// ReadVariants parses a tab-separated variant table with the protein accession, the 1-based position,
+// the reference residues and the alternative residues. Insertions use - as reference and are placed
+// after the given position, deletions use - as alternative
+func ReadVariants(f string) []Variant {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer file.Close()
+
+	var variants []Variant
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, "\t")
+		if len(parts) < 4 {
+			msg.Custom(errors.New("Variant lines need accession, position, reference and alternative columns"), "fatal")
+		}
+
+		pos, e := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if e != nil {
+			// header line
+			if len(variants) == 0 {
+				continue
+			}
+			msg.Custom(errors.New("Invalid variant position: "+parts[1]), "fatal")
+		}
+
+		v := Variant{
+			Accession:   strings.TrimSpace(parts[0]),
+			Position:    pos,
+			Reference:   strings.ToUpper(strings.Trim(strings.TrimSpace(parts[2]), "-")),
+			Alternative: strings.ToUpper(strings.Trim(strings.TrimSpace(parts[3]), "-")),
+		}
+
+		variants = append(variants, v)
+	}
+
+	if e := scanner.Err(); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	return variants
+}
+
+// Notation returns a compact HGVS-like description of the variant
+func (v Variant) Notation() string {
+
+	switch {
+	case len(v.Reference) == 0:
+		return fmt.Sprintf("%dins%s", v.Position, v.Alternative)
+	case len(v.Alternative) == 0 && len(v.Reference) == 1:
+		return fmt.Sprintf("%s%ddel", v.Reference, v.Position)
+	case len(v.Alternative) == 0:
+		return fmt.Sprintf("%s%d_%s%ddel", v.Reference[:1], v.Position, v.Reference[len(v.Reference)-1:], v.Position+len(v.Reference)-1)
+	case len(v.Reference) == 1 && len(v.Alternative) == 1:
+		return fmt.Sprintf("%s%d%s", v.Reference, v.Position, v.Alternative)
+	}
+
+	return fmt.Sprintf("%s%d_%s%ddelins%s", v.Reference[:1], v.Position, v.Reference[len(v.Reference)-1:], v.Position+len(v.Reference)-1, v.Alternative)
+}
+
+// Apply returns the variant sequence and the 1-based span of the changed residues on it, for
+// deletions the span covers the two residues flanking the junction
+func (v Variant) Apply(seq string) (string, int, int, error) {
+
+	if v.Position < 1 || v.Position > len(seq) {
+		return "", 0, 0, errors.New("position outside of the sequence")
+	}
+
+	// insertions go after the given position
+	if len(v.Reference) == 0 {
+		variant := seq[:v.Position] + v.Alternative + seq[v.Position:]
+		return variant, v.Position + 1, v.Position + len(v.Alternative), nil
+	}
+
+	end := v.Position - 1 + len(v.Reference)
+	if end > len(seq) || seq[v.Position-1:end] != v.Reference {
+		return "", 0, 0, errors.New("reference residues do not match the sequence")
+	}
+
+	variant := seq[:v.Position-1] + v.Alternative + seq[end:]
+
+	if len(v.Alternative) == 0 {
+		start := v.Position - 1
+		if start < 1 {
+			start = 1
+		}
+		return variant, start, v.Position, nil
+	}
+
+	return variant, v.Position, v.Position + len(v.Alternative) - 1, nil
+}
+
+// addVariants creates one variant entry for each variant on the matching target sequences, the
+// headers keep the reference header and carry the variant accession and span
+func (d *Base) addVariants(db map[string]string, f, decoyTag string) {
+
+	var accessions = make(map[string][]string)
+	for k, v := range db {
+		rec := d.processRecord(k, v, decoyTag)
+		accessions[rec.ID] = append(accessions[rec.ID], k)
+		if rec.PartHeader != rec.ID {
+			accessions[rec.PartHeader] = append(accessions[rec.PartHeader], k)
+		}
+	}
+
+	var added int
+
+	for _, i := range ReadVariants(f) {
+
+		headers, ok := accessions[i.Accession]
+		if !ok {
+			msg.Custom(errors.New("Protein not found for variant "+i.Accession+" "+i.Notation()), "warning")
+			continue
+		}
+
+		sort.Strings(headers)
+		header := headers[0]
+
+		seq, start, end, e := i.Apply(db[header])
+		if e != nil {
+			msg.Custom(fmt.Errorf("Variant %s %s skipped, %s", i.Accession, i.Notation(), e.Error()), "warning")
+			continue
+		}
+
+		vh := fmt.Sprintf("var|%s_%s|%s|%d-%d %s", i.Accession, i.Notation(), i.Notation(), start, end, header)
+		db[vh] = seq
+		added++
+	}
+
+	logrus.Info("Added ", added, " variant sequences")
+
+	return
+}
+
+// processVariant parses the variant entries, the annotation comes from the reference header
+func (d *Base) processVariant(k, v, decoyTag string) Record {
+
+	header := strings.TrimPrefix(k, decoyTag)
+
+	var token, reference string
+	if i := strings.Index(header, " "); i > 0 {
+		token = header[:i]
+		reference = header[i+1:]
+	} else {
+		token = header
+	}
+
+	var e Record
+	if len(reference) > 0 {
+		e = d.processRecord(reference, v, decoyTag)
+	}
+
+	// var|accession_notation|notation|start-end
+	fields := strings.Split(token, "|")
+	if len(fields) > 3 {
+		e.ID = fields[1]
+		e.EntryName = fields[1]
+		e.Variant = fields[2]
+
+		span := strings.Split(fields[3], "-")
+		if len(span) == 2 {
+			e.VariantStart, _ = strconv.Atoi(span[0])
+			e.VariantEnd, _ = strconv.Atoi(span[1])
+		}
+	}
+
+	e.OriginalHeader = k
+	e.PartHeader = strings.Split(k, " ")[0]
+	e.Sequence = v
+	e.Length = len(v)
+	e.IsDecoy = strings.HasPrefix(k, decoyTag)
+
+	return e
+}
+
+// SpansVariant reports if the peptide between the 1-based start and end positions covers the variant,
+// peptides must contain both residues flanking a deletion
+func (r Record) SpansVariant(start, end int) bool {
+
+	if len(r.Variant) == 0 || r.VariantStart == 0 {
+		return false
+	}
+
+	if strings.HasSuffix(r.Variant, "del") {
+		return start <= r.VariantStart && end >= r.VariantEnd
+	}
+
+	return start <= r.VariantEnd && end >= r.VariantStart
+}
@@ -0,0 +1,97 @@
+package dat
+
+import (
+	"testing"
+)
+
+func TestVariant_Apply(t *testing.T) {
+
+	seq := "MKPEPTIDER"
+
+	tests := []struct {
+		name     string
+		variant  Variant
+		notation string
+		want     string
+		start    int
+		end      int
+		wantErr  bool
+	}{
+		{name: "Testing substitution", variant: Variant{Position: 3, Reference: "P", Alternative: "L"}, notation: "P3L", want: "MKLEPTIDER", start: 3, end: 3},
+		{name: "Testing insertion", variant: Variant{Position: 2, Alternative: "GG"}, notation: "2insGG", want: "MKGGPEPTIDER", start: 3, end: 4},
+		{name: "Testing deletion", variant: Variant{Position: 5, Reference: "P"}, notation: "P5del", want: "MKPETIDER", start: 4, end: 5},
+		{name: "Testing range deletion", variant: Variant{Position: 5, Reference: "PT"}, notation: "P5_T6del", want: "MKPEIDER", start: 4, end: 5},
+		{name: "Testing delins", variant: Variant{Position: 6, Reference: "TI", Alternative: "W"}, notation: "T6_I7delinsW", want: "MKPEPWDER", start: 6, end: 6},
+		{name: "Testing reference mismatch", variant: Variant{Position: 4, Reference: "K", Alternative: "L"}, notation: "K4L", wantErr: true},
+		{name: "Testing position outside", variant: Variant{Position: 20, Reference: "K", Alternative: "L"}, notation: "K20L", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if got := tt.variant.Notation(); got != tt.notation {
+				t.Errorf("Notation() = %v, want %v", got, tt.notation)
+			}
+
+			got, start, end, e := tt.variant.Apply(seq)
+			if (e != nil) != tt.wantErr {
+				t.Errorf("Apply() error = %v, wantErr %v", e, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got != tt.want || start != tt.start || end != tt.end {
+				t.Errorf("Apply() = %v %d-%d, want %v %d-%d", got, start, end, tt.want, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestBase_processVariant(t *testing.T) {
+
+	var d Base
+
+	header := "var|P02768_P4L|P4L|4-4 sp|P02768|ALBU_HUMAN Serum albumin OS=Homo sapiens OX=9606 GN=ALB PE=1 SV=2"
+
+	target := d.processVariant(header, "MKPELTIDER", "rev_")
+	if target.ID != "P02768_P4L" || target.Variant != "P4L" || target.VariantStart != 4 || target.VariantEnd != 4 {
+		t.Errorf("processVariant() = %v %v %d-%d", target.ID, target.Variant, target.VariantStart, target.VariantEnd)
+	}
+
+	if target.GeneNames != "ALB" || target.PartHeader != "var|P02768_P4L|P4L|4-4" || target.IsDecoy {
+		t.Errorf("processVariant() annotation = %v %v %v", target.GeneNames, target.PartHeader, target.IsDecoy)
+	}
+
+	decoy := d.processVariant("rev_"+header, "REDITLEPKM", "rev_")
+	if !decoy.IsDecoy || decoy.ID != "P02768_P4L" {
+		t.Errorf("processVariant() decoy = %v %v", decoy.ID, decoy.IsDecoy)
+	}
+}
+
+func TestRecord_SpansVariant(t *testing.T) {
+
+	substitution := Record{Variant: "P4L", VariantStart: 4, VariantEnd: 4}
+	deletion := Record{Variant: "P5del", VariantStart: 4, VariantEnd: 5}
+
+	tests := []struct {
+		name   string
+		record Record
+		start  int
+		end    int
+		want   bool
+	}{
+		{name: "Testing covered substitution", record: substitution, start: 3, end: 10, want: true},
+		{name: "Testing distant substitution", record: substitution, start: 5, end: 10, want: false},
+		{name: "Testing covered junction", record: deletion, start: 3, end: 9, want: true},
+		{name: "Testing half junction", record: deletion, start: 5, end: 9, want: false},
+		{name: "Testing reference record", record: Record{}, start: 1, end: 10, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.record.SpansVariant(tt.start, tt.end); got != tt.want {
+				t.Errorf("SpansVariant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
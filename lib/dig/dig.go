@@ -0,0 +1,412 @@
+// Package dig (Digestion) performs the in-silico digestion of protein databases
+package dig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/dat"
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack"
+)
+
+// Digestion specificities
+const (
+	Specific    = bio.Specific
+	Semi        = bio.Semi
+	NonSpecific = bio.NonSpecific
+)
+
+// Peptide is a theoretical peptide and the database entries containing it
+type Peptide struct {
+	Sequence        string
+	Mass            float64
+	MissedCleavages uint8
+	Proteins        []string
+	Genes           []string
+	IsUniqueProtein bool
+	IsUniqueGene    bool
+	IsProteotypic   bool
+}
+
+// PeptideList is a list of theoretical peptides
+type PeptideList []Peptide
+
+// Len function for Sort
+func (a PeptideList) Len() int {
+	return len(a)
+}
+
+// Less function for Sort
+func (a PeptideList) Less(i, j int) bool {
+	return a[i].Sequence < a[j].Sequence
+}
+
+// Swap function for Sort
+func (a PeptideList) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+// Index is the peptide to protein index from a digested database
+type Index struct {
+	Enzyme          string
+	Specificity     string
+	MissedCleavages int
+	MinLength       int
+	MaxLength       int
+	MinMass         float64
+	MaxMass         float64
+	Peptides        PeptideList
+	Theoretical     map[string]int
+	Lookup          map[string]int
+}
+
+// Cleaved is a peptide produced by the digestion of a single sequence
+type Cleaved struct {
+	Sequence        string
+	Start           int
+	MissedCleavages uint8
+}
+
+// residueMass holds the monoisotopic residue masses
+var residueMass = make(map[rune]float64)
+
+func init() {
+	for _, i := range "ARNDCQEGHILKMFPSTWYV" {
+		residueMass[i] = bio.NewFromCode(string(i)).MonoIsotopeMass
+	}
+}
+
+// New constructor
+func New() Index {
+
+	var self Index
+
+	self.Theoretical = make(map[string]int)
+	self.Lookup = make(map[string]int)
+
+	return self
+}
+
+// Run is the main entry point for the digest command
+func Run(m met.Data) met.Data {
+
+	var db dat.Base
+	db.Restore()
+
+	if len(db.Records) < 1 {
+		msg.InputNotFound(errors.New("Database not found, annotate a database before the digestion"), "fatal")
+	}
+
+	if len(m.Digest.Enzyme) == 0 {
+		m.Digest.Enzyme = m.Database.Enz
+	}
+
+	logrus.Info("Digesting ", len(db.Records), " protein entries")
+
+	idx := Build(db.Records, m.Digest, bio.Registry(m.Database.EnzymeRules))
+
+	logrus.Info("Indexed ", len(idx.Peptides), " peptides")
+
+	idx.Serialize()
+	idx.Report(m.Home)
+
+	return m
+}
+
+// Build digests every target entry from the database and indexes the peptides by sequence
+func Build(records []dat.Record, p met.Digest, enzymes bio.EnzymeRegistry) Index {
+
+	var idx = New()
+
+	enzyme := enzymes.Enzyme(p.Enzyme)
+
+	idx.Enzyme = enzyme.Name
+	idx.Specificity = strings.ToLower(p.Specificity)
+	idx.MissedCleavages = p.MissedCleavages
+	idx.MinLength = p.MinLength
+	idx.MaxLength = p.MaxLength
+	idx.MinMass = p.MinMass
+	idx.MaxMass = p.MaxMass
+
+	// semi and non-specific enzyme rules set the specificity on their own
+	if len(idx.Specificity) == 0 || (idx.Specificity == Specific && len(enzyme.Specificity) > 0) {
+		idx.Specificity = enzyme.Specificity
+	}
+
+	if len(idx.Specificity) == 0 {
+		idx.Specificity = Specific
+	}
+
+	var peptides = make(map[string]*Peptide)
+	var proteins = make(map[string]map[string]uint8)
+	var genes = make(map[string]map[string]uint8)
+
+	for _, i := range records {
+
+		if i.IsDecoy {
+			continue
+		}
+
+		gene := i.GeneNames
+		if len(gene) == 0 {
+			gene = i.ID
+		}
+
+		for _, j := range Digest(i.Sequence, enzyme, idx.MissedCleavages, idx.MinLength, idx.MaxLength, idx.Specificity) {
+
+			mass, ok := PeptideMass(j.Sequence)
+			if !ok || (idx.MinMass > 0 && mass < idx.MinMass) || (idx.MaxMass > 0 && mass > idx.MaxMass) {
+				continue
+			}
+
+			pep, ok := peptides[j.Sequence]
+			if !ok {
+				pep = &Peptide{Sequence: j.Sequence, Mass: mass, MissedCleavages: j.MissedCleavages}
+				peptides[j.Sequence] = pep
+				proteins[j.Sequence] = make(map[string]uint8)
+				genes[j.Sequence] = make(map[string]uint8)
+			}
+
+			if j.MissedCleavages < pep.MissedCleavages {
+				pep.MissedCleavages = j.MissedCleavages
+			}
+
+			proteins[j.Sequence][i.ID] = 0
+			genes[j.Sequence][gene] = 0
+		}
+	}
+
+	for k, v := range peptides {
+
+		for i := range proteins[k] {
+			v.Proteins = append(v.Proteins, i)
+			idx.Theoretical[i]++
+		}
+
+		for i := range genes[k] {
+			v.Genes = append(v.Genes, i)
+		}
+
+		sort.Strings(v.Proteins)
+		sort.Strings(v.Genes)
+
+		v.IsUniqueProtein = len(v.Proteins) == 1
+		v.IsUniqueGene = len(v.Genes) == 1
+		v.IsProteotypic = v.IsUniqueGene && v.MissedCleavages == 0
+
+		idx.Peptides = append(idx.Peptides, *v)
+	}
+
+	sort.Sort(idx.Peptides)
+
+	for i, j := range idx.Peptides {
+		idx.Lookup[j.Sequence] = i
+	}
+
+	return idx
+}
+
+// Digest cleaves a protein sequence with the given enzyme, the semi-specific digestion requires only
+// one of the peptide termini to be a cleavage site and the non-specific digestion ignores the enzyme
+func Digest(seq string, e bio.Enzyme, missed, minLength, maxLength int, specificity string) []Cleaved {
+
+	var cleaved []Cleaved
+	var seen = make(map[string]uint8)
+
+	if maxLength <= 0 {
+		maxLength = len(seq)
+	}
+
+	sites := e.Sites(seq)
+
+	// number of cleavage sites inside the peptide
+	missedIn := func(start, end int) int {
+		var n int
+		for _, i := range sites {
+			if i > start && i < end {
+				n++
+			}
+		}
+		return n
+	}
+
+	add := func(start, end int) {
+
+		length := end - start
+		if length < minLength || length > maxLength || length < 1 {
+			return
+		}
+
+		key := fmt.Sprintf("%d-%d", start, end)
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = 0
+
+		n := missedIn(start, end)
+		if specificity != NonSpecific && n > missed {
+			return
+		}
+
+		cleaved = append(cleaved, Cleaved{Sequence: seq[start:end], Start: start, MissedCleavages: uint8(n)})
+	}
+
+	switch specificity {
+	case NonSpecific:
+		for i := 0; i < len(seq); i++ {
+			for j := i + 1; j <= len(seq) && j-i <= maxLength; j++ {
+				add(i, j)
+			}
+		}
+	case Semi:
+		for i, start := range sites {
+			for j := i + 1; j < len(sites) && j-i-1 <= missed; j++ {
+				end := sites[j]
+				for k := start + 1; k <= end; k++ {
+					add(start, k)
+				}
+				for k := start; k < end; k++ {
+					add(k, end)
+				}
+			}
+		}
+	default:
+		for i, start := range sites {
+			for j := i + 1; j < len(sites) && j-i-1 <= missed; j++ {
+				add(start, sites[j])
+			}
+		}
+	}
+
+	return cleaved
+}
+
+// PeptideMass returns the monoisotopic neutral mass, sequences with unknown residues are not valid
+func PeptideMass(seq string) (float64, bool) {
+
+	var mass = bio.Water
+
+	for _, i := range seq {
+		m, ok := residueMass[i]
+		if !ok {
+			return 0, false
+		}
+		mass += m
+	}
+
+	return mass, true
+}
+
+// IsUnique reports if a peptide maps to a single protein and to a single gene
+func (idx Index) IsUnique(seq string) (bool, bool) {
+
+	i, ok := idx.Lookup[seq]
+	if !ok {
+		return false, false
+	}
+
+	return idx.Peptides[i].IsUniqueProtein, idx.Peptides[i].IsUniqueGene
+}
+
+// Proteotypic returns the proteotypic peptides of a protein
+func (idx Index) Proteotypic(protein string) []string {
+
+	var list []string
+
+	for _, i := range idx.Peptides {
+		if !i.IsProteotypic {
+			continue
+		}
+		for _, j := range i.Proteins {
+			if j == protein {
+				list = append(list, i.Sequence)
+			}
+		}
+	}
+
+	return list
+}
+
+// Report writes the peptide index to a tab-separated file
+func (idx Index) Report(home string) {
+
+	output := fmt.Sprintf("%s%sdigest.tsv", home, string(filepath.Separator))
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create the digestion report"), "fatal")
+	}
+	defer file.Close()
+
+	_, e = io.WriteString(file, "Peptide\tPeptide Length\tCalculated Peptide Mass\tMissed Cleavages\tProteins\tGenes\tIs Unique Protein\tIs Unique Gene\tIs Proteotypic\n")
+	if e != nil {
+		msg.WriteToFile(errors.New("Cannot write to the digestion report"), "fatal")
+	}
+
+	for _, i := range idx.Peptides {
+
+		line := fmt.Sprintf("%s\t%d\t%.4f\t%d\t%s\t%s\t%t\t%t\t%t\n",
+			i.Sequence,
+			len(i.Sequence),
+			i.Mass,
+			i.MissedCleavages,
+			strings.Join(i.Proteins, ", "),
+			strings.Join(i.Genes, ", "),
+			i.IsUniqueProtein,
+			i.IsUniqueGene,
+			i.IsProteotypic,
+		)
+
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(errors.New("Cannot write to the digestion report"), "fatal")
+		}
+	}
+
+	return
+}
+
+// Serialize saves to disk a msgpack version of the peptide index
+func (idx *Index) Serialize() {
+
+	b, e := msgpack.Marshal(&idx)
+	if e != nil {
+		msg.MarshalFile(e, "fatal")
+	}
+
+	e = ioutil.WriteFile(sys.DigBin(), b, sys.FilePermission())
+	if e != nil {
+		msg.SerializeFile(e, "fatal")
+	}
+
+	return
+}
+
+// Restore reads the peptide index from the workspace, the index is optional and false is
+// returned when the database was not digested
+func (idx *Index) Restore() bool {
+
+	b, e := ioutil.ReadFile(sys.DigBin())
+	if e != nil {
+		return false
+	}
+
+	e = msgpack.Unmarshal(b, &idx)
+	if e != nil {
+		msg.DecodeMsgPck(e, "fatal")
+	}
+
+	return true
+}
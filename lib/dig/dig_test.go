@@ -0,0 +1,63 @@
+package dig
+
+import (
+	"testing"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/dat"
+	"philosopher/lib/met"
+)
+
+func TestDigest(t *testing.T) {
+
+	var trypsin bio.Enzyme
+	trypsin.Synth("trypsin")
+
+	tests := []struct {
+		name        string
+		seq         string
+		missed      int
+		specificity string
+		want        int
+	}{
+		{name: "Testing fully tryptic", seq: "AAKBBRCCKPDDK", missed: 0, specificity: Specific, want: 3},
+		{name: "Testing one missed cleavage", seq: "AAKBBRCCKPDDK", missed: 1, specificity: Specific, want: 5},
+		{name: "Testing semi-tryptic", seq: "AAKBBR", missed: 0, specificity: Semi, want: 10},
+		{name: "Testing non-specific", seq: "AAKB", missed: 0, specificity: NonSpecific, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Digest(tt.seq, trypsin, tt.missed, 1, 0, tt.specificity); len(got) != tt.want {
+				t.Errorf("Digest() = %v, want %v peptides", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+
+	records := []dat.Record{
+		{ID: "P1", GeneNames: "G1", Sequence: "PEPTIDEKSHAQEDK"},
+		{ID: "P2", GeneNames: "G1", Sequence: "SHAQEDKLTHEQK"},
+		{ID: "P3", GeneNames: "G2", Sequence: "LTHEQK"},
+		{ID: "rev_P3", Sequence: "KQEHTL", IsDecoy: true},
+	}
+
+	idx := Build(records, met.Digest{Enzyme: "trypsin", MinLength: 4}, bio.Registry(""))
+
+	if protein, gene := idx.IsUnique("PEPTIDEK"); !protein || !gene {
+		t.Errorf("IsUnique() PEPTIDEK = %v %v, want true true", protein, gene)
+	}
+
+	if protein, gene := idx.IsUnique("SHAQEDK"); protein || !gene {
+		t.Errorf("IsUnique() SHAQEDK = %v %v, want false true", protein, gene)
+	}
+
+	if idx.Theoretical["P2"] != 2 {
+		t.Errorf("Build() theoretical peptides for P2 = %v, want 2", idx.Theoretical["P2"])
+	}
+
+	if got := idx.Proteotypic("P2"); len(got) != 1 || got[0] != "SHAQEDK" {
+		t.Errorf("Proteotypic() = %v, want [SHAQEDK]", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"philosopher/lib/bio"
 	unix "philosopher/lib/ext/peptideprophet/unix"
 	wPeP "philosopher/lib/ext/peptideprophet/win"
 	"philosopher/lib/met"
@@ -29,6 +30,7 @@ type PeptideProphet struct {
 	LibgccDLL                   string
 	Zlib1DLL                    string
 	Mv                          string
+	Enzymes                     bio.EnzymeRegistry
 }
 
 // New constructor
@@ -63,6 +65,9 @@ func Run(m met.Data, args []string) met.Data {
 		m.PeptideProphet.Decoy = m.Database.Tag
 	}
 
+	// custom enzyme rules from the database command
+	pep.Enzymes = bio.Registry(m.Database.EnzymeRules)
+
 	// deploy the binaries
 	pep.Deploy(m.OS, m.Distro)
 
@@ -184,7 +189,11 @@ func interactParser(p PeptideProphet, params met.PeptideProphet, home, temp stri
 			}
 
 			if len(params.Enzyme) > 0 {
-				v := fmt.Sprintf("-E%s", params.Enzyme)
+				enzyme := params.Enzyme
+				if e, ok := p.Enzymes.Lookup(params.Enzyme); ok {
+					enzyme = e.TPP
+				}
+				v := fmt.Sprintf("-E%s", enzyme)
 				cmd.Args = append(cmd.Args, v)
 			}
 
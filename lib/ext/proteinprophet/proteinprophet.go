@@ -8,8 +8,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/sirupsen/logrus"
+
 	unix "philosopher/lib/ext/proteinprophet/unix"
 	wPoP "philosopher/lib/ext/proteinprophet/win"
+	"philosopher/lib/id"
+	"philosopher/lib/inf"
 	"philosopher/lib/met"
 	"philosopher/lib/msg"
 	"philosopher/lib/sys"
@@ -57,11 +61,19 @@ func Run(m met.Data, args []string) met.Data {
 		msg.NoParametersFound(errors.New("missing pep.xml"), "fatal")
 	}
 
-	// deploy the binaries
-	pop.Deploy(m.OS, m.Distro)
+	if m.ProteinProphet.Native {
+
+		// run the native protein validation
+		RunNative(m.ProteinProphet, m.Home, m.Temp, args)
+
+	} else {
 
-	// run ProteinProphet
-	pop.Execute(m.ProteinProphet, m.Home, m.Temp, args)
+		// deploy the binaries
+		pop.Deploy(m.OS, m.Distro)
+
+		// run ProteinProphet
+		pop.Execute(m.ProteinProphet, m.Home, m.Temp, args)
+	}
 
 	m.ProteinProphet.InputFiles = args
 
@@ -162,6 +174,42 @@ func (p ProteinProphet) Execute(params met.ProteinProphet, home, temp string, ar
 	return processedOutput
 }
 
+// RunNative validates the proteins without the ProteinProphet binary and writes the protXML
+func RunNative(params met.ProteinProphet, home, temp string, args []string) string {
+
+	var pepXML id.PepXML
+
+	for _, i := range args {
+
+		var p id.PepXML
+		p.Read(i)
+
+		if len(pepXML.Database) == 0 {
+			pepXML.Database = p.Database
+		}
+
+		pepXML.PeptideIdentification = append(pepXML.PeptideIdentification, p.PeptideIdentification...)
+	}
+
+	minProb := params.Minprob
+	if params.Accuracy {
+		minProb = 0
+	}
+
+	px := inf.ValidateProteins(pepXML, minProb, !params.NonSP)
+
+	output := fmt.Sprintf("%s%s%s.prot.xml", temp, string(filepath.Separator), params.Output)
+	px.Write(output)
+
+	logrus.Info("Validated ", len(px.ProteinSummary.ProteinGroup), " protein groups")
+
+	// copy to work directory
+	dest := fmt.Sprintf("%s%s%s", home, string(filepath.Separator), filepath.Base(output))
+	sys.CopyFile(output, dest)
+
+	return dest
+}
+
 func (p ProteinProphet) appendParams(params met.ProteinProphet, cmd *exec.Cmd) *exec.Cmd {
 
 	if params.ExcludeZ == true {
@@ -6,13 +6,19 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	unix "philosopher/lib/ext/ptmprophet/unix"
 	wPeP "philosopher/lib/ext/ptmprophet/win"
+	"philosopher/lib/id"
+	"philosopher/lib/loc"
 	"philosopher/lib/met"
 	"philosopher/lib/msg"
+	"philosopher/lib/mzn"
 	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
 )
 
 // PTMProphet is the main tool data configuration structure
@@ -40,17 +46,97 @@ func Run(m met.Data, args []string) met.Data {
 
 	var ptm = New(m.Temp)
 
-	// deploy the binaries
-	ptm.Deploy(m.OS, m.Distro)
+	if m.PTMProphet.Native {
+
+		// run the native site localization
+		RunNative(m.PTMProphet, args)
+
+	} else {
+
+		// deploy the binaries
+		ptm.Deploy(m.OS, m.Distro)
 
-	// run
-	ptm.Execute(m.PTMProphet, args)
+		// run
+		ptm.Execute(m.PTMProphet, args)
+	}
 
 	m.PTMProphet.InputFiles = args
 
 	return m
 }
 
+// RunNative scores the modification sites from the spectra without the PTMProphet binary, the
+// results are stored in the workspace and applied to the PSMs during filtering
+func RunNative(params met.PTMProphet, args []string) {
+
+	defs := loc.ParseDefinitions(params.Mods)
+	if len(defs) == 0 {
+		msg.Custom(errors.New("no modifications to localize"), "fatal")
+	}
+
+	var l = make(loc.Localization)
+	var sources []string
+
+	for _, i := range args {
+
+		var p id.PepXML
+		p.Read(i)
+
+		sources = append(sources, p.FileName)
+
+		spectra := readSpectra(p.SpectraFile, filepath.Dir(i))
+		if len(spectra) == 0 {
+			msg.Custom(fmt.Errorf("cannot find the spectra for %s", i), "warning")
+			continue
+		}
+
+		for k, v := range loc.Localize(p.PeptideIdentification, spectra, defs, float64(params.FragPPMTol), params.MaxFragZ) {
+			l[k] = v
+		}
+	}
+
+	l.Serialize(sources)
+
+	logrus.Info("Localized the modification sites on ", len(l), " PSMs")
+
+	return
+}
+
+// readSpectra loads the MS2 spectra by scan number, the spectra file is searched on the path given
+// by the pepXML and next to the pepXML file
+func readSpectra(f, dir string) map[int]mzn.Spectrum {
+
+	var spectra = make(map[int]mzn.Spectrum)
+
+	candidates := []string{f, filepath.Join(dir, filepath.Base(f)), filepath.Base(f)}
+
+	var file string
+	for _, i := range candidates {
+		if _, e := os.Stat(i); e == nil && len(f) > 0 {
+			file = i
+			break
+		}
+	}
+
+	if len(file) == 0 || !strings.EqualFold(filepath.Ext(file), ".mzML") {
+		return spectra
+	}
+
+	var ms mzn.MsData
+	ms.Read(file, true, false, true)
+
+	for _, i := range ms.Spectra {
+		scan, e := strconv.Atoi(i.Scan)
+		if e != nil {
+			continue
+		}
+		i.Decode()
+		spectra[scan] = i
+	}
+
+	return spectra
+}
+
 // Deploy PTMProphet binaries on binary directory
 func (p *PTMProphet) Deploy(os, distro string) {
 
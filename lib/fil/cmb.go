@@ -0,0 +1,254 @@
+package fil
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"philosopher/lib/id"
+	"philosopher/lib/mod"
+	"philosopher/lib/msg"
+	"philosopher/lib/spc"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EngineSet is a set of pepXML files produced by a single search engine
+type EngineSet struct {
+	Label string
+	Path  string
+}
+
+// ParseEngineSets reads the list of labelled pepXML sets informed as engine=path pairs separated by commas
+func ParseEngineSets(s string) []EngineSet {
+
+	var sets []EngineSet
+
+	for _, i := range strings.Split(s, ",") {
+
+		i = strings.TrimSpace(i)
+		if len(i) == 0 {
+			continue
+		}
+
+		pair := strings.SplitN(i, "=", 2)
+		if len(pair) != 2 || len(pair[0]) == 0 || len(pair[1]) == 0 {
+			msg.Custom(errors.New("the search engine sets must be informed as engine=path, got "+i), "fatal")
+		}
+
+		sets = append(sets, EngineSet{Label: pair[0], Path: pair[1]})
+	}
+
+	return sets
+}
+
+// readCombinedPepXMLInput reads the pepXML sets from different search engines and combines them into
+// a single PSM list where every spectrum is represented by its best supported peptide
+func readCombinedPepXMLInput(combine, decoyTag, temp string, models bool, maxRank int) (id.PepIDList, string) {
+
+	var engines = make(map[string]id.PepIDList)
+	var labels []string
+	var params []spc.Parameter
+	var modsIndex = make(map[string]mod.Modification)
+
+	sets := ParseEngineSets(combine)
+	if len(sets) < 2 {
+		msg.Custom(errors.New("at least two search engine sets are needed for combining results"), "fatal")
+	}
+
+	for _, i := range sets {
+
+		logrus.Info("Reading ", i.Label, " results")
+
+		pepIdent, p, m, _ := parsePepXMLInput(i.Path, decoyTag, temp, models, maxRank)
+
+		engines[i.Label] = append(engines[i.Label], pepIdent...)
+		labels = append(labels, i.Label)
+		params = append(params, p...)
+
+		for k, v := range m {
+			_, ok := modsIndex[k]
+			if !ok {
+				modsIndex[k] = v
+			}
+		}
+	}
+
+	pepIdent := CombineEngines(engines)
+
+	for _, i := range labels {
+
+		var exclusive, shared, conflicts int
+
+		for _, j := range pepIdent {
+			if hasEngine(j.SearchEngines, i) {
+				if len(j.SearchEngines) == 1 {
+					exclusive++
+				} else {
+					shared++
+				}
+			} else if hasEngine(j.ConflictingEngines, i) {
+				conflicts++
+			}
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"exclusive": exclusive,
+			"shared":    shared,
+			"conflicts": conflicts,
+		}).Info(fmt.Sprintf("%s contribution with %d PSMs", i, len(engines[i])))
+	}
+
+	serializeIdentifications(pepIdent, params, modsIndex, decoyTag)
+
+	return pepIdent, strings.Join(labels, "+")
+}
+
+// CombineEngines matches the PSMs from different search engines by spectrum, the peptides reported by
+// more than one engine are supported by all of them, and the combined score is the probability that at
+// least one of the engines is correct. Peptides in conflict are penalized by the best competing score
+func CombineEngines(engines map[string]id.PepIDList) id.PepIDList {
+
+	var labels []string
+	for i := range engines {
+		labels = append(labels, i)
+	}
+	sort.Strings(labels)
+
+	// spectrum -> peptide -> engine -> best PSM
+	var spectra = make(map[string]map[string]map[string]id.PeptideIdentification)
+	var spectrumOrder []string
+
+	for _, i := range labels {
+		for _, j := range engines[i] {
+
+			key := spectrumKey(j.Spectrum, j.HitRank)
+			peptide := strings.Replace(j.Peptide, "I", "L", -1)
+
+			_, ok := spectra[key]
+			if !ok {
+				spectra[key] = make(map[string]map[string]id.PeptideIdentification)
+				spectrumOrder = append(spectrumOrder, key)
+			}
+
+			_, ok = spectra[key][peptide]
+			if !ok {
+				spectra[key][peptide] = make(map[string]id.PeptideIdentification)
+			}
+
+			v, ok := spectra[key][peptide][i]
+			if !ok || j.Probability > v.Probability {
+				spectra[key][peptide][i] = j
+			}
+		}
+	}
+
+	var list id.PepIDList
+	var index uint32
+
+	for _, i := range spectrumOrder {
+
+		var peptides []string
+		for j := range spectra[i] {
+			peptides = append(peptides, j)
+		}
+		sort.Strings(peptides)
+
+		// the agreeing score is the probability that at least one engine is correct
+		var agreement = make(map[string]float64)
+		for _, j := range peptides {
+			missing := 1.0
+			for _, v := range spectra[i][j] {
+				missing *= (1 - v.Probability)
+			}
+			agreement[j] = 1 - missing
+		}
+
+		var winner string
+		var winnerScore = -1.0
+		var conflicting []string
+
+		for _, j := range peptides {
+
+			var competing float64
+			for _, k := range peptides {
+				if k != j && agreement[k] > competing {
+					competing = agreement[k]
+				}
+			}
+
+			score := agreement[j] * (1 - competing)
+			if score > winnerScore {
+				winnerScore = score
+				winner = j
+			}
+		}
+
+		// the representative PSM comes from the engine with the best score for the winner peptide
+		var psm id.PeptideIdentification
+		var engineList []string
+		for _, j := range labels {
+			v, ok := spectra[i][winner][j]
+			if !ok {
+				continue
+			}
+			engineList = append(engineList, j)
+			if len(engineList) == 1 || v.Probability > psm.Probability {
+				psm = v
+			}
+		}
+
+		for _, j := range peptides {
+			if j == winner {
+				continue
+			}
+			for k := range spectra[i][j] {
+				if !hasEngine(conflicting, k) {
+					conflicting = append(conflicting, k)
+				}
+			}
+		}
+		sort.Strings(conflicting)
+
+		var probabilities = make(map[string]float64)
+		for _, j := range engineList {
+			probabilities[j] = spectra[i][winner][j].Probability
+		}
+
+		psm.SearchEngines = engineList
+		psm.EngineProbabilities = probabilities
+		psm.ConflictingEngines = conflicting
+		psm.Probability = winnerScore
+		psm.Index = index
+
+		list = append(list, psm)
+		index++
+	}
+
+	return list
+}
+
+// spectrumKey removes the file name from the spectrum name so the same scan can be matched between
+// engines, the charge state and the hit rank are kept so chimeric and lower ranked hits are combined
+// with their counterparts instead of competing with the top hits
+func spectrumKey(s string, rank uint8) string {
+
+	if rank == 0 {
+		rank = 1
+	}
+
+	return fmt.Sprintf("%s#%d", strings.Split(s, "#")[0], rank)
+}
+
+// hasEngine checks if the engine is part of the list
+func hasEngine(list []string, engine string) bool {
+
+	for _, i := range list {
+		if i == engine {
+			return true
+		}
+	}
+
+	return false
+}
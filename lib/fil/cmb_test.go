@@ -0,0 +1,57 @@
+package fil
+
+import (
+	"testing"
+
+	"philosopher/lib/id"
+	"philosopher/lib/uti"
+)
+
+func TestCombineEngines(t *testing.T) {
+
+	engines := map[string]id.PepIDList{
+		"comet": {
+			{Spectrum: "run.00010.00010.2#comet.pep.xml", Peptide: "PEPTIDEK", HitRank: 1, Probability: 0.9},
+			{Spectrum: "run.00020.00020.2#comet.pep.xml", Peptide: "AAAK", HitRank: 1, Probability: 0.6},
+			{Spectrum: "run.00040.00040.2#comet.pep.xml", Peptide: "EEEK", HitRank: 1, Probability: 0.9},
+			{Spectrum: "run.00040.00040.2#comet.pep.xml", Peptide: "FFFK", HitRank: 2, Probability: 0.5},
+		},
+		"msfragger": {
+			{Spectrum: "run.00010.00010.2#fragger.pep.xml", Peptide: "PEPTLDEK", HitRank: 1, Probability: 0.8},
+			{Spectrum: "run.00020.00020.2#fragger.pep.xml", Peptide: "CCCK", HitRank: 1, Probability: 0.95},
+			{Spectrum: "run.00030.00030.2#fragger.pep.xml", Peptide: "DDDK", HitRank: 1, Probability: 0.7},
+			{Spectrum: "run.00030.00030.3#fragger.pep.xml", Peptide: "GGGK", HitRank: 1, Probability: 0.6},
+			{Spectrum: "run.00040.00040.2#fragger.pep.xml", Peptide: "FFFK", HitRank: 2, Probability: 0.4},
+		},
+	}
+
+	got := CombineEngines(engines)
+
+	if len(got) != 6 {
+		t.Fatalf("CombineEngines() got = %v PSMs, want %v", len(got), 6)
+	}
+
+	// agreement between engines, I and L are not distinguishable
+	if len(got[0].SearchEngines) != 2 || uti.ToFixed(got[0].Probability, 2) != 0.98 {
+		t.Errorf("CombineEngines() agreement got = %v %v, want 2 engines and 0.98", got[0].SearchEngines, got[0].Probability)
+	}
+
+	if got[0].EngineProbabilities["comet"] != 0.9 || got[0].EngineProbabilities["msfragger"] != 0.8 || len(got[0].SearchEngineScore) != 0 {
+		t.Errorf("CombineEngines() engine probabilities got = %v, scores = %v", got[0].EngineProbabilities, got[0].SearchEngineScore)
+	}
+
+	// conflict between engines, the best supported peptide wins and is penalized
+	if got[1].Peptide != "CCCK" || len(got[1].ConflictingEngines) != 1 || uti.ToFixed(got[1].Probability, 2) != 0.38 {
+		t.Errorf("CombineEngines() conflict got = %v %v %v, want CCCK, 1 conflicting engine and 0.38", got[1].Peptide, got[1].ConflictingEngines, got[1].Probability)
+	}
+
+	// the second ranked hit is combined with its counterpart and does not compete with the top hit
+	if got[2].Peptide != "EEEK" || got[2].Probability != 0.9 || got[3].Peptide != "FFFK" || uti.ToFixed(got[3].Probability, 2) != 0.7 {
+		t.Errorf("CombineEngines() ranks got = %v %v, %v %v", got[2].Peptide, got[2].Probability, got[3].Peptide, got[3].Probability)
+	}
+
+	// the same scan with different charge states is kept as different spectra
+	if got[4].Probability != 0.7 || got[5].Peptide != "GGGK" || len(got[5].ConflictingEngines) != 0 {
+		t.Errorf("CombineEngines() single engine got = %v, %v %v", got[4].Probability, got[5].Peptide, got[5].ConflictingEngines)
+	}
+}
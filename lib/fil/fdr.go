@@ -87,7 +87,7 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 			list = append(list, peplist[i])
 		}
 
-	} else if strings.EqualFold(level, "Ion") {
+	} else if strings.EqualFold(level, "Ion") || strings.EqualFold(level, "Modified Peptide") {
 
 		// 0 index means the one with highest score
 		for _, i := range input {
@@ -170,6 +170,116 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 	return cleanlist, minProb
 }
 
+// psmFDRFilter calculates the FDR at the PSM level, switching to the rank-aware competition
+// when the identifications contain lower ranked search hits
+func psmFDRFilter(input map[string]id.PepIDList, targetFDR float64, decoyTag string) (id.PepIDList, float64) {
+
+	for _, i := range input {
+		for _, j := range i {
+			if j.HitRank > 1 {
+				return RankAwareFDRFilter(input, targetFDR, decoyTag)
+			}
+		}
+	}
+
+	return PepXMLFDRFilter(input, targetFDR, "PSM", decoyTag)
+}
+
+// RankAwareFDRFilter calculates the PSM level FDR separately for each search hit rank. The top ranked
+// PSMs compete as usual, lower ranked PSMs are only considered for spectra with an accepted top ranked
+// PSM and a different peptide sequence. Spectra with more than one accepted PSM are marked as chimeric
+func RankAwareFDRFilter(input map[string]id.PepIDList, targetFDR float64, decoyTag string) (id.PepIDList, float64) {
+
+	var ranks = make(map[uint8]map[string]id.PepIDList)
+	var maxRank uint8
+
+	for k, v := range input {
+		for _, i := range v {
+
+			rank := i.HitRank
+			if rank < 1 {
+				rank = 1
+			}
+
+			_, ok := ranks[rank]
+			if !ok {
+				ranks[rank] = make(map[string]id.PepIDList)
+			}
+			ranks[rank][k] = append(ranks[rank][k], i)
+
+			if rank > maxRank {
+				maxRank = rank
+			}
+		}
+	}
+
+	var list id.PepIDList
+	var threshold float64
+
+	// spectrum -> accepted peptide sequences
+	var accepted = make(map[string]map[string]uint8)
+
+	for r := uint8(1); r <= maxRank; r++ {
+
+		var candidates = make(map[string]id.PepIDList)
+
+		for k, v := range ranks[r] {
+
+			peptides, ok := accepted[k]
+			if r > 1 && !ok {
+				continue
+			}
+
+			for _, i := range v {
+				_, ok := peptides[i.Peptide]
+				if !ok {
+					candidates[k] = append(candidates[k], i)
+				}
+			}
+		}
+
+		if len(candidates) == 0 {
+			continue
+		}
+
+		logrus.Info(fmt.Sprintf("Filtering rank %d PSMs", r))
+
+		filtered, t := PepXMLFDRFilter(candidates, targetFDR, "PSM", decoyTag)
+		if r == 1 {
+			threshold = t
+		}
+
+		for _, i := range filtered {
+
+			_, ok := accepted[i.Spectrum]
+			if !ok {
+				accepted[i.Spectrum] = make(map[string]uint8)
+			}
+			accepted[i.Spectrum][i.Peptide] = 0
+
+			list = append(list, i)
+		}
+	}
+
+	var chimeric int
+	for i := range list {
+		if len(accepted[list[i].Spectrum]) > 1 {
+			list[i].IsChimeric = true
+			if list[i].HitRank == 1 {
+				chimeric++
+			}
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"spectra": chimeric,
+	}).Info("Chimeric spectra")
+
+	sort.Sort(list)
+
+	return list, threshold
+}
+
 // PickedFDR employs the picked FDR strategy
 func PickedFDR(p id.ProtXML) id.ProtXML {
 
@@ -632,6 +742,213 @@ func ProtXMLFilter(p id.ProtXML, targetFDR, pepProb, protProb float64, isPicked,
 	return cleanlist
 }
 
+// ProteinPosteriorFDRFilter estimates the protein-level FDR ranking the razor proteins by their
+// posterior probabilities, the accepted list is cut where the q-value reaches the target FDR
+func ProteinPosteriorFDRFilter(p id.ProtXML, targetFDR float64, decoyTag string) id.ProtIDList {
+
+	var list id.ProtIDList
+
+	for _, i := range p.Groups {
+		for _, j := range i.Proteins {
+			if j.HasRazor == true {
+				list = append(list, j)
+			}
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Probability == list[j].Probability {
+			return list[i].ProteinName < list[j].ProteinName
+		}
+		return list[i].Probability > list[j].Probability
+	})
+
+	var isDecoy = make([]bool, len(list))
+	var probs = make([]float64, len(list))
+	for i := range list {
+		isDecoy[i] = cla.IsDecoyProtein(list[i], decoyTag)
+		probs[i] = list[i].Probability
+	}
+
+	accepted, calcFDR := qValueThreshold(probs, isDecoy, targetFDR)
+
+	var cleanlist id.ProtIDList
+	var minProb = 10.0
+	var t int
+	var d int
+
+	for i := range list {
+
+		if !accepted[i] {
+			continue
+		}
+
+		cleanlist = append(cleanlist, list[i])
+
+		if isDecoy[i] {
+			d++
+		} else {
+			t++
+		}
+
+		if list[i].Probability < minProb {
+			minProb = list[i].Probability
+		}
+	}
+
+	if len(cleanlist) == 0 {
+		msg.Custom(errors.New("The protein FDR filter didn't reach the desired threshold, try a higher threshold using the --prot parameter"), "error")
+	}
+
+	sort.Sort(cleanlist)
+
+	msg := fmt.Sprintf("Converged to %.2f %% FDR with %d Proteins", (calcFDR * 100), t)
+	logrus.WithFields(logrus.Fields{
+		"decoy":     d,
+		"total":     (t + d),
+		"threshold": minProb,
+	}).Info(msg)
+
+	return cleanlist
+}
+
+// GeneFDRFilter collapses the protein entries and isoforms by gene and estimates the gene-level FDR
+// using the target-decoy approach. Each gene is represented by its best scoring protein, proteins
+// without a gene annotation are kept as their own entries.
+func GeneFDRFilter(p id.ProtXML, proteinGenes map[string]string, targetFDR float64, decoyTag string) map[string]float64 {
+
+	var scores = make(map[string]float64)
+	var genes []string
+	var accepted = make(map[string]float64)
+
+	for _, i := range p.Groups {
+		for _, j := range i.Proteins {
+
+			gene, ok := proteinGenes[j.ProteinName]
+			if !ok || len(gene) == 0 {
+				gene = j.ProteinName
+			}
+
+			v, ok := scores[gene]
+			if !ok {
+				genes = append(genes, gene)
+				scores[gene] = j.Probability
+			} else if j.Probability > v {
+				scores[gene] = j.Probability
+			}
+		}
+	}
+
+	sort.Slice(genes, func(i, j int) bool {
+		if scores[genes[i]] == scores[genes[j]] {
+			return genes[i] < genes[j]
+		}
+		return scores[genes[i]] > scores[genes[j]]
+	})
+
+	var isDecoy = make([]bool, len(genes))
+	var probs = make([]float64, len(genes))
+	for i, j := range genes {
+		isDecoy[i] = cla.IsDecoy(j, decoyTag)
+		probs[i] = scores[j]
+	}
+
+	passed, calcFDR := qValueThreshold(probs, isDecoy, targetFDR)
+
+	var minProb = 10.0
+	var t int
+	var d int
+
+	for i, j := range genes {
+
+		if !passed[i] {
+			continue
+		}
+
+		accepted[j] = scores[j]
+
+		if isDecoy[i] {
+			d++
+		} else {
+			t++
+		}
+
+		if scores[j] < minProb {
+			minProb = scores[j]
+		}
+	}
+
+	if len(accepted) == 0 {
+		msg.Custom(errors.New("The gene FDR filter didn't reach the desired threshold, try a higher threshold using the --gene parameter"), "warning")
+	}
+
+	msg := fmt.Sprintf("Converged to %.2f %% FDR with %d Genes", (calcFDR * 100), t)
+	logrus.WithFields(logrus.Fields{
+		"decoy":     d,
+		"total":     (t + d),
+		"threshold": minProb,
+	}).Info(msg)
+
+	return accepted
+}
+
+// qValueThreshold walks a list ranked from the best to the worst score estimating the FDR for each
+// entry, the q-value is the minimum FDR observed from the bottom of the list up to the entry. Entries
+// with the same score share the same FDR so the cutoff only moves at score boundaries. The entries
+// with a q-value up to the target are accepted, the highest accepted q-value is returned
+func qValueThreshold(scores []float64, isDecoy []bool, targetFDR float64) ([]bool, float64) {
+
+	var targets float64
+	var decoys float64
+	var fdr = make([]float64, len(isDecoy))
+	var accepted = make([]bool, len(isDecoy))
+
+	for i := 0; i < len(isDecoy); {
+
+		j := i
+		for j < len(isDecoy) && scores[j] == scores[i] {
+			if isDecoy[j] {
+				decoys++
+			} else {
+				targets++
+			}
+			j++
+		}
+
+		value := 1.0
+		if targets > 0 {
+			value = decoys / targets
+		}
+
+		for k := i; k < j; k++ {
+			fdr[k] = value
+		}
+
+		i = j
+	}
+
+	var calcFDR float64
+	var qvalue = 1.0
+
+	for i := (len(isDecoy) - 1); i >= 0; i-- {
+
+		if fdr[i] < qvalue {
+			qvalue = fdr[i]
+		}
+
+		if uti.ToFixed(qvalue, 4) <= targetFDR {
+
+			accepted[i] = true
+
+			if qvalue > calcFDR {
+				calcFDR = qvalue
+			}
+		}
+	}
+
+	return accepted, calcFDR
+}
+
 // sequentialFDRControl estimates FDR levels by applying a second filter where all
 // proteins from the protein filtered list are matched against filtered PSMs
 func sequentialFDRControl(pep id.PepIDList, pro id.ProtIDList, psm, peptide, ion float64, decoyTag string) {
@@ -651,7 +968,7 @@ func sequentialFDRControl(pep id.PepIDList, pro id.ProtIDList, psm, peptide, ion
 		"ions":     len(uniqIons),
 	}).Info("Applying sequential FDR estimation")
 
-	filteredPSM, _ := PepXMLFDRFilter(uniqPsms, psm, "PSM", decoyTag)
+	filteredPSM, _ := psmFDRFilter(uniqPsms, psm, decoyTag)
 	filteredPSM.Serialize("psm")
 
 	filteredPeptides, _ := PepXMLFDRFilter(uniqPeps, peptide, "Peptide", decoyTag)
@@ -695,7 +1012,7 @@ func twoDFDRFilter(pep id.PepIDList, pro id.ProtIDList, psm, peptide, ion float6
 		"ions":     len(uniqIons),
 	}).Info("Second filtering results")
 
-	filteredPSM, _ := PepXMLFDRFilter(uniqPsms, psm, "PSM", decoyTag)
+	filteredPSM, _ := psmFDRFilter(uniqPsms, psm, decoyTag)
 	filteredPSM.Serialize("psm")
 
 	filteredPeptides, _ := PepXMLFDRFilter(uniqPeps, peptide, "Peptide", decoyTag)
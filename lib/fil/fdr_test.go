@@ -1,5 +1,117 @@
 package fil
 
+import (
+	"reflect"
+	"testing"
+
+	"philosopher/lib/id"
+)
+
+func TestGeneFDRFilter(t *testing.T) {
+
+	var p id.ProtXML
+	p.Groups = append(p.Groups, id.GroupIdentification{})
+	p.Groups[0].Proteins = id.ProtIDList{
+		{ProteinName: "sp|P1|A1", Probability: 0.99},
+		{ProteinName: "sp|P2|A2", Probability: 0.95},
+		{ProteinName: "sp|P3|B1", Probability: 0.90},
+		{ProteinName: "sp|P4|C1", Probability: 0.80},
+		{ProteinName: "rev_sp|P5|D1", Probability: 0.50},
+		{ProteinName: "sp|P6|E1", Probability: 0.40},
+	}
+
+	genes := map[string]string{
+		"sp|P1|A1":     "GENEA",
+		"sp|P2|A2":     "GENEA",
+		"sp|P3|B1":     "GENEB",
+		"rev_sp|P5|D1": "rev_GENED",
+	}
+
+	got := GeneFDRFilter(p, genes, 0.01, "rev_")
+
+	if len(got) != 3 {
+		t.Errorf("GeneFDRFilter() got = %v genes, want %v", len(got), 3)
+	}
+
+	if got["GENEA"] != 0.99 {
+		t.Errorf("GeneFDRFilter() gene score got = %v, want %v", got["GENEA"], 0.99)
+	}
+
+	if _, ok := got["sp|P4|C1"]; !ok {
+		t.Errorf("GeneFDRFilter() missing protein without gene annotation")
+	}
+}
+
+func Test_qValueThreshold(t *testing.T) {
+	tests := []struct {
+		name     string
+		scores   []float64
+		isDecoy  []bool
+		accepted []bool
+		fdr      float64
+	}{
+		{
+			name:     "Testing distinct scores",
+			scores:   []float64{0.9, 0.8, 0.7, 0.6},
+			isDecoy:  []bool{false, false, true, false},
+			accepted: []bool{true, true, false, false},
+			fdr:      0,
+		},
+		{
+			name:     "Testing a decoy tied with a target",
+			scores:   []float64{0.9, 0.8, 0.8, 0.6},
+			isDecoy:  []bool{false, false, true, false},
+			accepted: []bool{true, false, false, false},
+			fdr:      0,
+		},
+		{
+			name:     "Testing a decoy tied ahead of a target",
+			scores:   []float64{0.9, 0.8, 0.8, 0.6},
+			isDecoy:  []bool{false, true, false, false},
+			accepted: []bool{true, false, false, false},
+			fdr:      0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepted, fdr := qValueThreshold(tt.scores, tt.isDecoy, 0.01)
+			if !reflect.DeepEqual(accepted, tt.accepted) || fdr != tt.fdr {
+				t.Errorf("qValueThreshold() = %v, %v, want %v, %v", accepted, fdr, tt.accepted, tt.fdr)
+			}
+		})
+	}
+}
+
+func TestRankAwareFDRFilter(t *testing.T) {
+
+	psms := id.PepIDList{
+		{Spectrum: "s1", Peptide: "AAAK", Protein: "sp|P1|A", HitRank: 1, Probability: 0.99},
+		{Spectrum: "s2", Peptide: "BBBK", Protein: "sp|P2|B", HitRank: 1, Probability: 0.98},
+		{Spectrum: "s3", Peptide: "CCCK", Protein: "sp|P3|C", HitRank: 1, Probability: 0.97},
+		{Spectrum: "s4", Peptide: "DDDK", Protein: "rev_sp|P4|D", HitRank: 1, Probability: 0.10},
+		{Spectrum: "s1", Peptide: "EEEK", Protein: "sp|P5|E", HitRank: 2, Probability: 0.80},
+		{Spectrum: "s2", Peptide: "BBBK", Protein: "sp|P2|B", HitRank: 2, Probability: 0.70},
+		{Spectrum: "s3", Peptide: "GGGK", Protein: "rev_sp|P6|G", HitRank: 2, Probability: 0.05},
+		{Spectrum: "s4", Peptide: "FFFK", Protein: "sp|P7|F", HitRank: 2, Probability: 0.90},
+	}
+
+	got, threshold := RankAwareFDRFilter(GetUniquePSMs(psms), 0.01, "rev_")
+
+	if len(got) != 4 {
+		t.Fatalf("RankAwareFDRFilter() got = %v PSMs, want %v", len(got), 4)
+	}
+
+	if threshold != 0.97 {
+		t.Errorf("RankAwareFDRFilter() threshold got = %v, want %v", threshold, 0.97)
+	}
+
+	for _, i := range got {
+		if i.IsChimeric != (i.Spectrum == "s1") {
+			t.Errorf("RankAwareFDRFilter() chimeric flag for %s got = %v", i.Spectrum, i.IsChimeric)
+		}
+	}
+}
+
 // func TestPepXMLFDRFilter(t *testing.T) {
 
 // 	tes.SetupTestEnv()
@@ -14,9 +14,11 @@ import (
 	"philosopher/lib/dat"
 	"philosopher/lib/id"
 	"philosopher/lib/inf"
+	"philosopher/lib/loc"
 	"philosopher/lib/met"
 	"philosopher/lib/mod"
 	"philosopher/lib/msg"
+	"philosopher/lib/prf"
 	"philosopher/lib/qua"
 	"philosopher/lib/rep"
 	"philosopher/lib/spc"
@@ -45,15 +47,40 @@ func Run(f met.Data) met.Data {
 		f.Filter.TwoD = true
 	}
 
-	pepid, searchEngine := readPepXMLInput(f.Filter.Pex, f.Filter.Tag, f.Temp, f.Filter.Model, f.MSFragger.CalibrateMass)
+	var pepid id.PepIDList
+	var searchEngine string
+
+	if len(f.Filter.Mzid) > 0 {
+		pepid, searchEngine = readMzIdentMLInput(f.Filter.Mzid, f.Filter.Tag, f.Filter.Ranks)
+	} else if len(f.Filter.Tsv) > 0 {
+		pepid, searchEngine = readMSFraggerTSVInput(f.Filter.Tsv, f.Filter.Tag, fraggerFixedMods(f.MSFragger), f.Filter.Ranks)
+	} else if len(f.Filter.Combine) > 0 {
+		pepid, searchEngine = readCombinedPepXMLInput(f.Filter.Combine, f.Filter.Tag, f.Temp, f.Filter.Model, f.Filter.Ranks)
+	} else {
+		pepid, searchEngine = readPepXMLInput(f.Filter.Pex, f.Filter.Tag, f.Temp, f.Filter.Model, f.MSFragger.CalibrateMass, f.Filter.Ranks)
+	}
 
 	f.SearchEngine = searchEngine
 
+	// add the native site localization to the PSMs without PTMProphet results, the same for every
+	// input format, the serialized identifications are updated for the two-dimensional filter.
+	// Results from previous runs on other files are discarded
+	if l := loc.Restore(pepid); len(l) > 0 {
+		l.Apply(pepid)
+
+		pepxml.Restore()
+		l.Apply(pepxml.PeptideIdentification)
+		pepxml.Serialize()
+		pepxml = id.PepXML{}
+	}
+
 	psmT, pepT, ionT := processPeptideIdentifications(pepid, f.Filter.Tag, f.Filter.Mods, f.Filter.PsmFDR, f.Filter.PepFDR, f.Filter.IonFDR)
 	_ = psmT
 	_ = pepT
 	_ = ionT
 
+	processModifiedPeptideIdentifications(pepid, f.Filter.Tag, f.Filter.ModPepFDR)
+
 	if len(f.Filter.Pox) > 0 {
 
 		protXML := readProtXMLInput(f.Filter.Pox, f.Filter.Tag, f.Filter.Weight)
@@ -66,14 +93,14 @@ func Run(f met.Data) met.Data {
 			var filteredPSM id.PepIDList
 			filteredPSM.Restore("psm")
 
-			pepid, razorMap, coverMap := inf.ProteinInference(filteredPSM)
+			pepid, razorMap, coverMap, groups := inf.ProteinInference(filteredPSM)
 			filteredPSM = nil
 
 			pepid.Serialize("psm")
 			pepid.Serialize("pep")
 			pepid.Serialize("ion")
 
-			processProteinInferenceIdentifications(pepid, razorMap, coverMap, f.Filter.PtFDR, f.Filter.PepFDR, f.Filter.ProtProb, f.Filter.Picked, f.Filter.Tag)
+			processProteinInferenceIdentifications(pepid, razorMap, coverMap, groups, f.Filter.PtFDR, f.Filter.PepFDR, f.Filter.ProtProb, f.Filter.Picked, f.Filter.Bayes, f.Filter.Tag)
 		}
 
 	}
@@ -134,6 +161,7 @@ func Run(f met.Data) met.Data {
 
 		logrus.Info("Processing modifications")
 		e.AssembleModificationReport()
+		e.AssembleMassShifts()
 	} else {
 		e.MapMods(false)
 	}
@@ -143,6 +171,11 @@ func Run(f met.Data) met.Data {
 	e.AssemblePeptideReport(pept, f.Filter.Tag)
 	pept = nil
 
+	var mpep id.PepIDList
+	mpep.Restore("mpep")
+	e.AssembleModifiedPeptideReport(mpep, f.Filter.Tag)
+	mpep = nil
+
 	// evaluate modifications in data set
 	if f.Filter.Mapmods == true {
 		e.UpdateIonModCount()
@@ -177,6 +210,20 @@ func Run(f met.Data) met.Data {
 	logrus.Info("Calculating spectral counts")
 	e = qua.CalculateSpectralCounts(e)
 
+	if f.Filter.GeneFDR > 0 && (len(f.Filter.Pox) > 0 || f.Filter.Inference == true) {
+		logrus.Info("Processing gene identifications")
+		genes, proteinGenes := processGeneIdentifications(dtb, f.Filter.GeneFDR, f.Filter.Tag)
+		e.AssembleGeneReport(genes, proteinGenes, f.Filter.Tag)
+	}
+
+	if len(f.Filter.Glycan) > 0 {
+		logrus.Info("Assigning glycan compositions")
+		e.AssignGlycans(f.Filter.Glycan, f.Filter.GlycoDir, f.Filter.GlycanTol)
+	}
+
+	logrus.Info("Resolving modifications")
+	e.ResolveModifications()
+
 	logrus.Info("Saving")
 	e.SerializeGranular()
 
@@ -184,7 +231,38 @@ func Run(f met.Data) met.Data {
 }
 
 // readPepXMLInput reads one or more fies and organize the data into PSM list
-func readPepXMLInput(xmlFile, decoyTag, temp string, models bool, calibratedMass int) (id.PepIDList, string) {
+func readPepXMLInput(xmlFile, decoyTag, temp string, models bool, calibratedMass, maxRank int) (id.PepIDList, string) {
+
+	pepIdent, params, modsIndex, searchEngine := parsePepXMLInput(xmlFile, decoyTag, temp, models, maxRank)
+
+	serializeIdentifications(pepIdent, params, modsIndex, decoyTag)
+
+	return pepIdent, searchEngine
+}
+
+// serializeIdentifications creates a "fake" global pepXML comprising all data from the input files,
+// the spectra that match to both decoys and targets are promoted to target hits before saving
+func serializeIdentifications(pepIdent id.PepIDList, params []spc.Parameter, modsIndex map[string]mod.Modification, decoyTag string) {
+
+	var pepXML id.PepXML
+	pepXML.DecoyTag = decoyTag
+	pepXML.SearchParameters = params
+	pepXML.PeptideIdentification = pepIdent
+	pepXML.Modifications.Index = modsIndex
+
+	// promoting Spectra that matches to both decoys and targets to TRUE hits
+	pepXML.PromoteProteinIDs()
+
+	// serialize all pep files
+	sort.Sort(pepXML.PeptideIdentification)
+	pepXML.Serialize()
+
+	return
+}
+
+// parsePepXMLInput reads one or more pepXML files and collects the PSMs ranked up to maxRank, the search
+// parameters and the modifications
+func parsePepXMLInput(xmlFile, decoyTag, temp string, models bool, maxRank int) (id.PepIDList, []spc.Parameter, map[string]mod.Modification, string) {
 
 	var files = make(map[string]uint8)
 	var fileCheckList []string
@@ -233,6 +311,7 @@ func readPepXMLInput(xmlFile, decoyTag, temp string, models bool, calibratedMass
 	for i := range files {
 		var p id.PepXML
 		p.DecoyTag = decoyTag
+		p.MaxHitRank = uint8(maxRank)
 		p.Read(i)
 
 		params = p.SearchParameters
@@ -261,23 +340,179 @@ func readPepXMLInput(xmlFile, decoyTag, temp string, models bool, calibratedMass
 		searchEngine = p.SearchEngine
 	}
 
-	// create a "fake" global pepXML comprising all data
-	var pepXML id.PepXML
-	pepXML.DecoyTag = decoyTag
-	pepXML.SearchParameters = params
-	pepXML.PeptideIdentification = pepIdent
-	pepXML.Modifications.Index = modsIndex
+	return pepIdent, params, modsIndex, searchEngine
+}
 
-	// promoting Spectra that matches to both decoys and targets to TRUE hits
-	pepXML.PromoteProteinIDs()
+// readMzIdentMLInput reads one or more mzIdentML files and organize the data into PSM list
+func readMzIdentMLInput(mzidFile, decoyTag string, maxRank int) (id.PepIDList, string) {
 
-	// serialize all pep files
-	sort.Sort(pepXML.PeptideIdentification)
-	pepXML.Serialize()
+	var files []string
+	var pepIdent id.PepIDList
+	var params []spc.Parameter
+	var modsIndex = make(map[string]mod.Modification)
+	var searchEngine string
+
+	if strings.HasSuffix(strings.ToLower(mzidFile), ".mzid") {
+		files = append(files, mzidFile)
+	} else {
+		glob := fmt.Sprintf("%s%s*.mzid", mzidFile, string(filepath.Separator))
+		list, _ := filepath.Glob(glob)
+
+		if len(list) == 0 {
+			msg.NoParametersFound(errors.New("missing mzIdentML files"), "fatal")
+		}
+
+		for _, i := range list {
+			absPath, _ := filepath.Abs(i)
+			files = append(files, absPath)
+		}
+	}
+
+	for _, i := range files {
+		var p id.PepXML
+		p.DecoyTag = decoyTag
+		p.MaxHitRank = uint8(maxRank)
+		p.ReadMzIdentML(i)
+
+		params = p.SearchParameters
+
+		pepIdent = append(pepIdent, p.PeptideIdentification...)
+
+		for _, k := range p.Modifications.Index {
+			_, ok := modsIndex[k.Index]
+			if !ok {
+				modsIndex[k.Index] = k
+			}
+		}
+
+		searchEngine = p.SearchEngine
+	}
+
+	serializeIdentifications(pepIdent, params, modsIndex, decoyTag)
 
 	return pepIdent, searchEngine
 }
 
+// readMSFraggerTSVInput reads one or more MSFragger TSV files and organize the data into PSM list
+func readMSFraggerTSVInput(tsvFile, decoyTag string, fixedMods map[string]float64, maxRank int) (id.PepIDList, string) {
+
+	var files []string
+	var pepIdent id.PepIDList
+	var modsIndex = make(map[string]mod.Modification)
+	var searchEngine string
+
+	if strings.HasSuffix(strings.ToLower(tsvFile), ".tsv") {
+		files = append(files, tsvFile)
+	} else {
+		glob := fmt.Sprintf("%s%s*.tsv", tsvFile, string(filepath.Separator))
+		list, _ := filepath.Glob(glob)
+
+		// the folder may also contain reports that are not MSFragger results
+		for _, i := range list {
+			if id.IsMSFraggerTSV(i) {
+				absPath, _ := filepath.Abs(i)
+				files = append(files, absPath)
+			}
+		}
+
+		if len(files) == 0 {
+			msg.NoParametersFound(errors.New("missing MSFragger TSV files"), "fatal")
+		}
+	}
+
+	// the fixed modifications are not on the TSV files, the parameter file saved next to
+	// the results is merged with the ones defined on the workspace
+	params := filepath.Join(filepath.Dir(files[0]), "fragger.params")
+	if _, e := os.Stat(params); e == nil {
+		fixedMods = mergeFixedMods(fixedMods, id.ReadMSFraggerParams(params))
+	}
+
+	var hasFixed bool
+	for _, v := range fixedMods {
+		if v != 0 {
+			hasFixed = true
+		}
+	}
+
+	if !hasFixed {
+		logrus.Warning("no fixed modifications were found for the MSFragger TSV files, place the fragger.params file next to them to report the fixed modifications")
+	}
+
+	for _, i := range files {
+		var p id.PepXML
+		p.DecoyTag = decoyTag
+		p.MaxHitRank = uint8(maxRank)
+		p.ReadMSFraggerTSV(i, fixedMods)
+
+		pepIdent = append(pepIdent, p.PeptideIdentification...)
+
+		for _, k := range p.Modifications.Index {
+			_, ok := modsIndex[k.Index]
+			if !ok {
+				modsIndex[k.Index] = k
+			}
+		}
+
+		searchEngine = p.SearchEngine
+	}
+
+	serializeIdentifications(pepIdent, nil, modsIndex, decoyTag)
+
+	return pepIdent, searchEngine
+}
+
+// fraggerFixedMods collects the fixed modifications defined on the MSFragger parameters
+func fraggerFixedMods(p met.MSFragger) map[string]float64 {
+
+	var fixedMods = map[string]float64{
+		"n": p.AddNTermPeptide,
+		"c": p.AddCtermPeptide,
+		"A": p.AddAlanine,
+		"C": p.AddCysteine,
+		"D": p.AddAsparticAcid,
+		"E": p.AddGlutamicAcid,
+		"F": p.AddPhenylAlnine,
+		"G": p.AddGlycine,
+		"H": p.AddHistidine,
+		"I": p.AddIsoleucine,
+		"K": p.AddLysine,
+		"L": p.AddLeucine,
+		"M": p.AddMethionine,
+		"N": p.AddAsparagine,
+		"P": p.AddProline,
+		"Q": p.AddGlutamine,
+		"R": p.AddArginine,
+		"S": p.AddSerine,
+		"T": p.AddThreonine,
+		"V": p.AddValine,
+		"W": p.AddTryptophan,
+		"Y": p.AddTyrosine,
+	}
+
+	return fixedMods
+}
+
+// mergeFixedMods combines the workspace fixed modifications with the ones from the MSFragger
+// parameter file, the parameter file describes the search so it wins on conflicting values
+func mergeFixedMods(workspace, params map[string]float64) map[string]float64 {
+
+	var fixedMods = make(map[string]float64)
+
+	for k, v := range workspace {
+		fixedMods[k] = v
+	}
+
+	for k, v := range params {
+		w, ok := fixedMods[k]
+		if ok && w != 0 && w != v {
+			msg.Custom(fmt.Errorf("the fixed modification on %s is %.6f on the workspace and %.6f on fragger.params, using %.6f", k, w, v, v), "warning")
+		}
+		fixedMods[k] = v
+	}
+
+	return fixedMods
+}
+
 // processPeptideIdentifications reads and process pepXML
 func processPeptideIdentifications(p id.PepIDList, decoyTag, mods string, psm, peptide, ion float64) (float64, float64, float64) {
 
@@ -330,7 +565,7 @@ func processPeptideIdentifications(p id.PepIDList, decoyTag, mods string, psm, p
 		"ions":     len(uniqIons),
 	}).Info("Database search results")
 
-	filteredPSM, psmThreshold := PepXMLFDRFilter(uniqPsms, psm, "PSM", decoyTag)
+	filteredPSM, psmThreshold := psmFDRFilter(uniqPsms, psm, decoyTag)
 	filteredPSM.Serialize("psm")
 
 	filteredPeptides, peptideThreshold := PepXMLFDRFilter(uniqPeps, peptide, "Peptide", decoyTag)
@@ -347,6 +582,22 @@ func processPeptideIdentifications(p id.PepIDList, decoyTag, mods string, psm, p
 	return psmThreshold, peptideThreshold, ionThreshold
 }
 
+// processModifiedPeptideIdentifications applies the FDR to the modified peptide forms, the ions from
+// all charge states of the same modified peptide are grouped together
+func processModifiedPeptideIdentifications(p id.PepIDList, decoyTag string, modPep float64) float64 {
+
+	uniqModPeps := GetUniqueModifiedPeptides(p)
+
+	logrus.WithFields(logrus.Fields{
+		"modified peptides": len(uniqModPeps),
+	}).Info("Database search results")
+
+	filteredModPeptides, modPeptideThreshold := PepXMLFDRFilter(uniqModPeps, modPep, "Modified Peptide", decoyTag)
+	filteredModPeptides.Serialize("mpep")
+
+	return modPeptideThreshold
+}
+
 func ptmBasedPSMFiltering(uniqPsms map[string]id.PepIDList, targetFDR float64, decoyTag, mods string) {
 
 	// unmodified = no ptms
@@ -573,7 +824,7 @@ func ExtractIonsFromPSMs(p id.PepIDList) map[string]id.PepIDList {
 	uniqMap := make(map[string]id.PepIDList)
 
 	for _, i := range p {
-		ion := fmt.Sprintf("%s#%d#%.4f", i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
+		ion := prf.IonKey(i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
 		uniqMap[ion] = append(uniqMap[ion], i)
 	}
 
@@ -602,6 +853,24 @@ func GetUniquePeptides(p id.PepIDList) map[string]id.PepIDList {
 	return uniqMap
 }
 
+// GetUniqueModifiedPeptides selects only unique modified peptide forms for the given data structure
+func GetUniqueModifiedPeptides(p id.PepIDList) map[string]id.PepIDList {
+
+	uniqMap := make(map[string]id.PepIDList)
+
+	for _, i := range p {
+		key := i.ModifiedForm
+		uniqMap[key] = append(uniqMap[key], i)
+	}
+
+	// organize id list by score
+	for _, v := range uniqMap {
+		sort.Sort(v)
+	}
+
+	return uniqMap
+}
+
 // readProtXMLInput reads one or more fies and organize the data into PSM list
 func readProtXMLInput(xmlFile, decoyTag string, weight float64) id.ProtXML {
 
@@ -686,132 +955,139 @@ func processProteinIdentifications(p id.ProtXML, ptFDR, pepProb, protProb float6
 
 // processProteinInferenceIdentifications checks if pickedFDR ar razor options should be applied to given data set, if they do,
 // the inputed Philosopher inference data is processed before filtered.
-func processProteinInferenceIdentifications(psm id.PepIDList, razorMap map[string]string, coverMap map[string]float64, ptFDR, pepProb, protProb float64, isPicked bool, decoyTag string) {
+func processProteinInferenceIdentifications(psm id.PepIDList, razorMap map[string]string, coverMap map[string]float64, groups []inf.ProteinGroup, ptFDR, pepProb, protProb float64, isPicked, isBayes bool, decoyTag string) {
 
 	var t int
 	var d int
 	var proXML id.ProtXML
-	var proGrps id.GroupList
 	var proteinList = make(map[string]id.ProteinIdentification)
+	var peptideEntries = make(map[string][]string)
+	var parsimonious = make(map[string]uint8)
 
-	// build the ProtXML strct
-	grpID := id.GroupIdentification{
-		GroupNumber: 0,
-		Probability: 1.00,
-	}
+	proXML.DecoyTag = decoyTag
 
-	proGrps = append(proGrps, grpID)
+	// build the ProtXML struct, one group for each set of connected proteins
+	for _, i := range groups {
 
-	proXML.DecoyTag = decoyTag
-	proXML.Groups = proGrps
+		grp := id.GroupIdentification{
+			GroupNumber: i.Number,
+			Probability: 1.00,
+		}
+		proXML.Groups = append(proXML.Groups, grp)
 
-	for _, i := range psm {
-		_, ok := proteinList[i.Protein]
-		if !ok {
+		for _, j := range i.Proteins {
 
 			p := id.ProteinIdentification{
-				GroupNumber:    0,
-				GroupSiblingID: "a",
-				ProteinName:    i.Protein,
-				Picked:         0,
-				HasRazor:       false,
+				GroupNumber:              i.Number,
+				GroupSiblingID:           j.SiblingID,
+				ProteinName:              j.ProteinName,
+				IndistinguishableProtein: j.IndistinguishableProtein,
+				Length:                   "0",
+				PercentCoverage:          float32(coverMap[j.ProteinName]),
+				PctSpectrumIDs:           0.0,
+				GroupProbability:         1.00,
+				Confidence:               1.00,
+				Picked:                   0,
+				HasRazor:                 false,
+			}
+
+			proteinList[j.ProteinName] = p
+
+			for k := range j.Peptides {
+				peptideEntries[k] = append(peptideEntries[k], j.ProteinName)
 			}
 
-			proteinList[i.Protein] = p
+			if j.IsParsimonious {
+				parsimonious[j.ProteinName] = 0
+			}
 		}
 	}
 
-	for i := range proteinList {
-		if strings.HasPrefix(i, decoyTag) {
-			d++
-		} else {
-			t++
-		}
+	for i := range peptideEntries {
+		sort.Strings(peptideEntries[i])
 	}
 
-	// add the razor / non-razor marked proteins
-	var razorMarked = make(map[string]uint8)
+	// add the ions to every protein entry they map to, the shared ions are weighted by the
+	// number of proteins from the minimal set that could explain them
+	var ionIndex = make(map[string]map[string]int)
 	for _, i := range psm {
 
-		pro := proteinList[i.Protein]
-		razorProtein, ok := razorMap[i.Peptide]
+		var shared int
+		for _, j := range peptideEntries[i.Peptide] {
+			_, ok := parsimonious[j]
+			if ok {
+				shared++
+			}
+		}
+
+		ionForm := prf.IonKey(i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
 
-		if ok && pro.ProteinName == razorProtein {
+		for _, j := range peptideEntries[i.Peptide] {
 
-			pro.Length = "0"
-			pro.PercentCoverage = float32(coverMap[pro.ProteinName])
-			pro.PctSpectrumIDs = 0.0
-			pro.GroupProbability = 1.00
-			pro.Confidence = 1.00
-			pro.HasRazor = true
+			pro := proteinList[j]
 
-			if i.Probability > pro.Probability {
+			_, isParsimonious := parsimonious[j]
+			isRazor := razorMap[i.Peptide] == j
+
+			// proteins outside the minimal set are not supported by the evidence
+			if isParsimonious && i.Probability > pro.Probability {
 				pro.Probability = i.Probability
-				pro.TopPepProb = i.Probability
 			}
 
-			razorMarked[pro.ProteinName] = 0
+			if i.Probability > pro.TopPepProb {
+				pro.TopPepProb = i.Probability
+			}
 
-		} else {
+			if isRazor {
+				pro.HasRazor = true
+			}
 
-			_, ok := razorMarked[pro.ProteinName]
+			_, ok := ionIndex[j]
 			if !ok {
-				pro.Length = "0"
-				pro.PercentCoverage = float32(coverMap[pro.ProteinName])
-				pro.PctSpectrumIDs = 0.0
-				pro.GroupProbability = 1.00
-				pro.Confidence = 1.00
-				pro.HasRazor = false
-
-				if i.Probability > pro.Probability {
-					pro.Probability = i.Probability
-					pro.TopPepProb = i.Probability
-				}
-
+				ionIndex[j] = make(map[string]int)
 			}
-		}
-
-		proteinList[i.Protein] = pro
-	}
-
-	// add the ions
-	//var addedIon = make(map[string]uint8)
-	for _, i := range psm {
 
-		//ionForm := fmt.Sprintf("%s#%d#%.4f", i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
-		pro := proteinList[i.Protein]
-		razorProtein, ok := razorMap[i.Peptide]
+			idx, ok := ionIndex[j][ionForm]
+			if ok {
 
-		if ok && pro.ProteinName == razorProtein {
+				pro.PeptideIons[idx].NumberOfInstances++
+				if i.Probability > pro.PeptideIons[idx].InitialProbability {
+					pro.PeptideIons[idx].InitialProbability = i.Probability
+				}
 
-			pro.UniqueStrippedPeptides = append(pro.UniqueStrippedPeptides, i.Peptide)
-			pro.TotalNumberPeptides++
+				proteinList[j] = pro
+				continue
+			}
 
 			pep := id.PeptideIonIdentification{
-				PeptideSequence:      i.Peptide,
-				ModifiedPeptide:      i.ModifiedPeptide,
-				Charge:               i.AssumedCharge,
-				Weight:               1,
-				GroupWeight:          0,
-				CalcNeutralPepMass:   i.CalcNeutralPepMass,
-				SharedParentProteins: len(i.AlternativeProteins),
-				Razor:                1,
+				PeptideSequence:    i.Peptide,
+				ModifiedPeptide:    i.ModifiedPeptide,
+				Charge:             i.AssumedCharge,
+				InitialProbability: i.Probability,
+				GroupWeight:        0,
+				CalcNeutralPepMass: i.CalcNeutralPepMass,
+				NumberOfInstances:  1,
+				Razor:              0,
 			}
 
-			pep.PeptideParentProtein = i.AlternativeProteins
+			if isParsimonious && shared > 0 {
+				pep.Weight = 1 / float64(shared)
+			}
 
-			pep.NumberOfInstances++
+			if isRazor {
+				pep.Razor = 1
+			}
 
-			if i.Probability > pep.InitialProbability {
-				pep.InitialProbability = i.Probability
+			for _, k := range peptideEntries[i.Peptide] {
+				if k != j {
+					pep.PeptideParentProtein = append(pep.PeptideParentProtein, k)
+				}
 			}
+			pep.SharedParentProteins = len(pep.PeptideParentProtein)
 
-			if len(i.AlternativeProteins) < 2 {
+			if len(pep.PeptideParentProtein) == 0 {
 				pep.IsNondegenerateEvidence = true
 				pep.IsUnique = true
-			} else {
-				pep.IsNondegenerateEvidence = false
-				pep.IsUnique = false
 			}
 
 			pep.Modifications.Index = make(map[string]mod.Modification)
@@ -819,69 +1095,75 @@ func processProteinInferenceIdentifications(psm id.PepIDList, razorMap map[strin
 				pep.Modifications.Index[k] = v
 			}
 
-			pro.IndistinguishableProtein = i.AlternativeProteins
-			pro.HasRazor = true
-			pro.PeptideIons = append(pro.PeptideIons, pep)
-
-			proteinList[i.Protein] = pro
-
-		} else {
-
-			pro.UniqueStrippedPeptides = append(pro.UniqueStrippedPeptides, i.Peptide)
-			pro.TotalNumberPeptides++
-
-			pep := id.PeptideIonIdentification{
-				PeptideSequence:      i.Peptide,
-				ModifiedPeptide:      i.ModifiedPeptide,
-				Charge:               i.AssumedCharge,
-				Weight:               0,
-				GroupWeight:          0,
-				CalcNeutralPepMass:   i.CalcNeutralPepMass,
-				SharedParentProteins: len(i.AlternativeProteins),
-				Razor:                0,
+			var hasPeptide bool
+			for _, k := range pro.UniqueStrippedPeptides {
+				if k == i.Peptide {
+					hasPeptide = true
+					break
+				}
 			}
 
-			if i.Probability > pep.InitialProbability {
-				pep.InitialProbability = i.Probability
+			if !hasPeptide {
+				pro.UniqueStrippedPeptides = append(pro.UniqueStrippedPeptides, i.Peptide)
 			}
 
-			pep.PeptideParentProtein = i.AlternativeProteins
+			pro.TotalNumberPeptides++
+			pro.PeptideIons = append(pro.PeptideIons, pep)
+			ionIndex[j][ionForm] = len(pro.PeptideIons) - 1
 
-			pep.NumberOfInstances++
+			proteinList[j] = pro
+		}
+	}
 
-			if len(i.AlternativeProteins) < 2 {
-				pep.IsNondegenerateEvidence = true
-				pep.IsUnique = true
-			} else {
-				pep.IsNondegenerateEvidence = false
-				pep.IsUnique = false
-			}
+	// the Bayesian posteriors replace the peptide based protein probabilities
+	if isBayes == true {
 
-			pep.Modifications.Index = make(map[string]mod.Modification)
-			for k, v := range i.Modifications.Index {
-				pep.Modifications.Index[k] = v
-			}
+		logrus.Info("Calculating protein posterior probabilities")
 
-			pro.IndistinguishableProtein = i.AlternativeProteins
-			pro.PeptideIons = append(pro.PeptideIons, pep)
+		posteriors, groupPosteriors := inf.ProteinPosteriors(psm, groups)
 
-			proteinList[i.Protein] = pro
+		for i := range proXML.Groups {
+			proXML.Groups[i].Probability = groupPosteriors[proXML.Groups[i].GroupNumber]
+		}
 
+		for k, v := range proteinList {
+			pro := v
+			pro.Probability = posteriors[k]
+			pro.GroupProbability = groupPosteriors[pro.GroupNumber]
+			proteinList[k] = pro
 		}
 	}
 
-	for _, i := range proteinList {
-		proXML.Groups[0].Proteins = append(proXML.Groups[0].Proteins, i)
+	for i := range proXML.Groups {
+		for _, j := range groups[i].Proteins {
+
+			pro := proteinList[j.ProteinName]
+			proXML.Groups[i].Proteins = append(proXML.Groups[i].Proteins, pro)
+
+			if pro.HasRazor {
+				if strings.HasPrefix(pro.ProteinName, decoyTag) {
+					d++
+				} else {
+					t++
+				}
+			}
+		}
 	}
 
 	// tagget / decoy / threshold
 	logrus.WithFields(logrus.Fields{
 		"target": t,
 		"decoy":  d,
+		"groups": len(groups),
 	}).Info("Protein inference results")
 
 	// run the FDR filter for proteins
-	pid := ProtXMLFilter(proXML, ptFDR, pepProb, protProb, false, true, decoyTag)
+	var pid id.ProtIDList
+	if isBayes == true {
+		pid = ProteinPosteriorFDRFilter(proXML, ptFDR, decoyTag)
+	} else {
+		pid = ProtXMLFilter(proXML, ptFDR, pepProb, protProb, false, true, decoyTag)
+	}
 
 	// save results on meta folder
 	proXML.Serialize()
@@ -890,6 +1172,33 @@ func processProteinInferenceIdentifications(psm id.PepIDList, razorMap map[strin
 	return
 }
 
+// processGeneIdentifications maps the inferred proteins to their genes using the database annotation
+// and applies the gene-level FDR. Decoy entries are collapsed into decoy genes carrying the decoy tag.
+func processGeneIdentifications(dtb dat.Base, geneFDR float64, decoyTag string) (map[string]float64, map[string]string) {
+
+	var proXML id.ProtXML
+	proXML.Restore()
+
+	var proteinGenes = make(map[string]string)
+	for _, i := range dtb.Records {
+
+		genes := strings.Fields(i.GeneNames)
+		if len(genes) == 0 {
+			continue
+		}
+
+		if i.IsDecoy {
+			proteinGenes[i.PartHeader] = decoyTag + genes[0]
+		} else {
+			proteinGenes[i.PartHeader] = genes[0]
+		}
+	}
+
+	genes := GeneFDRFilter(proXML, proteinGenes, geneFDR, decoyTag)
+
+	return genes, proteinGenes
+}
+
 // proteinProfile ...
 func proteinProfile(p id.ProtXML) (t, d int) {
 
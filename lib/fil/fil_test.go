@@ -1,6 +1,7 @@
 package fil
 
 import (
+	"os"
 	"philosopher/lib/id"
 	"philosopher/lib/sys"
 	"philosopher/lib/tes"
@@ -39,7 +40,7 @@ func Test_readPepXMLInput(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 
-			got, got1 := readPepXMLInput(tt.args.xmlFile, tt.args.decoyTag, tt.args.temp, tt.args.models, tt.args.calibratedMass)
+			got, got1 := readPepXMLInput(tt.args.xmlFile, tt.args.decoyTag, tt.args.temp, tt.args.models, tt.args.calibratedMass, 1)
 			pepIDList = got
 
 			if !reflect.DeepEqual(len(got), tt.want) {
@@ -349,3 +350,108 @@ func Test_readProtXMLInput(t *testing.T) {
 		})
 	}
 }
+
+func Test_mergeFixedMods(t *testing.T) {
+	tests := []struct {
+		name      string
+		workspace map[string]float64
+		params    map[string]float64
+		want      map[string]float64
+	}{
+		{
+			name:      "Testing modifications from both sources",
+			workspace: map[string]float64{"C": 57.021464, "n": 0},
+			params:    map[string]float64{"K": 229.162932, "n": 229.162932},
+			want:      map[string]float64{"C": 57.021464, "K": 229.162932, "n": 229.162932},
+		},
+		{
+			name:      "Testing conflicting modifications",
+			workspace: map[string]float64{"C": 57.021464},
+			params:    map[string]float64{"C": 0},
+			want:      map[string]float64{"C": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeFixedMods(tt.workspace, tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeFixedMods() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func modifiedPeptideTestList() id.PepIDList {
+	return id.PepIDList{
+		{Spectrum: "run1.100.100.2", ModifiedForm: "PEPTIDEK", Protein: "sp|P1|A", Probability: 0.90},
+		{Spectrum: "run1.101.101.3", ModifiedForm: "PEPTIDEK", Protein: "sp|P1|A", Probability: 0.99},
+		{Spectrum: "run1.102.102.2", ModifiedForm: "PEPTIDEK[+42.0106]", Protein: "sp|P1|A", Probability: 0.95},
+		{Spectrum: "run1.103.103.2", ModifiedForm: "PEPTIDEK[+42.0470]", Protein: "sp|P1|A", Probability: 0.80},
+		{Spectrum: "run1.104.104.2", ModifiedForm: "EDITPEPK", Protein: "rev_sp|P2|B", Probability: 0.70},
+		{Spectrum: "run1.105.105.2", ModifiedForm: "PEPTIDER", Protein: "sp|P3|C", Probability: 0.60},
+	}
+}
+
+func Test_GetUniqueModifiedPeptides(t *testing.T) {
+
+	got := GetUniqueModifiedPeptides(modifiedPeptideTestList())
+
+	tests := []struct {
+		name  string
+		form  string
+		size  int
+		first float64
+	}{
+		{
+			name:  "Testing charge states grouped by form",
+			form:  "PEPTIDEK",
+			size:  2,
+			first: 0.99,
+		},
+		{
+			name:  "Testing forms with the same nominal mass",
+			form:  "PEPTIDEK[+42.0470]",
+			size:  1,
+			first: 0.80,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := got[tt.form]
+			if len(v) != tt.size || v[0].Probability != tt.first {
+				t.Errorf("GetUniqueModifiedPeptides() = %v, want %d PSMs with best probability %v", v, tt.size, tt.first)
+			}
+		})
+	}
+
+	if len(got) != 5 {
+		t.Errorf("GetUniqueModifiedPeptides() = %d forms, want 5", len(got))
+	}
+}
+
+func Test_processModifiedPeptideIdentifications(t *testing.T) {
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+
+	os.Chdir(t.TempDir())
+	os.Mkdir(sys.MetaDir(), sys.FilePermission())
+
+	// the decoy is ranked below three target forms, the forms above it are accepted
+	threshold := processModifiedPeptideIdentifications(modifiedPeptideTestList(), "rev_", 0.01)
+	if threshold != 0.80 {
+		t.Errorf("processModifiedPeptideIdentifications() = %v, want 0.80", threshold)
+	}
+
+	var mpep id.PepIDList
+	mpep.Restore("mpep")
+
+	var forms = make(map[string]float64)
+	for _, i := range mpep {
+		forms[i.ModifiedForm] = i.Probability
+	}
+
+	want := map[string]float64{"PEPTIDEK": 0.99, "PEPTIDEK[+42.0106]": 0.95, "PEPTIDEK[+42.0470]": 0.80}
+	if !reflect.DeepEqual(forms, want) {
+		t.Errorf("accepted forms = %v, want %v", forms, want)
+	}
+}
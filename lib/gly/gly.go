@@ -0,0 +1,282 @@
+// Package gly (Glycosylation) assigns glycan compositions and N-glycosites to glycopeptides
+package gly
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/msg"
+)
+
+// Monosaccharide residue masses
+var monosaccharides = map[string]float64{
+	"HexNAc": 203.079373,
+	"Hex":    162.052824,
+	"Fuc":    146.057909,
+	"NeuAc":  291.095417,
+	"NeuGc":  307.090331,
+}
+
+// monosaccharideOrder is the order used to write the compositions
+var monosaccharideOrder = []string{"HexNAc", "Hex", "Fuc", "NeuAc", "NeuGc"}
+
+// OxoniumIons are the glycan fragment ions observed on the MS2 spectra of glycopeptides
+var OxoniumIons = []float64{
+	204.086649, // HexNAc
+	138.054950, // HexNAc fragment
+	186.076084, // HexNAc - H2O
+	168.065520, // HexNAc - 2H2O
+	163.060101, // Hex
+	366.139472, // HexHexNAc
+	274.092128, // NeuAc - H2O
+	292.102693, // NeuAc
+	512.197381, // HexHexNAcFuc
+}
+
+// IsotopeErrors are the precursor isotope errors considered when matching the compositions
+var IsotopeErrors = []int{0, 1, 2, 3, -1}
+
+var compositionRegex = regexp.MustCompile(`([A-Za-z]+)\(?(\d+)\)?`)
+
+// Composition is a glycan composition
+type Composition struct {
+	Name     string
+	Residues map[string]int
+	Mass     float64
+}
+
+// Assignment is the glycan composition matched to a PSM mass difference
+type Assignment struct {
+	Composition Composition
+	Isotope     int
+	Error       float64
+}
+
+// ParseComposition reads compositions written as HexNAc(2)Hex(5)Fuc(1) or HexNAc2Hex5Fuc1
+func ParseComposition(s string) (Composition, error) {
+
+	var c Composition
+	c.Residues = make(map[string]int)
+
+	s = strings.TrimSpace(s)
+
+	matches := compositionRegex.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return c, fmt.Errorf("invalid glycan composition %s", s)
+	}
+
+	// every character must belong to a monosaccharide
+	var consumed int
+	for _, i := range matches {
+		consumed += len(i[0])
+	}
+	if consumed != len(s) {
+		return c, fmt.Errorf("invalid glycan composition %s", s)
+	}
+
+	for _, i := range matches {
+
+		mass, ok := monosaccharides[i[1]]
+		if !ok {
+			return c, fmt.Errorf("unknown monosaccharide %s", i[1])
+		}
+
+		n, _ := strconv.Atoi(i[2])
+		c.Residues[i[1]] += n
+		c.Mass += mass * float64(n)
+	}
+
+	c.Name = c.String()
+
+	return c, nil
+}
+
+// String writes the composition in the standard monosaccharide order
+func (c Composition) String() string {
+
+	var b strings.Builder
+
+	for _, i := range monosaccharideOrder {
+		if n := c.Residues[i]; n > 0 {
+			b.WriteString(fmt.Sprintf("%s(%d)", i, n))
+		}
+	}
+
+	return b.String()
+}
+
+// ReadDatabase reads the glycan database, one composition per line with an optional mass on the
+// second tab-separated column, lines starting with # are ignored
+func ReadDatabase(f string) []Composition {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(errors.New("Cannot open the glycan database"), "fatal")
+	}
+	defer file.Close()
+
+	var list []Composition
+	var seen = make(map[string]uint8)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+
+		c, e := ParseComposition(fields[0])
+		if e != nil {
+			// headers and unsupported entries are skipped
+			continue
+		}
+
+		if len(fields) > 1 {
+			mass, e := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+			if e == nil {
+				c.Mass = mass
+			}
+		}
+
+		if _, ok := seen[c.Name]; ok {
+			continue
+		}
+		seen[c.Name] = 0
+
+		list = append(list, c)
+	}
+
+	if e := scanner.Err(); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	if len(list) == 0 {
+		msg.Custom(errors.New("The glycan database has no valid compositions"), "fatal")
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Mass < list[j].Mass })
+
+	return list
+}
+
+// Match finds the composition closest to the mass difference within the ppm tolerance of the
+// precursor mass, considering the isotope errors
+func Match(massdiff, precursor float64, comps []Composition, tol float64) (Assignment, bool) {
+
+	var best Assignment
+	var found bool
+
+	limit := precursor * tol * 1e-6
+
+	for _, iso := range IsotopeErrors {
+
+		target := massdiff - float64(iso)*bio.C13
+
+		for _, c := range comps {
+
+			diff := math.Abs(target - c.Mass)
+			if diff > limit {
+				continue
+			}
+
+			// lower isotope errors are preferred when the errors are the same
+			if !found || diff < best.Error {
+				best = Assignment{Composition: c, Isotope: iso, Error: diff}
+				found = true
+			}
+		}
+	}
+
+	return best, found
+}
+
+// Sequons returns the 0-based positions of the asparagines on N-X-S/T motifs where X is not a proline
+func Sequons(seq string) []int {
+
+	var list []int
+
+	for i := 0; i+2 < len(seq); i++ {
+		if seq[i] == 'N' && seq[i+1] != 'P' && (seq[i+2] == 'S' || seq[i+2] == 'T') {
+			list = append(list, i)
+		}
+	}
+
+	return list
+}
+
+// Glycosites returns the sequon positions covered by the peptide, the protein sequence is used to
+// find the motifs that extend after the peptide C-terminus. Positions are 1-based on the protein,
+// no position is returned when the peptide is not found on the protein
+func Glycosites(peptide, protein string) []int {
+
+	var sites []int
+
+	offset := strings.Index(protein, peptide)
+	if offset < 0 || len(peptide) == 0 {
+		return sites
+	}
+
+	end := offset + len(peptide) + 2
+	if end > len(protein) {
+		end = len(protein)
+	}
+
+	for _, i := range Sequons(protein[offset:end]) {
+		if i < len(peptide) {
+			sites = append(sites, offset+i+1)
+		}
+	}
+
+	return sites
+}
+
+// OxoniumCount returns how many oxonium ions are found on the spectrum within the ppm tolerance
+func OxoniumCount(mz []float64, tol float64) int {
+
+	sorted := make([]float64, len(mz))
+	copy(sorted, mz)
+	sort.Float64s(sorted)
+
+	var count int
+	for _, i := range OxoniumIons {
+		if hasPeak(sorted, i, tol) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// HasOxonium checks the spectrum for the HexNAc oxonium ion and at least another oxonium ion
+func HasOxonium(mz []float64, tol float64) bool {
+
+	sorted := make([]float64, len(mz))
+	copy(sorted, mz)
+	sort.Float64s(sorted)
+
+	if !hasPeak(sorted, OxoniumIons[0], tol) {
+		return false
+	}
+
+	return OxoniumCount(sorted, tol) >= 2
+}
+
+// hasPeak searches the sorted peaks for the m/z within the ppm tolerance
+func hasPeak(sorted []float64, mz, tol float64) bool {
+
+	delta := mz * tol * 1e-6
+	i := sort.SearchFloat64s(sorted, mz-delta)
+
+	return i < len(sorted) && sorted[i] <= mz+delta
+}
@@ -0,0 +1,129 @@
+package gly
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"philosopher/lib/bio"
+)
+
+func TestParseComposition(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		comp    string
+		want    string
+		mass    float64
+		wantErr bool
+	}{
+		{name: "Testing parentheses notation", comp: "HexNAc(2)Hex(5)", want: "HexNAc(2)Hex(5)", mass: 1216.422863},
+		{name: "Testing compact notation", comp: "Hex5HexNAc2", want: "HexNAc(2)Hex(5)", mass: 1216.422863},
+		{name: "Testing fucosylated sialylated glycan", comp: "HexNAc(4)Hex(5)Fuc(1)NeuAc(2)", want: "HexNAc(4)Hex(5)Fuc(1)NeuAc(2)", mass: 2350.830355},
+		{name: "Testing unknown monosaccharide", comp: "HexNAc(2)Kdn(1)", wantErr: true},
+		{name: "Testing invalid text", comp: "Composition", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, e := ParseComposition(tt.comp)
+			if (e != nil) != tt.wantErr {
+				t.Fatalf("ParseComposition() error = %v, wantErr %v", e, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Name != tt.want || math.Abs(got.Mass-tt.mass) > 0.0001 {
+				t.Errorf("ParseComposition() = %v %.6f, want %v %.6f", got.Name, got.Mass, tt.want, tt.mass)
+			}
+		})
+	}
+}
+
+func TestReadDatabase(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "gly")
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "glycans.txt")
+	ioutil.WriteFile(f, []byte("# N-glycans\nComposition\tMass\nHexNAc(2)Hex(5)\nHexNAc(2)Hex(9)\t1864.634\nHex5HexNAc2\n"), 0644)
+
+	comps := ReadDatabase(f)
+	if len(comps) != 2 {
+		t.Fatalf("ReadDatabase() = %v, want 2 compositions", comps)
+	}
+
+	if comps[1].Name != "HexNAc(2)Hex(9)" || comps[1].Mass != 1864.634 {
+		t.Errorf("ReadDatabase() = %v", comps[1])
+	}
+}
+
+func TestMatch(t *testing.T) {
+
+	man5, _ := ParseComposition("HexNAc(2)Hex(5)")
+	man6, _ := ParseComposition("HexNAc(2)Hex(6)")
+	comps := []Composition{man5, man6}
+
+	got, ok := Match(man5.Mass, 3000, comps, 10)
+	if !ok || got.Composition.Name != man5.Name || got.Isotope != 0 {
+		t.Errorf("Match() = %v", got)
+	}
+
+	got, ok = Match(man6.Mass+bio.C13, 3000, comps, 10)
+	if !ok || got.Composition.Name != man6.Name || got.Isotope != 1 {
+		t.Errorf("Match() with isotope error = %v", got)
+	}
+
+	if _, ok = Match(man5.Mass+0.5, 3000, comps, 10); ok {
+		t.Errorf("Match() should not match outside the tolerance")
+	}
+}
+
+func TestGlycosites(t *testing.T) {
+
+	protein := "MKNGSAANPTLLNVTEK"
+
+	tests := []struct {
+		name    string
+		peptide string
+		want    []int
+	}{
+		{name: "Testing sequon inside the peptide", peptide: "NGSAANPTLLNVTEK", want: []int{3, 13}},
+		{name: "Testing proline exclusion", peptide: "AANPTLLK", want: nil},
+		{name: "Testing sequon after the C-terminus", peptide: "ANPTLLN", want: []int{13}},
+		{name: "Testing peptide outside the protein", peptide: "WNYTR", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Glycosites(tt.peptide, protein)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Glycosites() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Glycosites() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHasOxonium(t *testing.T) {
+
+	glyco := []float64{150.1, 204.0868, 366.1396, 500.3}
+	hexose := []float64{163.0601, 366.1396}
+	plain := []float64{150.1, 500.3}
+
+	if !HasOxonium(glyco, 20) {
+		t.Errorf("HasOxonium() should find the HexNAc ions")
+	}
+
+	if HasOxonium(hexose, 20) || HasOxonium(plain, 20) {
+		t.Errorf("HasOxonium() requires the HexNAc oxonium ion")
+	}
+
+	if OxoniumCount(glyco, 20) != 2 {
+		t.Errorf("OxoniumCount() = %d, want 2", OxoniumCount(glyco, 20))
+	}
+}
@@ -0,0 +1,449 @@
+package id
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/mod"
+	"philosopher/lib/msg"
+	"philosopher/lib/psi"
+	"philosopher/lib/spc"
+	"philosopher/lib/uti"
+)
+
+// ReadMzIdentML parses a mzIdentML 1.1 or 1.2 file and organizes the identifications
+// in the same structure used for the pepXML files
+func (p *PepXML) ReadMzIdentML(f string) {
+
+	var xml psi.MzIdentML
+	xml.Parse(f)
+
+	p.FileName = path.Base(f)
+	p.Modifications.Index = make(map[string]mod.Modification)
+
+	if len(xml.AnalysisSoftwareList.AnalysisSoftware) > 0 {
+		sw := xml.AnalysisSoftwareList.AnalysisSoftware[0]
+		p.SearchEngine = sw.SoftwareName.CVParam.Name
+		if len(p.SearchEngine) == 0 {
+			p.SearchEngine = sw.Name
+		}
+	}
+
+	if len(xml.DataCollection.Inputs.SearchDatabase) > 0 {
+		p.Database = xml.DataCollection.Inputs.SearchDatabase[0].Location
+	}
+
+	// the source file name is used to compose the spectrum names
+	var sources = make(map[string]string)
+	for _, i := range xml.DataCollection.Inputs.SpectraData {
+		base := filepath.Base(strings.Replace(i.Location, "\\", "/", -1))
+		sources[i.ID] = strings.TrimSuffix(base, filepath.Ext(base))
+		p.SpectraFile = i.Location
+	}
+
+	// map fixed and variable modifications from the search protocol
+	var fixedMods = make(map[string]string)
+	for _, i := range xml.AnalysisProtocolCollection.SpectrumIdentificationProtocol {
+
+		for _, j := range i.ModificationParams.SearchModification {
+
+			variable := "Y"
+			if j.FixedMod == "true" {
+				variable = "N"
+			}
+
+			// terminal modifications are registered using the same names given to the
+			// terminal modifications found on the peptides
+			if term := searchModificationTerminus(j); len(term) > 0 {
+				fixedMods[fmt.Sprintf("%s#%.4f", term, j.MassDelta)] = variable
+				continue
+			}
+
+			for _, k := range strings.Split(j.Residues, " ") {
+				key := fmt.Sprintf("%s#%.4f", k, j.MassDelta)
+				fixedMods[key] = variable
+			}
+		}
+
+		for _, j := range i.AdditionalSearchParams.UserParam {
+			par := spc.Parameter{
+				Name:  j.Name,
+				Value: j.Value,
+			}
+			p.SearchParameters = append(p.SearchParameters, par)
+		}
+	}
+
+	var dbSequences = make(map[string]string)
+	for _, i := range xml.SequenceCollection.DBSequence {
+		dbSequences[i.ID] = i.Accession
+	}
+
+	var peptides = make(map[string]psi.Peptide)
+	for _, i := range xml.SequenceCollection.Peptide {
+		peptides[i.ID] = i
+	}
+
+	var evidences = make(map[string]psi.PeptideEvidence)
+	for _, i := range xml.SequenceCollection.PeptideEvidence {
+		evidences[i.ID] = i
+	}
+
+	var psmlist PepIDList
+	var index uint32
+
+	maxRank := p.MaxHitRank
+	if maxRank < 1 {
+		maxRank = 1
+	}
+
+	for _, i := range xml.DataCollection.AnalysisData.SpectrumIdentificationList {
+		for _, j := range i.SpectrumIdentificationResult {
+
+			// only one peptide is kept for each rank, up to the maximum rank
+			var ranks = make(map[uint8]bool)
+
+			for _, k := range j.SpectrumIdentificationItem {
+
+				rank := k.Rank
+				if rank == 0 {
+					rank = 1
+				}
+
+				if rank > maxRank || ranks[rank] {
+					continue
+				}
+
+				pep, ok := peptides[k.PeptideRef]
+				if !ok {
+					continue
+				}
+
+				psm := processSpectrumIdentificationItem(j, k, pep, evidences, dbSequences, fixedMods, sources[j.SpectraDataRef], p.FileName, p.DecoyTag)
+				psm.Index = index
+				psm.HitRank = rank
+				ranks[rank] = true
+
+				// register the modifications found on the PSM on the file index
+				for _, m := range psm.Modifications.Index {
+					if m.Type != "Assigned" {
+						continue
+					}
+
+					key := fmt.Sprintf("%s#%.4f", m.AminoAcid, m.MonoIsotopicMass)
+					_, ok := p.Modifications.Index[key]
+					if !ok {
+						gm := m
+						gm.Index = key
+						gm.Position = ""
+						gm.IsobaricMods = make(map[string]float64)
+						p.Modifications.Index[key] = gm
+					}
+				}
+
+				psmlist = append(psmlist, psm)
+				index++
+			}
+		}
+	}
+
+	p.PeptideIdentification = psmlist
+	p.Prophet = "mzIdentML"
+
+	if len(psmlist) == 0 {
+		msg.NoPSMFound(errors.New(f), "warning")
+	}
+
+	return
+}
+
+// processSpectrumIdentificationItem converts one mzIdentML identification item into a PSM
+func processSpectrumIdentificationItem(sir psi.SpectrumIdentificationResult, sii psi.SpectrumIdentificationItem, pep psi.Peptide, evidences map[string]psi.PeptideEvidence, dbSequences map[string]string, fixedMods map[string]string, source, fileName, decoyTag string) PeptideIdentification {
+
+	var psm PeptideIdentification
+	psm.Modifications.Index = make(map[string]mod.Modification)
+	psm.AlternativeProteinsIndexed = make(map[string]int)
+	psm.SearchEngineScore = make(map[string]float64)
+
+	psm.SpectrumFile = fileName
+	psm.Peptide = strings.TrimSpace(pep.PeptideSequence.Value)
+	psm.AssumedCharge = sii.ChargeState
+	psm.HitRank = sii.Rank
+
+	charge := float64(sii.ChargeState)
+	psm.CalcNeutralPepMass = (sii.CalculatedMassToCharge * charge) - (charge * bio.Proton)
+	psm.PrecursorNeutralMass = (sii.ExperimentalMassToCharge * charge) - (charge * bio.Proton)
+	psm.UncalibratedPrecursorNeutralMass = psm.PrecursorNeutralMass
+	psm.Massdiff = uti.ToFixed(psm.PrecursorNeutralMass-psm.CalcNeutralPepMass, 4)
+
+	var title string
+	for _, i := range sir.CVParam {
+		switch i.Accession {
+		case "MS:1000796":
+			title = i.Value
+		case "MS:1001115":
+			psm.Scan, _ = strconv.Atoi(i.Value)
+		case "MS:1000016":
+			rt, _ := strconv.ParseFloat(i.Value, 64)
+			if strings.EqualFold(i.UnitName, "minute") {
+				rt = rt * 60
+			}
+			psm.RetentionTime = rt
+		}
+	}
+
+	if psm.Scan == 0 {
+		psm.Scan = scanFromSpectrumID(sir.SpectrumID)
+	}
+
+	// the spectrum name follows the same convention used by the pepXML files so the
+	// quantification steps can find the corresponding scans on the mzML files
+	if strings.Count(title, ".") >= 3 {
+		psm.Spectrum = strings.Split(title, " ")[0]
+	} else {
+		psm.Spectrum = fmt.Sprintf("%s.%05d.%05d.%d", source, psm.Scan, psm.Scan, psm.AssumedCharge)
+	}
+
+	for x, i := range sii.PeptideEvidenceRef {
+
+		evi, ok := evidences[i.PeptideEvidenceRef]
+		if !ok {
+			continue
+		}
+
+		protein := dbSequences[evi.DBSequenceRef]
+
+		// decoys flagged on the evidence are named with the decoy tag so they are
+		// recognized as decoys by the filter
+		if evi.IsDecoy == "true" && len(decoyTag) > 0 && !strings.HasPrefix(protein, decoyTag) {
+			protein = decoyTag + protein
+		}
+
+		if x == 0 {
+			psm.Protein = protein
+			psm.PrevAA = evi.Pre
+			psm.NextAA = evi.Post
+		} else if protein != psm.Protein {
+			psm.AlternativeProteins = append(psm.AlternativeProteins, protein)
+			psm.AlternativeProteinsIndexed[protein]++
+		}
+	}
+
+	psm.NumberTotalProteins = uint16(len(psm.AlternativeProteins) + 1)
+
+	for _, i := range sii.CVParam {
+
+		value, e := uti.ParseFloat(i.Value)
+		if e != nil {
+			continue
+		}
+
+		psm.SearchEngineScore[i.Name] = value
+
+		switch i.Accession {
+		case "MS:1002052", "MS:1001330", "MS:1002257":
+			// MS-GF:SpecEValue, X!Tandem:expect, Comet:expectation value
+			psm.Expectation = value
+		case "MS:1001331":
+			// X!Tandem:hyperscore
+			psm.Hyperscore = value
+		case "MS:1002252":
+			psm.Xcorr = value
+		case "MS:1002253":
+			psm.DeltaCN = value
+		case "MS:1002255":
+			psm.SPScore = value
+		case "MS:1002256":
+			psm.SPRank = value
+		case "MS:1002357":
+			// PSM-level probability
+			psm.Probability = value
+		}
+	}
+
+	for _, i := range sii.UserParam {
+		value, e := uti.ParseFloat(i.Value)
+		if e == nil {
+			psm.SearchEngineScore[i.Name] = value
+		}
+	}
+
+	// search engines that do not report a probability are scored using the expectation
+	// value, the transformation is monotonic so the target-decoy competition is preserved
+	if psm.Probability == 0 && psm.Expectation > 0 {
+		psm.Probability = 1 / (1 + psm.Expectation)
+	}
+
+	psm.mapModsFromMzIdentML(pep, fixedMods)
+
+	// to be able to accept multiple entries with the same spectrum name, we fuse the
+	// file name to the spectrum name. This is going to be used as an identifiable attribute
+	// Before reporting the filtered PSMs, the file name is removed from the spectrum name.
+	psm.Spectrum = fmt.Sprintf("%s#%s", psm.Spectrum, fileName)
+
+	return psm
+}
+
+// mapModsFromMzIdentML receives a mzIdentML peptide with modifications and adds them to the given struct
+func (p *PeptideIdentification) mapModsFromMzIdentML(pep psi.Peptide, fixedMods map[string]string) {
+
+	var nTerm float64
+	var cTerm float64
+	var residues = make(map[int]float64)
+
+	for _, i := range pep.Modification {
+
+		location, _ := strconv.Atoi(i.Location)
+
+		var name string
+		var accession string
+		for _, j := range i.CVParam {
+			if strings.HasPrefix(j.Accession, "UNIMOD:") || len(name) == 0 {
+				name = j.Name
+				accession = j.Accession
+			}
+		}
+
+		m := mod.Modification{
+			ID:           accession,
+			Name:         name,
+			Type:         "Assigned",
+			MassDiff:     i.MonoIsotopicMassDelta,
+			AverageMass:  i.AvgMassDelta,
+			Variable:     "Y",
+			IsobaricMods: make(map[string]float64),
+		}
+
+		if location == 0 {
+
+			m.AminoAcid = "N-term"
+			m.Terminus = "n"
+			m.MonoIsotopicMass = bio.Hydrogen + i.MonoIsotopicMassDelta
+			m.Index = fmt.Sprintf("N-term#%.4f", m.MonoIsotopicMass)
+			nTerm += m.MonoIsotopicMass
+
+			v, ok := fixedMods[fmt.Sprintf("N-term#%.4f", i.MonoIsotopicMassDelta)]
+			if ok {
+				m.Variable = v
+			}
+
+		} else if location > len(p.Peptide) {
+
+			m.AminoAcid = "C-term"
+			m.Terminus = "c"
+			m.MonoIsotopicMass = (bio.Water - bio.Hydrogen) + i.MonoIsotopicMassDelta
+			m.Index = fmt.Sprintf("C-term#%.4f", m.MonoIsotopicMass)
+			cTerm += m.MonoIsotopicMass
+
+			v, ok := fixedMods[fmt.Sprintf("C-term#%.4f", i.MonoIsotopicMassDelta)]
+			if ok {
+				m.Variable = v
+			}
+
+		} else {
+
+			aa := string(p.Peptide[location-1])
+			m.AminoAcid = aa
+			m.Position = strconv.Itoa(location)
+			m.MonoIsotopicMass = bio.NewFromCode(aa).MonoIsotopeMass + i.MonoIsotopicMassDelta
+			m.Index = fmt.Sprintf("%s#%d#%.4f", aa, location, m.MonoIsotopicMass)
+			residues[location] = m.MonoIsotopicMass
+
+			v, ok := fixedMods[fmt.Sprintf("%s#%.4f", aa, i.MonoIsotopicMassDelta)]
+			if ok {
+				m.Variable = v
+			}
+		}
+
+		p.Modifications.Index[m.Index] = m
+	}
+
+	// rebuild the modified sequence using the pepXML notation
+	if len(pep.Modification) > 0 {
+
+		var modPep strings.Builder
+
+		if nTerm != 0 {
+			modPep.WriteString(fmt.Sprintf("n[%.0f]", nTerm))
+		}
+
+		for i, j := range p.Peptide {
+			modPep.WriteRune(j)
+			v, ok := residues[i+1]
+			if ok {
+				modPep.WriteString(fmt.Sprintf("[%.0f]", v))
+			}
+		}
+
+		if cTerm != 0 {
+			modPep.WriteString(fmt.Sprintf("c[%.0f]", cTerm))
+		}
+
+		p.ModifiedPeptide = modPep.String()
+	}
+
+	p.ModifiedForm = p.Modifications.ProForma(p.Peptide)
+
+	// the observed mass shift is kept the same way it is done for pepXML
+	key := fmt.Sprintf("%.4f", p.Massdiff)
+	_, ok := p.Modifications.Index[key]
+	if !ok {
+		m := mod.Modification{
+			Index:        key,
+			Name:         "Unknown",
+			Type:         "Observed",
+			MassDiff:     p.Massdiff,
+			IsobaricMods: make(map[string]float64),
+		}
+		p.Modifications.Index[key] = m
+	}
+
+	return
+}
+
+// searchModificationTerminus returns the terminus name of a terminal search modification
+func searchModificationTerminus(m psi.SearchModification) string {
+
+	for _, i := range m.SpecificityRules {
+		for _, j := range i.CVParam {
+			switch j.Accession {
+			case "MS:1001189", "MS:1002057":
+				// peptide and protein N-term
+				return "N-term"
+			case "MS:1001190", "MS:1002058":
+				// peptide and protein C-term
+				return "C-term"
+			}
+		}
+	}
+
+	return ""
+}
+
+// scanFromSpectrumID extracts the scan number from the native spectrum identifiers
+func scanFromSpectrumID(s string) int {
+
+	scanReg := regexp.MustCompile(`scan=(\d+)`)
+	indexReg := regexp.MustCompile(`index=(\d+)`)
+
+	m := scanReg.FindStringSubmatch(s)
+	if m != nil {
+		scan, _ := strconv.Atoi(m[1])
+		return scan
+	}
+
+	// MGF indexes are zero-based
+	m = indexReg.FindStringSubmatch(s)
+	if m != nil {
+		index, _ := strconv.Atoi(m[1])
+		return index + 1
+	}
+
+	return 0
+}
@@ -0,0 +1,191 @@
+package id
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/mod"
+	"philosopher/lib/psi"
+)
+
+func Test_scanFromSpectrumID(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want int
+	}{
+		{
+			name: "Testing Thermo native ID",
+			args: "controllerType=0 controllerNumber=1 scan=60782",
+			want: 60782,
+		},
+		{
+			name: "Testing MGF index",
+			args: "index=9",
+			want: 10,
+		},
+		{
+			name: "Testing unknown ID",
+			args: "spectrum 1",
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scanFromSpectrumID(tt.args); got != tt.want {
+				t.Errorf("scanFromSpectrumID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mzidTestItem() (psi.SpectrumIdentificationResult, psi.SpectrumIdentificationItem, psi.Peptide, map[string]psi.PeptideEvidence, map[string]string) {
+
+	pep := psi.Peptide{
+		ID:              "pep1",
+		PeptideSequence: psi.PeptideSequence{Value: "PEPMCK"},
+		Modification: []psi.Modification{
+			{Location: "0", MonoIsotopicMassDelta: 42.010565, CVParam: []psi.CVParam{{Accession: "UNIMOD:1", Name: "Acetyl"}}},
+			{Location: "4", MonoIsotopicMassDelta: 15.994915, CVParam: []psi.CVParam{{Accession: "UNIMOD:35", Name: "Oxidation"}}},
+			{Location: "5", MonoIsotopicMassDelta: 57.021464, CVParam: []psi.CVParam{{Accession: "UNIMOD:4", Name: "Carbamidomethyl"}}},
+		},
+	}
+
+	evidences := map[string]psi.PeptideEvidence{
+		"ev1": {ID: "ev1", DBSequenceRef: "db1", Pre: "K", Post: "A"},
+		"ev2": {ID: "ev2", DBSequenceRef: "db2", Pre: "R", Post: "L"},
+		"ev3": {ID: "ev3", DBSequenceRef: "db3", Pre: "R", Post: "-", IsDecoy: "true"},
+	}
+
+	dbSequences := map[string]string{"db1": "sp|P1|A", "db2": "sp|P2|B", "db3": "sp|P3|C"}
+
+	sir := psi.SpectrumIdentificationResult{
+		SpectrumID: "controllerType=0 controllerNumber=1 scan=1001",
+		CVParam:    []psi.CVParam{{Accession: "MS:1000016", Value: "10.5", UnitName: "minute"}},
+	}
+
+	sii := psi.SpectrumIdentificationItem{
+		ChargeState:              2,
+		Rank:                     1,
+		CalculatedMassToCharge:   400.5,
+		ExperimentalMassToCharge: 400.501,
+		PeptideRef:               "pep1",
+		PeptideEvidenceRef:       []psi.PeptideEvidenceRef{{PeptideEvidenceRef: "ev1"}, {PeptideEvidenceRef: "ev2"}, {PeptideEvidenceRef: "ev3"}},
+		CVParam:                  []psi.CVParam{{Accession: "MS:1002052", Name: "MS-GF:SpecEValue", Value: "0.25"}},
+		UserParam:                []psi.UserParam{{Name: "custom score", Value: "3.5"}},
+	}
+
+	return sir, sii, pep, evidences, dbSequences
+}
+
+func Test_processSpectrumIdentificationItem(t *testing.T) {
+
+	sir, sii, pep, evidences, dbSequences := mzidTestItem()
+
+	psm := processSpectrumIdentificationItem(sir, sii, pep, evidences, dbSequences, map[string]string{}, "run1", "run1.mzid", "rev_")
+
+	if psm.Spectrum != "run1.01001.01001.2#run1.mzid" || psm.Scan != 1001 || psm.RetentionTime != 630 {
+		t.Errorf("spectrum = %v, scan = %v, retention time = %v", psm.Spectrum, psm.Scan, psm.RetentionTime)
+	}
+
+	if psm.Peptide != "PEPMCK" || psm.AssumedCharge != 2 || psm.HitRank != 1 {
+		t.Errorf("peptide = %v, charge = %v, rank = %v", psm.Peptide, psm.AssumedCharge, psm.HitRank)
+	}
+
+	if math.Abs(psm.CalcNeutralPepMass-(801-2*bio.Proton)) > 1e-6 || psm.Massdiff != 0.002 {
+		t.Errorf("calculated mass = %v, mass difference = %v", psm.CalcNeutralPepMass, psm.Massdiff)
+	}
+
+	if psm.Protein != "sp|P1|A" || psm.PrevAA != "K" || psm.NextAA != "A" || psm.NumberTotalProteins != 3 {
+		t.Errorf("protein = %v %v %v, total = %v", psm.Protein, psm.PrevAA, psm.NextAA, psm.NumberTotalProteins)
+	}
+
+	// decoys flagged on the peptide evidence receive the decoy tag
+	if len(psm.AlternativeProteins) != 2 || psm.AlternativeProteins[0] != "sp|P2|B" || psm.AlternativeProteins[1] != "rev_sp|P3|C" {
+		t.Errorf("alternative proteins = %v", psm.AlternativeProteins)
+	}
+
+	if psm.Expectation != 0.25 || psm.Probability != 0.8 || psm.SearchEngineScore["custom score"] != 3.5 {
+		t.Errorf("expectation = %v, probability = %v, scores = %v", psm.Expectation, psm.Probability, psm.SearchEngineScore)
+	}
+}
+
+func Test_mapModsFromMzIdentML(t *testing.T) {
+
+	_, _, pep, _, _ := mzidTestItem()
+
+	fixedMods := map[string]string{
+		"C#57.0215":      "N",
+		"M#15.9949":      "Y",
+		"N-term#42.0106": "N",
+	}
+
+	var psm PeptideIdentification
+	psm.Peptide = "PEPMCK"
+	psm.Modifications.Index = make(map[string]mod.Modification)
+	psm.mapModsFromMzIdentML(pep, fixedMods)
+
+	if psm.ModifiedPeptide != "n[43]PEPM[147]C[160]K" {
+		t.Errorf("ModifiedPeptide = %v", psm.ModifiedPeptide)
+	}
+
+	// the ProForma form keeps the exact mass shifts instead of the rounded pepXML masses
+	if psm.ModifiedForm != "[+42.0106]-PEPM[+15.9949]C[+57.0215]K" {
+		t.Errorf("ModifiedForm = %v", psm.ModifiedForm)
+	}
+
+	tests := []struct {
+		index    string
+		name     string
+		aa       string
+		position string
+		variable string
+	}{
+		{"N-term#43.0184", "Acetyl", "N-term", "", "N"},
+		{"M#4#147.0354", "Oxidation", "M", "4", "Y"},
+		{"C#5#160.0306", "Carbamidomethyl", "C", "5", "N"},
+	}
+
+	for _, tt := range tests {
+		m, ok := psm.Modifications.Index[tt.index]
+		if !ok {
+			t.Errorf("modification %v not found on %v", tt.index, psm.Modifications.Index)
+			continue
+		}
+		if m.Name != tt.name || m.AminoAcid != tt.aa || m.Position != tt.position || m.Variable != tt.variable || m.Type != "Assigned" {
+			t.Errorf("modification %v = %+v", tt.index, m)
+		}
+	}
+}
+
+func Test_searchModificationTerminus(t *testing.T) {
+	tests := []struct {
+		name string
+		args psi.SearchModification
+		want string
+	}{
+		{
+			name: "Testing peptide N-term",
+			args: psi.SearchModification{Residues: ".", SpecificityRules: []psi.SpecificityRules{{CVParam: []psi.CVParam{{Accession: "MS:1001189"}}}}},
+			want: "N-term",
+		},
+		{
+			name: "Testing protein C-term",
+			args: psi.SearchModification{Residues: ".", SpecificityRules: []psi.SpecificityRules{{CVParam: []psi.CVParam{{Accession: "MS:1002058"}}}}},
+			want: "C-term",
+		},
+		{
+			name: "Testing residue",
+			args: psi.SearchModification{Residues: "C"},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchModificationTerminus(tt.args); got != tt.want {
+				t.Errorf("searchModificationTerminus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -36,6 +36,7 @@ type PepXML struct {
 	Prophet               string
 	Modifications         mod.Modifications
 	Models                []spc.DistributionPoint
+	MaxHitRank            uint8
 	PeptideIdentification PepIDList
 }
 
@@ -48,6 +49,7 @@ type PeptideIdentification struct {
 	Peptide                          string
 	Protein                          string
 	ModifiedPeptide                  string
+	ModifiedForm                     string
 	AlternativeProteins              []string
 	AlternativeProteinsIndexed       map[string]int
 	AssumedCharge                    uint8
@@ -83,6 +85,11 @@ type PeptideIdentification struct {
 	Intensity                        float64
 	IonMobility                      float64
 	IsRejected                       uint8
+	SearchEngineScore                map[string]float64
+	SearchEngines                    []string
+	EngineProbabilities              map[string]float64
+	ConflictingEngines               []string
+	IsChimeric                       bool
 	Modifications                    mod.Modifications
 }
 
@@ -216,8 +223,16 @@ func (p *PepXML) Read(f string) {
 		var psmlist PepIDList
 		sq := mpa.MsmsRunSummary.SpectrumQuery
 		for _, i := range sq {
-			psm := processSpectrumQuery(i, massDeviation, p.Modifications, p.DecoyTag, p.FileName)
-			psmlist = append(psmlist, psm)
+			psms := processSpectrumQuery(i, massDeviation, p.Modifications, p.DecoyTag, p.FileName, p.MaxHitRank)
+			psmlist = append(psmlist, psms...)
+		}
+
+		// the search hits from the same spectrum query share the query index, when lower ranked
+		// hits are kept each PSM gets its own index, the same way it is done for the other inputs
+		if p.MaxHitRank > 1 {
+			for i := range psmlist {
+				psmlist[i].Index = uint32(i)
+			}
 		}
 
 		p.PeptideIdentification = psmlist
@@ -235,31 +250,49 @@ func (p *PepXML) Read(f string) {
 	return
 }
 
-func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.Modifications, decoyTag, FileName string) PeptideIdentification {
+// processSpectrumQuery converts the search hits from a spectrum query into PSMs, only the hits
+// ranked up to maxRank are kept, hits without a rank are considered top ranked
+func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.Modifications, decoyTag, FileName string, maxRank uint8) PepIDList {
 
-	var psm PeptideIdentification
-	psm.Modifications.Index = make(map[string]mod.Modification)
-	psm.AlternativeProteinsIndexed = make(map[string]int)
+	var psms PepIDList
 
-	psm.Index = sq.Index
-	psm.SpectrumFile = FileName
-	psm.Spectrum = string(sq.Spectrum)
-	psm.Scan = sq.StartScan
-	psm.AssumedCharge = sq.AssumedCharge
-	psm.RetentionTime = sq.RetentionTimeSec
-	psm.IonMobility = sq.IonMobility
+	if maxRank < 1 {
+		maxRank = 1
+	}
+
+	var query PeptideIdentification
+	query.Index = sq.Index
+	query.SpectrumFile = FileName
+	query.Spectrum = string(sq.Spectrum)
+	query.Scan = sq.StartScan
+	query.AssumedCharge = sq.AssumedCharge
+	query.RetentionTime = sq.RetentionTimeSec
+	query.IonMobility = sq.IonMobility
 
 	if sq.UncalibratedPrecursorNeutralMass > 0 {
-		psm.PrecursorNeutralMass = sq.PrecursorNeutralMass
-		psm.UncalibratedPrecursorNeutralMass = sq.UncalibratedPrecursorNeutralMass
+		query.PrecursorNeutralMass = sq.PrecursorNeutralMass
+		query.UncalibratedPrecursorNeutralMass = sq.UncalibratedPrecursorNeutralMass
 	} else {
-		psm.PrecursorNeutralMass = sq.PrecursorNeutralMass
-		psm.UncalibratedPrecursorNeutralMass = sq.PrecursorNeutralMass
+		query.PrecursorNeutralMass = sq.PrecursorNeutralMass
+		query.UncalibratedPrecursorNeutralMass = sq.PrecursorNeutralMass
 	}
 
 	for _, i := range sq.SearchResult.SearchHit {
 
+		if i.HitRank > maxRank {
+			continue
+		}
+
+		// every hit gets its own PSM, sharing only the spectrum query attributes
+		psm := query
+		psm.Modifications.Index = make(map[string]mod.Modification)
+		psm.AlternativeProteinsIndexed = make(map[string]int)
+
 		psm.HitRank = i.HitRank
+		if psm.HitRank == 0 {
+			psm.HitRank = 1
+		}
+
 		psm.PrevAA = string(i.PrevAA)
 		psm.NextAA = string(i.NextAA)
 		psm.MissedCleavages = i.MissedCleavages
@@ -352,9 +385,11 @@ func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.
 		psm.Spectrum = fmt.Sprintf("%s#%s", psm.Spectrum, FileName)
 
 		psm.mapModsFromPepXML(i.ModificationInfo, mods)
+
+		psms = append(psms, psm)
 	}
 
-	return psm
+	return psms
 }
 
 // mapModsFromPepXML receives a pepXML struct with modifications and adds them to the given struct
@@ -400,6 +435,8 @@ func (p *PeptideIdentification) mapModsFromPepXML(m spc.ModificationInfo, mods m
 		}
 	}
 
+	p.ModifiedForm = p.Modifications.ProForma(p.Peptide)
+
 	// if isotopicCorr >= 0.036386 || isotopicCorr <= -0.036386 {
 	key := fmt.Sprintf("%.4f", p.Massdiff)
 	_, ok := p.Modifications.Index[key]
@@ -709,6 +746,8 @@ func (p *PepIDList) Serialize(level string) {
 		dest = sys.PepBin()
 	} else if level == "ion" {
 		dest = sys.IonBin()
+	} else if level == "mpep" {
+		dest = sys.ModPepBin()
 	} else {
 		msg.Custom(errors.New("Cannot determine binary data class"), "fatal")
 	}
@@ -737,6 +776,8 @@ func (p *PepIDList) Restore(level string) {
 		dest = sys.PepBin()
 	} else if level == "ion" {
 		dest = sys.IonBin()
+	} else if level == "mpep" {
+		dest = sys.ModPepBin()
 	} else {
 		msg.Custom(errors.New("Cannot determine binary data class"), "fatal")
 	}
@@ -0,0 +1,53 @@
+package id
+
+import (
+	"testing"
+
+	"philosopher/lib/mod"
+	"philosopher/lib/spc"
+)
+
+func Test_processSpectrumQuery(t *testing.T) {
+
+	sq := spc.SpectrumQuery{
+		Spectrum:      []byte("run.00010.00010.2"),
+		StartScan:     10,
+		AssumedCharge: 2,
+		SearchResult: spc.SearchResult{
+			SearchHit: []spc.SearchHit{
+				{HitRank: 1, Peptide: []byte("PEPTIDEK"), Protein: []byte("sp|P1|A")},
+				{HitRank: 2, Peptide: []byte("AAAK"), Protein: []byte("sp|P2|B")},
+				{HitRank: 3, Peptide: []byte("CCCK"), Protein: []byte("rev_sp|P3|C")},
+			},
+		},
+	}
+
+	mods := mod.Modifications{Index: make(map[string]mod.Modification)}
+
+	tests := []struct {
+		name     string
+		maxRank  uint8
+		peptides []string
+	}{
+		{"Testing the default rank", 0, []string{"PEPTIDEK"}},
+		{"Testing the top rank", 1, []string{"PEPTIDEK"}},
+		{"Testing two ranks", 2, []string{"PEPTIDEK", "AAAK"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got := processSpectrumQuery(sq, 0, mods, "rev_", "run.pep.xml", tt.maxRank)
+
+			if len(got) != len(tt.peptides) {
+				t.Fatalf("processSpectrumQuery() = %d PSMs, want %d", len(got), len(tt.peptides))
+			}
+
+			for i := range got {
+				if got[i].Peptide != tt.peptides[i] || got[i].HitRank != uint8(i+1) || got[i].Spectrum != "run.00010.00010.2#run.pep.xml" {
+					t.Errorf("processSpectrumQuery() = %v %v %v", got[i].Peptide, got[i].HitRank, got[i].Spectrum)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,484 @@
+package id
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/mod"
+	"philosopher/lib/msg"
+	"philosopher/lib/uti"
+)
+
+// ReadMSFraggerTSV parses the tabular output produced by MSFragger and organizes the
+// identifications in the same structure used for the pepXML files. The fixed modifications
+// are not reported on the TSV files, so they need to be informed using the residue code,
+// or n and c for the peptide termini, and the mass delta
+func (p *PepXML) ReadMSFraggerTSV(f string, fixedMods map[string]float64) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer file.Close()
+
+	p.FileName = path.Base(f)
+	p.SearchEngine = "MSFragger"
+	p.Prophet = "MSFragger"
+	p.SpectraFile = strings.TrimSuffix(p.FileName, filepath.Ext(p.FileName))
+	p.Modifications.Index = make(map[string]mod.Modification)
+
+	var header = make(map[string]int)
+	var psmlist PepIDList
+	var index uint32
+
+	maxRank := p.MaxHitRank
+	if maxRank < 1 {
+		maxRank = 1
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+
+		line := strings.Split(scanner.Text(), "\t")
+
+		if len(header) == 0 {
+			for i, j := range line {
+				header[strings.TrimSpace(j)] = i
+			}
+
+			_, ok := header["scannum"]
+			if !ok {
+				msg.Custom(errors.New("the file does not look like a MSFragger TSV output: "+f), "fatal")
+			}
+
+			// the expectation value is the only score that can be used for the FDR estimation
+			_, ok = header["expectscore"]
+			if !ok {
+				msg.Custom(errors.New("the MSFragger TSV file is missing the expectscore column: "+f), "fatal")
+			}
+
+			continue
+		}
+
+		if len(line) < len(header) {
+			continue
+		}
+
+		psm := processMSFraggerTSVLine(line, header, fixedMods, p.SpectraFile, p.FileName)
+
+		// only the peptides ranked up to the maximum rank are kept for each spectrum
+		if psm.HitRank == 0 {
+			psm.HitRank = 1
+		}
+
+		if psm.HitRank > maxRank {
+			continue
+		}
+
+		psm.Index = index
+
+		// register the modifications found on the PSM on the file index
+		for _, m := range psm.Modifications.Index {
+			if m.Type != "Assigned" {
+				continue
+			}
+
+			key := fmt.Sprintf("%s#%.4f", m.AminoAcid, m.MonoIsotopicMass)
+			_, ok := p.Modifications.Index[key]
+			if !ok {
+				gm := m
+				gm.Index = key
+				gm.Position = ""
+				gm.IsobaricMods = make(map[string]float64)
+				p.Modifications.Index[key] = gm
+			}
+		}
+
+		psmlist = append(psmlist, psm)
+		index++
+	}
+
+	if e := scanner.Err(); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	p.PeptideIdentification = psmlist
+
+	if len(psmlist) == 0 {
+		msg.NoPSMFound(errors.New(f), "warning")
+	}
+
+	return
+}
+
+// processMSFraggerTSVLine converts one line from the MSFragger TSV file into a PSM
+func processMSFraggerTSVLine(line []string, header map[string]int, fixedMods map[string]float64, source, fileName string) PeptideIdentification {
+
+	var psm PeptideIdentification
+	psm.Modifications.Index = make(map[string]mod.Modification)
+	psm.AlternativeProteinsIndexed = make(map[string]int)
+	psm.SearchEngineScore = make(map[string]float64)
+
+	column := func(name string) string {
+		i, ok := header[name]
+		if !ok || i >= len(line) {
+			return ""
+		}
+		return strings.TrimSpace(line[i])
+	}
+
+	number := func(name string) float64 {
+		v, _ := uti.ParseFloat(column(name))
+		return v
+	}
+
+	psm.SpectrumFile = fileName
+	psm.Scan, _ = strconv.Atoi(column("scannum"))
+	psm.Peptide = column("peptide")
+	psm.PrevAA = column("peptide_prev_aa")
+	psm.NextAA = column("peptide_next_aa")
+	psm.Protein = column("protein")
+
+	charge, _ := strconv.Atoi(column("charge"))
+	psm.AssumedCharge = uint8(charge)
+
+	hitRank, _ := strconv.Atoi(column("hit_rank"))
+	psm.HitRank = uint8(hitRank)
+
+	psm.PrecursorNeutralMass = number("precursor_neutral_mass")
+	psm.UncalibratedPrecursorNeutralMass = psm.PrecursorNeutralMass
+	psm.CalcNeutralPepMass = number("calc_neutral_pep_mass")
+	psm.Massdiff = number("massdiff")
+	psm.IonMobility = number("ion_mobility")
+
+	// MSFragger reports the retention time in minutes
+	psm.RetentionTime = number("retention_time") * 60
+
+	psm.NumberMatchedIons = uint16(number("num_matched_ions"))
+	psm.TotalNumberIons = uint16(number("tot_num_ions"))
+	psm.NumberTolTerm = uint8(number("num_tol_term"))
+	psm.NumberOfEnzymaticTermini = psm.NumberTolTerm
+	psm.MissedCleavages = uint8(number("num_missed_cleavages"))
+	psm.NumberofMissedCleavages = int(psm.MissedCleavages)
+
+	psm.Hyperscore = number("hyperscore")
+	psm.Nextscore = number("nextscore")
+	psm.Expectation = number("expectscore")
+
+	for _, i := range []string{"hyperscore", "nextscore", "expectscore", "score_without_delta_mass", "best_score_with_delta_mass", "second_best_score_with_delta_mass", "delta_score"} {
+		_, ok := header[i]
+		if ok {
+			psm.SearchEngineScore[i] = number(i)
+		}
+	}
+
+	for _, i := range strings.FieldsFunc(column("alternative_proteins"), func(r rune) bool { return r == ';' || r == ',' }) {
+		i = strings.TrimSpace(i)
+		if len(i) > 0 && i != psm.Protein {
+			psm.AlternativeProteins = append(psm.AlternativeProteins, i)
+			psm.AlternativeProteinsIndexed[i]++
+		}
+	}
+
+	psm.NumberTotalProteins = uint16(len(psm.AlternativeProteins) + 1)
+
+	// the expectation value is used as the PSM score, the transformation is monotonic
+	// so the target-decoy competition is preserved
+	if _, ok := header["expectscore"]; ok {
+		psm.Probability = 1 / (1 + psm.Expectation)
+	}
+
+	psm.Spectrum = fmt.Sprintf("%s.%05d.%05d.%d", source, psm.Scan, psm.Scan, psm.AssumedCharge)
+
+	psm.mapModsFromMSFraggerTSV(column("modification_info"), fixedMods)
+
+	// to be able to accept multiple entries with the same spectrum name, we fuse the
+	// file name to the spectrum name. This is going to be used as an identifiable attribute
+	// Before reporting the filtered PSMs, the file name is removed from the spectrum name.
+	psm.Spectrum = fmt.Sprintf("%s#%s", psm.Spectrum, fileName)
+
+	return psm
+}
+
+// mapModsFromMSFraggerTSV receives the MSFragger modification info and adds the assigned
+// and fixed modifications to the given struct. The variable modifications are reported as
+// a comma separated list like 5M(15.9949), N-term(42.0106)
+func (p *PeptideIdentification) mapModsFromMSFraggerTSV(info string, fixedMods map[string]float64) {
+
+	reg := regexp.MustCompile(`^(\d*)([A-Za-z-]+?)(\d*)\((-?[\d.]+)\)$`)
+
+	var nTerm float64
+	var cTerm float64
+	var residues = make(map[int]float64)
+	var variable = make(map[int]string)
+
+	addMod := func(aa string, location int, delta float64, kind string) string {
+
+		m := mod.Modification{
+			Type:         "Assigned",
+			MassDiff:     delta,
+			Variable:     kind,
+			IsobaricMods: make(map[string]float64),
+		}
+
+		switch aa {
+		case "N-term", "n":
+			m.AminoAcid = "N-term"
+			m.Terminus = "n"
+			m.MonoIsotopicMass = bio.Hydrogen + delta
+			m.Index = fmt.Sprintf("N-term#%.4f", m.MonoIsotopicMass)
+			nTerm = m.MonoIsotopicMass
+		case "C-term", "c":
+			m.AminoAcid = "C-term"
+			m.Terminus = "c"
+			m.MonoIsotopicMass = (bio.Water - bio.Hydrogen) + delta
+			m.Index = fmt.Sprintf("C-term#%.4f", m.MonoIsotopicMass)
+			cTerm = m.MonoIsotopicMass
+		default:
+			m.AminoAcid = aa
+			m.Position = strconv.Itoa(location)
+			m.MonoIsotopicMass = bio.NewFromCode(aa).MonoIsotopeMass + delta
+			m.Index = fmt.Sprintf("%s#%d#%.4f", aa, location, m.MonoIsotopicMass)
+			residues[location] = m.MonoIsotopicMass
+		}
+
+		p.Modifications.Index[m.Index] = m
+
+		return m.Index
+	}
+
+	// a variable modification on a residue that also carries a fixed one is reported with the
+	// variable delta only, the fixed delta is added to the mass shift and to the modified residue mass
+	addFixed := func(location int, delta float64) {
+
+		key := variable[location]
+
+		m := p.Modifications.Index[key]
+		delete(p.Modifications.Index, key)
+
+		m.MassDiff += delta
+		m.MonoIsotopicMass += delta
+
+		switch location {
+		case 0:
+			m.Index = fmt.Sprintf("N-term#%.4f", m.MonoIsotopicMass)
+			nTerm = m.MonoIsotopicMass
+		case -1:
+			m.Index = fmt.Sprintf("C-term#%.4f", m.MonoIsotopicMass)
+			cTerm = m.MonoIsotopicMass
+		default:
+			m.Index = fmt.Sprintf("%s#%d#%.4f", m.AminoAcid, location, m.MonoIsotopicMass)
+			residues[location] = m.MonoIsotopicMass
+		}
+
+		p.Modifications.Index[m.Index] = m
+		variable[location] = m.Index
+	}
+
+	for _, i := range strings.Split(info, ",") {
+
+		i = strings.TrimSpace(i)
+		if len(i) == 0 {
+			continue
+		}
+
+		m := reg.FindStringSubmatch(i)
+		if m == nil {
+			continue
+		}
+
+		delta, e := uti.ParseFloat(m[4])
+		if e != nil {
+			continue
+		}
+
+		// MSFragger versions differ on the position placement, before or after the residue
+		position := m[1]
+		if len(position) == 0 {
+			position = m[3]
+		}
+		location, _ := strconv.Atoi(position)
+
+		aa := m[2]
+		switch aa {
+		case "N-term", "n":
+			location = 0
+		case "C-term", "c":
+			location = -1
+		default:
+			if location < 1 || location > len(p.Peptide) {
+				continue
+			}
+			aa = string(p.Peptide[location-1])
+		}
+
+		variable[location] = addMod(aa, location, delta, "Y")
+	}
+
+	// fixed modifications are not part of the modification info
+	var fixed []string
+	for i := range fixedMods {
+		fixed = append(fixed, i)
+	}
+	sort.Strings(fixed)
+
+	for _, i := range fixed {
+
+		delta := fixedMods[i]
+		if delta == 0 {
+			continue
+		}
+
+		switch i {
+		case "n":
+			if _, ok := variable[0]; ok {
+				addFixed(0, delta)
+			} else {
+				addMod(i, 0, delta, "N")
+			}
+		case "c":
+			if _, ok := variable[-1]; ok {
+				addFixed(-1, delta)
+			} else {
+				addMod(i, 0, delta, "N")
+			}
+		default:
+			for j, k := range p.Peptide {
+				if string(k) != i {
+					continue
+				}
+				if _, ok := variable[j+1]; ok {
+					addFixed(j+1, delta)
+				} else {
+					addMod(i, j+1, delta, "N")
+				}
+			}
+		}
+	}
+
+	// rebuild the modified sequence using the pepXML notation
+	if nTerm != 0 || cTerm != 0 || len(residues) > 0 {
+
+		var modPep strings.Builder
+
+		if nTerm != 0 {
+			modPep.WriteString(fmt.Sprintf("n[%.0f]", nTerm))
+		}
+
+		for i, j := range p.Peptide {
+			modPep.WriteRune(j)
+			v, ok := residues[i+1]
+			if ok {
+				modPep.WriteString(fmt.Sprintf("[%.0f]", v))
+			}
+		}
+
+		if cTerm != 0 {
+			modPep.WriteString(fmt.Sprintf("c[%.0f]", cTerm))
+		}
+
+		p.ModifiedPeptide = modPep.String()
+	}
+
+	p.ModifiedForm = p.Modifications.ProForma(p.Peptide)
+
+	// the observed mass shift is kept the same way it is done for pepXML
+	key := fmt.Sprintf("%.4f", p.Massdiff)
+	_, ok := p.Modifications.Index[key]
+	if !ok {
+		m := mod.Modification{
+			Index:        key,
+			Name:         "Unknown",
+			Type:         "Observed",
+			MassDiff:     p.Massdiff,
+			IsobaricMods: make(map[string]float64),
+		}
+		p.Modifications.Index[key] = m
+	}
+
+	return
+}
+
+// ReadMSFraggerParams collects the fixed modifications from a MSFragger parameter file, the
+// peptide termini are reported as n and c and the residues by their one letter code
+func ReadMSFraggerParams(f string) map[string]float64 {
+
+	var fixedMods = make(map[string]float64)
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer file.Close()
+
+	reg := regexp.MustCompile(`^add_([A-Z])_\w+$`)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		pair := strings.SplitN(line, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(pair[0])
+		value, e := uti.ParseFloat(strings.TrimSpace(pair[1]))
+		if e != nil {
+			continue
+		}
+
+		switch key {
+		case "add_Nterm_peptide":
+			fixedMods["n"] = value
+		case "add_Cterm_peptide":
+			fixedMods["c"] = value
+		default:
+			m := reg.FindStringSubmatch(key)
+			if m != nil {
+				fixedMods[m[1]] = value
+			}
+		}
+	}
+
+	if e := scanner.Err(); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	return fixedMods
+}
+
+// IsMSFraggerTSV checks if the given file has the MSFragger TSV header
+func IsMSFraggerTSV(f string) bool {
+
+	file, e := os.Open(f)
+	if e != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if scanner.Scan() {
+		header := strings.Split(scanner.Text(), "\t")
+		if len(header) > 0 && strings.TrimSpace(header[0]) == "scannum" {
+			return true
+		}
+	}
+
+	return false
+}
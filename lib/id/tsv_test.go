@@ -0,0 +1,144 @@
+package id
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const fraggerTSV = "scannum\tprecursor_neutral_mass\tretention_time\tcharge\thit_rank\tpeptide\tpeptide_prev_aa\tpeptide_next_aa\tprotein\tnum_matched_ions\ttot_num_ions\tcalc_neutral_pep_mass\tmassdiff\tnum_tol_term\tnum_missed_cleavages\tmodification_info\thyperscore\tnextscore\texpectscore\talternative_proteins\n" +
+	"1001\t1077.4706\t10.5\t2\t1\tPEPMCK\tK\tA\tsp|P1|A\t8\t10\t1077.4706\t0.0012\t2\t0\t4M(15.9949)\t25.1\t12.2\t0.0100\tsp|P2|B\n" +
+	"1001\t1077.4706\t10.5\t2\t2\tPEPMCR\tK\tA\tsp|P3|C\t5\t10\t1077.4701\t0.0005\t2\t0\t\t12.2\t10.1\t0.5000\t\n" +
+	"1002\t800.4000\t11.0\t3\t1\tACDCK\tR\tL\trev_sp|P4|D\t6\t8\t800.3990\t0.0010\t2\t0\tN-term(42.0106), 4C(-17.0265)\t15.0\t9.0\t1.0000\t\n"
+
+func writeFixture(t *testing.T, name, content string) (string, func()) {
+
+	dir, e := ioutil.TempDir("", "tsv")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	f := filepath.Join(dir, name)
+	if e := ioutil.WriteFile(f, []byte(content), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	return f, func() { os.RemoveAll(dir) }
+}
+
+func TestReadMSFraggerTSV(t *testing.T) {
+
+	f, clean := writeFixture(t, "run1.tsv", fraggerTSV)
+	defer clean()
+
+	if !IsMSFraggerTSV(f) {
+		t.Fatalf("IsMSFraggerTSV() = false")
+	}
+
+	var p PepXML
+	p.ReadMSFraggerTSV(f, map[string]float64{"C": 57.021464})
+
+	// the second ranked hit is left out with the default maximum rank
+	if len(p.PeptideIdentification) != 2 {
+		t.Fatalf("ReadMSFraggerTSV() = %d PSMs, want 2", len(p.PeptideIdentification))
+	}
+
+	psm := p.PeptideIdentification[0]
+	if psm.Spectrum != "run1.01001.01001.2#run1.tsv" || psm.AssumedCharge != 2 || psm.RetentionTime != 630 {
+		t.Errorf("ReadMSFraggerTSV() = %v %v %v", psm.Spectrum, psm.AssumedCharge, psm.RetentionTime)
+	}
+
+	if psm.AlternativeProteins[0] != "sp|P2|B" || psm.NumberTotalProteins != 2 || psm.Probability < 0.99 {
+		t.Errorf("ReadMSFraggerTSV() proteins = %v, probability = %v", psm.AlternativeProteins, psm.Probability)
+	}
+
+	if psm.ModifiedPeptide != "PEPM[147]C[160]K" {
+		t.Errorf("ModifiedPeptide = %v", psm.ModifiedPeptide)
+	}
+
+	var assigned int
+	for _, i := range psm.Modifications.Index {
+		if i.Type == "Assigned" {
+			assigned++
+		}
+	}
+
+	if assigned != 2 {
+		t.Errorf("Modifications = %v", psm.Modifications.Index)
+	}
+
+	p.MaxHitRank = 2
+	p.ReadMSFraggerTSV(f, nil)
+	if len(p.PeptideIdentification) != 3 || p.PeptideIdentification[1].HitRank != 2 {
+		t.Errorf("ReadMSFraggerTSV() with two ranks = %d PSMs", len(p.PeptideIdentification))
+	}
+}
+
+func TestMapModsFromMSFraggerTSV(t *testing.T) {
+
+	f, clean := writeFixture(t, "run1.tsv", fraggerTSV)
+	defer clean()
+
+	var p PepXML
+	p.ReadMSFraggerTSV(f, map[string]float64{"C": 57.021464})
+
+	psm := p.PeptideIdentification[1]
+
+	// the variable delta on the carbamidomethylated cysteine is added to the fixed one, on
+	// both the residue mass and the mass shift
+	if psm.ModifiedPeptide != "n[43]AC[160]DC[143]K" {
+		t.Errorf("ModifiedPeptide = %v", psm.ModifiedPeptide)
+	}
+
+	if psm.ModifiedForm != "[+42.0106]-AC[+57.0215]DC[+39.9950]K" {
+		t.Errorf("ModifiedForm = %v", psm.ModifiedForm)
+	}
+
+	var found bool
+	for _, i := range psm.Modifications.Index {
+		if i.Variable == "Y" && i.AminoAcid == "C" {
+			found = true
+			if math.Abs(i.MassDiff-39.994964) > 1e-6 || i.MonoIsotopicMass < 143.0 || i.MonoIsotopicMass > 143.1 {
+				t.Errorf("Modification = %+v", i)
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("variable modification not found: %v", psm.Modifications.Index)
+	}
+}
+
+func TestReadMSFraggerParams(t *testing.T) {
+
+	content := strings.Join([]string{
+		"database_name = db.fasta",
+		"add_Cterm_peptide = 0.000000",
+		"add_Nterm_peptide = 229.162932 # TMT",
+		"add_C_cysteine = 57.021464",
+		"add_K_lysine = 229.162932",
+		"add_Nterm_protein = 0.000000",
+	}, "\n")
+
+	f, clean := writeFixture(t, "fragger.params", content)
+	defer clean()
+
+	mods := ReadMSFraggerParams(f)
+
+	if mods["n"] != 229.162932 || mods["C"] != 57.021464 || mods["K"] != 229.162932 || len(mods) != 4 {
+		t.Errorf("ReadMSFraggerParams() = %v", mods)
+	}
+}
+
+func TestProcessMSFraggerTSVLineWithoutExpectation(t *testing.T) {
+
+	header := map[string]int{"scannum": 0, "peptide": 1, "charge": 2, "hyperscore": 3}
+	psm := processMSFraggerTSVLine([]string{"1001", "PEPTIDE", "2", "25.1"}, header, nil, "run1", "run1.tsv")
+
+	if psm.Probability != 0 || psm.Hyperscore != 25.1 {
+		t.Errorf("processMSFraggerTSVLine() probability = %v, hyperscore = %v", psm.Probability, psm.Hyperscore)
+	}
+}
@@ -0,0 +1,268 @@
+package inf
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"philosopher/lib/id"
+)
+
+const (
+	// emission is the probability of a peptide being observed when a parent protein is present
+	emission = 0.9
+	// noise is the probability of a peptide being observed when no parent protein is present
+	noise = 0.01
+	// prior is the probability of a protein being present before looking at the data
+	prior = 0.1
+	// maxExactProteins is the largest group size solved by full enumeration of the protein states
+	maxExactProteins = 16
+	// gibbsSweeps and gibbsBurnIn control the sampling used on the larger groups
+	gibbsSweeps = 2000
+	gibbsBurnIn = 200
+)
+
+// bayesGroup is the subgraph from a protein group used by the probability model
+type bayesGroup struct {
+	proteins []string
+	peptides []float64
+	parents  [][]int
+	children [][]int
+}
+
+// ProteinPosteriors scores the protein groups with a Bayesian model in the spirit of Fido, where a
+// present protein emits each of its peptides independently and the observed peptide probabilities
+// are the evidence. Shared peptides are explained by all their parent proteins at the same time, so
+// proteins competing for the same evidence share its weight. The posteriors are returned for each
+// protein entry and for each group, the group posterior is the probability of any entry being present
+func ProteinPosteriors(psm id.PepIDList, groups []ProteinGroup) (map[string]float64, map[uint32]float64) {
+
+	var proteins = make(map[string]float64)
+	var groupProbs = make(map[uint32]float64)
+
+	// the peptide evidence is the best PSM probability for each sequence
+	var evidence = make(map[string]float64)
+	for _, i := range psm {
+		p := math.Min(math.Max(i.Probability, 0), 1)
+		if p > evidence[i.Peptide] {
+			evidence[i.Peptide] = p
+		}
+	}
+
+	for _, i := range groups {
+
+		g := newBayesGroup(i, evidence)
+
+		var posteriors []float64
+		var anyPresent float64
+
+		if len(g.proteins) <= maxExactProteins {
+			posteriors, anyPresent = g.enumerate()
+		} else {
+			posteriors, anyPresent = g.sample(int64(i.Number))
+		}
+
+		for j, k := range g.proteins {
+			proteins[k] = posteriors[j]
+		}
+
+		groupProbs[i.Number] = anyPresent
+	}
+
+	return proteins, groupProbs
+}
+
+// newBayesGroup builds the bipartite graph for a single protein group
+func newBayesGroup(grp ProteinGroup, evidence map[string]float64) bayesGroup {
+
+	var g bayesGroup
+	var peptideIndex = make(map[string]int)
+	var sequences []string
+
+	for _, i := range grp.Proteins {
+		for j := range i.Peptides {
+			_, ok := peptideIndex[j]
+			if !ok {
+				peptideIndex[j] = 0
+				sequences = append(sequences, j)
+			}
+		}
+	}
+
+	sort.Strings(sequences)
+	for i, j := range sequences {
+		peptideIndex[j] = i
+		g.peptides = append(g.peptides, evidence[j])
+	}
+
+	g.parents = make([][]int, len(sequences))
+	g.children = make([][]int, len(grp.Proteins))
+
+	for i, j := range grp.Proteins {
+
+		g.proteins = append(g.proteins, j.ProteinName)
+
+		for k := range j.Peptides {
+			e := peptideIndex[k]
+			g.parents[e] = append(g.parents[e], i)
+			g.children[i] = append(g.children[i], e)
+		}
+	}
+
+	return g
+}
+
+// peptideLikelihood is the likelihood of the peptide evidence given the number of present parents
+func peptideLikelihood(p float64, present int) float64 {
+
+	absent := (1 - noise) * math.Pow(1-emission, float64(present))
+
+	return p*(1-absent) + (1-p)*absent
+}
+
+// logJoint calculates the log probability of a protein state and the evidence
+func (g bayesGroup) logJoint(state []bool) float64 {
+
+	var l float64
+
+	for _, i := range state {
+		if i {
+			l += math.Log(prior)
+		} else {
+			l += math.Log(1 - prior)
+		}
+	}
+
+	for i, j := range g.parents {
+		var present int
+		for _, k := range j {
+			if state[k] {
+				present++
+			}
+		}
+		l += math.Log(peptideLikelihood(g.peptides[i], present))
+	}
+
+	return l
+}
+
+// enumerate calculates the exact posteriors by visiting all protein states
+func (g bayesGroup) enumerate() ([]float64, float64) {
+
+	n := len(g.proteins)
+	states := 1 << uint(n)
+
+	var logs = make([]float64, states)
+	var top = math.Inf(-1)
+	var state = make([]bool, n)
+
+	for s := 0; s < states; s++ {
+		for i := 0; i < n; i++ {
+			state[i] = s&(1<<uint(i)) != 0
+		}
+		logs[s] = g.logJoint(state)
+		if logs[s] > top {
+			top = logs[s]
+		}
+	}
+
+	var total float64
+	var marginals = make([]float64, n)
+
+	for s := 0; s < states; s++ {
+		w := math.Exp(logs[s] - top)
+		total += w
+		for i := 0; i < n; i++ {
+			if s&(1<<uint(i)) != 0 {
+				marginals[i] += w
+			}
+		}
+	}
+
+	for i := range marginals {
+		marginals[i] /= total
+	}
+
+	// the only state without proteins is the first one
+	anyPresent := 1 - math.Exp(logs[0]-top)/total
+
+	return marginals, anyPresent
+}
+
+// sample estimates the posteriors using Gibbs sampling, the seed keeps the results reproducible
+func (g bayesGroup) sample(seed int64) ([]float64, float64) {
+
+	n := len(g.proteins)
+	r := rand.New(rand.NewSource(seed))
+
+	var state = make([]bool, n)
+	var present = make([]int, len(g.peptides))
+	var counts = make([]float64, n)
+	var anyPresent float64
+	var active = n
+
+	for i := range state {
+		state[i] = true
+	}
+
+	for i, j := range g.parents {
+		present[i] = len(j)
+	}
+
+	for sweep := 0; sweep < gibbsSweeps; sweep++ {
+
+		for i := 0; i < n; i++ {
+
+			// log odds of the protein being present given the state of all other proteins
+			var on = math.Log(prior)
+			var off = math.Log(1 - prior)
+
+			for _, e := range g.children[i] {
+				others := present[e]
+				if state[i] {
+					others--
+				}
+				on += math.Log(peptideLikelihood(g.peptides[e], others+1))
+				off += math.Log(peptideLikelihood(g.peptides[e], others))
+			}
+
+			next := r.Float64() < 1/(1+math.Exp(off-on))
+
+			if next != state[i] {
+
+				var delta = 1
+				if !next {
+					delta = -1
+				}
+
+				for _, e := range g.children[i] {
+					present[e] += delta
+				}
+
+				active += delta
+				state[i] = next
+			}
+		}
+
+		if sweep < gibbsBurnIn {
+			continue
+		}
+
+		for i := range state {
+			if state[i] {
+				counts[i]++
+			}
+		}
+
+		if active > 0 {
+			anyPresent++
+		}
+	}
+
+	samples := float64(gibbsSweeps - gibbsBurnIn)
+	for i := range counts {
+		counts[i] /= samples
+	}
+
+	return counts, anyPresent / samples
+}
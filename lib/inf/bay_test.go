@@ -0,0 +1,63 @@
+package inf
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/id"
+)
+
+func TestProteinPosteriors(t *testing.T) {
+
+	psm := id.PepIDList{
+		{Peptide: "AAAK", Protein: "P1", Probability: 0.99},
+		{Peptide: "BBBK", Protein: "P1", Probability: 0.95, AlternativeProteinsIndexed: map[string]int{"P2": 1}},
+		{Peptide: "CCCK", Protein: "P3", Probability: 0.20},
+	}
+
+	groups := GroupProteins(psm)
+	proteins, grp := ProteinPosteriors(psm, groups)
+
+	if proteins["P1"] < 0.95 {
+		t.Errorf("ProteinPosteriors() protein with unique evidence got = %v, want > 0.95", proteins["P1"])
+	}
+
+	// the shared peptide is already explained by P1
+	if proteins["P2"] >= prior*2 {
+		t.Errorf("ProteinPosteriors() protein with shared evidence got = %v, want close to the prior", proteins["P2"])
+	}
+
+	if proteins["P3"] >= proteins["P2"] || proteins["P3"] >= prior {
+		t.Errorf("ProteinPosteriors() protein with weak evidence got = %v, want below the prior", proteins["P3"])
+	}
+
+	if grp[groups[0].Number] < proteins["P1"] {
+		t.Errorf("ProteinPosteriors() group posterior got = %v, want >= %v", grp[groups[0].Number], proteins["P1"])
+	}
+}
+
+func Test_bayesGroupSample(t *testing.T) {
+
+	psm := id.PepIDList{
+		{Peptide: "AAAK", Protein: "P1", Probability: 0.90},
+		{Peptide: "BBBK", Protein: "P1", Probability: 0.60, AlternativeProteinsIndexed: map[string]int{"P2": 1}},
+		{Peptide: "CCCK", Protein: "P2", Probability: 0.40, AlternativeProteinsIndexed: map[string]int{"P3": 1}},
+	}
+
+	groups := GroupProteins(psm)
+	evidence := map[string]float64{"AAAK": 0.90, "BBBK": 0.60, "CCCK": 0.40}
+	g := newBayesGroup(groups[0], evidence)
+
+	exact, exactAny := g.enumerate()
+	sampled, sampledAny := g.sample(1)
+
+	for i := range exact {
+		if math.Abs(exact[i]-sampled[i]) > 0.05 {
+			t.Errorf("sample() got = %v, want close to %v for %s", sampled[i], exact[i], g.proteins[i])
+		}
+	}
+
+	if math.Abs(exactAny-sampledAny) > 0.05 {
+		t.Errorf("sample() group posterior got = %v, want close to %v", sampledAny, exactAny)
+	}
+}
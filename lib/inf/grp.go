@@ -0,0 +1,304 @@
+package inf
+
+import (
+	"sort"
+	"strings"
+
+	"philosopher/lib/id"
+)
+
+// ProteinGroup is a set of proteins connected by shared peptides
+type ProteinGroup struct {
+	Number   uint32
+	Proteins []GroupedProtein
+}
+
+// GroupedProtein is a protein entry from a protein group, carrying the proteins that are
+// indistinguishable from it
+type GroupedProtein struct {
+	ProteinName              string
+	SiblingID                string
+	IndistinguishableProtein []string
+	Peptides                 map[string]uint8
+	SubsetOf                 string
+	IsParsimonious           bool
+}
+
+// GroupProteins builds the bipartite graph between peptides and proteins and organizes the proteins
+// in groups of connected entries. Proteins matching the same set of peptides are collapsed into a
+// single entry, entries with all peptides contained by another entry are labeled as subsets, and the
+// minimal set of entries explaining all peptides is selected following the Occam's razor principle,
+// using a greedy approximation
+func GroupProteins(psm id.PepIDList) []ProteinGroup {
+
+	var proteinPeptides = make(map[string]map[string]uint8)
+	var primary = make(map[string]int)
+
+	for _, i := range psm {
+
+		addPeptide(proteinPeptides, i.Protein, i.Peptide)
+		primary[i.Protein]++
+
+		for j := range i.AlternativeProteinsIndexed {
+			addPeptide(proteinPeptides, j, i.Peptide)
+		}
+	}
+
+	// collapse the indistinguishable proteins, the representative protein is the one reported
+	// by the search engine for most PSMs
+	var signatures = make(map[string][]string)
+	for k, v := range proteinPeptides {
+		s := peptideSignature(v)
+		signatures[s] = append(signatures[s], k)
+	}
+
+	var entries []GroupedProtein
+	for _, v := range signatures {
+
+		sort.Slice(v, func(i, j int) bool {
+			if primary[v[i]] != primary[v[j]] {
+				return primary[v[i]] > primary[v[j]]
+			}
+			return v[i] < v[j]
+		})
+
+		e := GroupedProtein{
+			ProteinName: v[0],
+			Peptides:    proteinPeptides[v[0]],
+		}
+
+		if len(v) > 1 {
+			e.IndistinguishableProtein = v[1:]
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ProteinName < entries[j].ProteinName })
+
+	var peptideEntries = make(map[string][]int)
+	for i := range entries {
+		for j := range entries[i].Peptides {
+			peptideEntries[j] = append(peptideEntries[j], i)
+		}
+	}
+
+	// label the subsets, the largest entry containing all the peptides is the reference
+	for i := range entries {
+
+		var superset = -1
+
+		for _, j := range peptideEntries[anyPeptide(entries[i].Peptides)] {
+
+			if j == i || len(entries[j].Peptides) <= len(entries[i].Peptides) || !containsAll(entries[j].Peptides, entries[i].Peptides) {
+				continue
+			}
+
+			if superset < 0 || len(entries[j].Peptides) > len(entries[superset].Peptides) {
+				superset = j
+			}
+		}
+
+		if superset >= 0 {
+			entries[i].SubsetOf = entries[superset].ProteinName
+		}
+	}
+
+	// the connected entries are found by joining all entries sharing a peptide
+	var parent = make([]int, len(entries))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for _, v := range peptideEntries {
+		for _, j := range v[1:] {
+			a, b := find(v[0]), find(j)
+			if a != b {
+				parent[b] = a
+			}
+		}
+	}
+
+	var components = make(map[int][]int)
+	for i := range entries {
+		r := find(i)
+		components[r] = append(components[r], i)
+	}
+
+	var groups []ProteinGroup
+
+	for _, v := range components {
+
+		selectMinimalSet(entries, v)
+
+		sort.Slice(v, func(i, j int) bool {
+			a, b := entries[v[i]], entries[v[j]]
+			if a.IsParsimonious != b.IsParsimonious {
+				return a.IsParsimonious
+			}
+			if len(a.Peptides) != len(b.Peptides) {
+				return len(a.Peptides) > len(b.Peptides)
+			}
+			return a.ProteinName < b.ProteinName
+		})
+
+		var g ProteinGroup
+		for i, j := range v {
+			e := entries[j]
+			e.SiblingID = siblingID(i)
+			g.Proteins = append(g.Proteins, e)
+		}
+
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i].Proteins[0], groups[j].Proteins[0]
+		if len(a.Peptides) != len(b.Peptides) {
+			return len(a.Peptides) > len(b.Peptides)
+		}
+		return a.ProteinName < b.ProteinName
+	})
+
+	for i := range groups {
+		groups[i].Number = uint32(i + 1)
+	}
+
+	return groups
+}
+
+// selectMinimalSet marks the entries that explain all peptides from a group, the entry explaining
+// most of the remaining peptides is taken at each step. This greedy selection approximates the
+// minimum set cover and may keep more entries than the optimal solution
+func selectMinimalSet(entries []GroupedProtein, group []int) {
+
+	var uncovered = make(map[string]uint8)
+	for _, i := range group {
+		for j := range entries[i].Peptides {
+			uncovered[j] = 0
+		}
+	}
+
+	for len(uncovered) > 0 {
+
+		var best = -1
+		var bestCount int
+
+		for _, i := range group {
+
+			if entries[i].IsParsimonious || len(entries[i].SubsetOf) > 0 {
+				continue
+			}
+
+			var count int
+			for j := range entries[i].Peptides {
+				_, ok := uncovered[j]
+				if ok {
+					count++
+				}
+			}
+
+			if count == 0 {
+				continue
+			}
+
+			if best < 0 || count > bestCount ||
+				(count == bestCount && len(entries[i].Peptides) > len(entries[best].Peptides)) ||
+				(count == bestCount && len(entries[i].Peptides) == len(entries[best].Peptides) && entries[i].ProteinName < entries[best].ProteinName) {
+				best = i
+				bestCount = count
+			}
+		}
+
+		if best < 0 {
+			break
+		}
+
+		entries[best].IsParsimonious = true
+		for j := range entries[best].Peptides {
+			delete(uncovered, j)
+		}
+	}
+
+	return
+}
+
+// addPeptide adds an edge between a protein and a peptide
+func addPeptide(graph map[string]map[string]uint8, protein, peptide string) {
+
+	if len(protein) == 0 {
+		return
+	}
+
+	_, ok := graph[protein]
+	if !ok {
+		graph[protein] = make(map[string]uint8)
+	}
+
+	graph[protein][peptide] = 0
+
+	return
+}
+
+// peptideSignature creates a key that is shared by all proteins with the same set of peptides
+func peptideSignature(peptides map[string]uint8) string {
+
+	var list []string
+	for i := range peptides {
+		list = append(list, i)
+	}
+	sort.Strings(list)
+
+	return strings.Join(list, "+")
+}
+
+// anyPeptide returns the first peptide from the set in alphabetical order
+func anyPeptide(peptides map[string]uint8) string {
+
+	var first string
+	for i := range peptides {
+		if len(first) == 0 || i < first {
+			first = i
+		}
+	}
+
+	return first
+}
+
+// containsAll checks if all elements from the subset are part of the set
+func containsAll(set, subset map[string]uint8) bool {
+
+	for i := range subset {
+		_, ok := set[i]
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// siblingID converts the entry position inside the group to the protXML sibling notation, a to z,
+// followed by aa, ab, and so on
+func siblingID(i int) string {
+
+	var s string
+
+	for {
+		s = string(rune('a'+i%26)) + s
+		i = i/26 - 1
+		if i < 0 {
+			break
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,68 @@
+package inf
+
+import (
+	"testing"
+
+	"philosopher/lib/id"
+)
+
+func TestGroupProteins(t *testing.T) {
+
+	psm := id.PepIDList{
+		{Peptide: "AAAK", Protein: "P1", AlternativeProteinsIndexed: map[string]int{"P2": 1, "P3": 1}},
+		{Peptide: "BBBK", Protein: "P1", AlternativeProteinsIndexed: map[string]int{"P2": 1}},
+		{Peptide: "CCCK", Protein: "P1", AlternativeProteinsIndexed: map[string]int{"P2": 1, "P4": 1}},
+		{Peptide: "DDDK", Protein: "P4"},
+		{Peptide: "EEEK", Protein: "P5"},
+	}
+
+	groups := GroupProteins(psm)
+
+	if len(groups) != 2 {
+		t.Fatalf("GroupProteins() got = %v groups, want %v", len(groups), 2)
+	}
+
+	g := groups[0]
+	if g.Number != 1 || len(g.Proteins) != 3 {
+		t.Fatalf("GroupProteins() first group got = %v with %v entries, want 1 with 3", g.Number, len(g.Proteins))
+	}
+
+	if g.Proteins[0].ProteinName != "P1" || len(g.Proteins[0].IndistinguishableProtein) != 1 || g.Proteins[0].IndistinguishableProtein[0] != "P2" {
+		t.Errorf("GroupProteins() indistinguishable got = %v %v, want P1 [P2]", g.Proteins[0].ProteinName, g.Proteins[0].IndistinguishableProtein)
+	}
+
+	if !g.Proteins[0].IsParsimonious || g.Proteins[0].SiblingID != "a" {
+		t.Errorf("GroupProteins() representative got = %v %v, want parsimonious a", g.Proteins[0].IsParsimonious, g.Proteins[0].SiblingID)
+	}
+
+	if g.Proteins[1].ProteinName != "P4" || !g.Proteins[1].IsParsimonious || g.Proteins[1].SiblingID != "b" {
+		t.Errorf("GroupProteins() second entry got = %v %v %v, want P4 parsimonious b", g.Proteins[1].ProteinName, g.Proteins[1].IsParsimonious, g.Proteins[1].SiblingID)
+	}
+
+	if g.Proteins[2].ProteinName != "P3" || g.Proteins[2].IsParsimonious || g.Proteins[2].SubsetOf != "P1" {
+		t.Errorf("GroupProteins() subset got = %v %v %v, want P3 subset of P1", g.Proteins[2].ProteinName, g.Proteins[2].IsParsimonious, g.Proteins[2].SubsetOf)
+	}
+
+	if groups[1].Number != 2 || groups[1].Proteins[0].ProteinName != "P5" {
+		t.Errorf("GroupProteins() second group got = %v %v, want 2 P5", groups[1].Number, groups[1].Proteins[0].ProteinName)
+	}
+}
+
+func Test_siblingID(t *testing.T) {
+	tests := []struct {
+		name string
+		args int
+		want string
+	}{
+		{name: "Testing first sibling", args: 0, want: "a"},
+		{name: "Testing last single letter sibling", args: 25, want: "z"},
+		{name: "Testing double letter sibling", args: 27, want: "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := siblingID(tt.args); got != tt.want {
+				t.Errorf("siblingID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
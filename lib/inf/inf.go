@@ -1,13 +1,13 @@
 package inf
 
 import (
-	"fmt"
 	"regexp"
 	"sort"
 	"strings"
 
 	"philosopher/lib/dat"
 	"philosopher/lib/id"
+	"philosopher/lib/prf"
 	"philosopher/lib/uti"
 )
 
@@ -25,8 +25,10 @@ type Peptide struct {
 	MappedProteinsWithDecoys map[string]int
 }
 
-// ProteinInference ...
-func ProteinInference(psm id.PepIDList) (id.PepIDList, map[string]string, map[string]float64) {
+// ProteinInference groups the proteins following the parsimony principle and assigns each peptide
+// to a razor protein from the minimal protein set. The minimal set is chosen greedily, so it is not
+// guaranteed to be the smallest possible set when several combinations explain the same peptides
+func ProteinInference(psm id.PepIDList) (id.PepIDList, map[string]string, map[string]float64, []ProteinGroup) {
 
 	var peptideList []Peptide
 	var exclusionList = make(map[string]int)
@@ -42,7 +44,7 @@ func ProteinInference(psm id.PepIDList) (id.PepIDList, map[string]string, map[st
 	// build the peptide index
 	for _, i := range psm {
 
-		ionForm := fmt.Sprintf("%s#%d#%.4f", i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
+		ionForm := prf.IonKey(i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
 
 		_, ok := exclusionList[ionForm]
 		if !ok {
@@ -93,7 +95,7 @@ func ProteinInference(psm id.PepIDList) (id.PepIDList, map[string]string, map[st
 
 	for _, i := range psm {
 
-		ionForm := fmt.Sprintf("%s#%d#%.4f", i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
+		ionForm := prf.IonKey(i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
 
 		v, ok := peptideIndex[ionForm]
 		if ok {
@@ -149,17 +151,45 @@ func ProteinInference(psm id.PepIDList) (id.PepIDList, map[string]string, map[st
 
 	proteinCoverageMap := calculateProteinCoverage(proteinPepSeqMap, db)
 
+	groups := GroupProteins(psm)
+
+	// only the representative proteins from the minimal set are razor candidates
+	var representative = make(map[string]string)
+	var parsimonious = make(map[string]uint8)
+	for _, i := range groups {
+		for _, j := range i.Proteins {
+
+			representative[j.ProteinName] = j.ProteinName
+			for _, k := range j.IndistinguishableProtein {
+				representative[k] = j.ProteinName
+			}
+
+			if j.IsParsimonious {
+				parsimonious[j.ProteinName] = 0
+			}
+		}
+	}
+
 	// assign razor
 	var razorMap = make(map[string]string)
 	for i := range peptideList {
 
 		var protein string
 		var candidateProteins []string
+		var candidateIndex = make(map[string]uint8)
 		var tnp int
 		var coverage float64
 
 		for k := range peptideList[i].MappedProteins {
-			candidateProteins = append(candidateProteins, k)
+
+			r := representative[k]
+
+			_, ok := parsimonious[r]
+			_, added := candidateIndex[r]
+			if ok && !added {
+				candidateProteins = append(candidateProteins, r)
+				candidateIndex[r] = 0
+			}
 		}
 
 		sort.Strings(candidateProteins)
@@ -220,7 +250,7 @@ func ProteinInference(psm id.PepIDList) (id.PepIDList, map[string]string, map[st
 		}
 	}
 
-	return psm, razorMap, proteinCoverageMap
+	return psm, razorMap, proteinCoverageMap, groups
 }
 
 // calculateProteinCoverage returns a percentage of coverage based on a set of peptides
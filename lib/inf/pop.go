@@ -0,0 +1,425 @@
+package inf
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"philosopher/lib/id"
+	"philosopher/lib/prf"
+	"philosopher/lib/spc"
+)
+
+// nspBins are the lower limits of the number of sibling peptides bins used by the NSP model
+var nspBins = []float64{0, 0.25, 1, 2, 5, 10, 20}
+
+const (
+	nspIterations    = 3
+	weightIterations = 50
+	weightTolerance  = 0.0001
+)
+
+// validationIon is a peptide ion and its evidence collected from the PSMs
+type validationIon struct {
+	key         string
+	sequence    string
+	modified    string
+	charge      uint8
+	mass        float64
+	ntt         uint8
+	probability float64
+	instances   int
+}
+
+// ValidateProteins is a native replacement for ProteinProphet. The peptide probabilities are adjusted
+// by the number of sibling peptides (NSP) from the same protein, the shared peptides are apportioned
+// among their parent proteins according to the protein probabilities, and the protein and group
+// probabilities are calculated from the adjusted and weighted peptide evidence
+func ValidateProteins(p id.PepXML, minProb float64, useNSP bool) spc.ProtXML {
+
+	var psm id.PepIDList
+	for _, i := range p.PeptideIdentification {
+		if i.Probability >= minProb && len(i.Peptide) > 0 {
+			psm = append(psm, i)
+		}
+	}
+
+	// collect the peptide ions, the ion probability is the best among its spectra
+	var ions = make(map[string]*validationIon)
+	var sequenceIons = make(map[string][]string)
+
+	for _, i := range psm {
+
+		key := prf.IonKey(i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
+
+		v, ok := ions[key]
+		if !ok {
+			v = &validationIon{
+				key:      key,
+				sequence: i.Peptide,
+				modified: i.ModifiedPeptide,
+				charge:   i.AssumedCharge,
+				mass:     i.CalcNeutralPepMass,
+				ntt:      i.NumberOfEnzymaticTermini,
+			}
+			ions[key] = v
+			sequenceIons[i.Peptide] = append(sequenceIons[i.Peptide], key)
+		}
+
+		v.instances++
+		if i.Probability > v.probability {
+			v.probability = i.Probability
+		}
+	}
+
+	for i := range sequenceIons {
+		sort.Strings(sequenceIons[i])
+	}
+
+	groups := GroupProteins(psm)
+
+	// sequence -> entries containing it
+	var parents = make(map[string][]string)
+	var entries = make(map[string]GroupedProtein)
+
+	for _, i := range groups {
+		for _, j := range i.Proteins {
+			entries[j.ProteinName] = j
+			for k := range j.Peptides {
+				parents[k] = append(parents[k], j.ProteinName)
+			}
+		}
+	}
+
+	for i := range parents {
+		sort.Strings(parents[i])
+	}
+
+	// the shared peptides start equally apportioned among their parent proteins
+	var weights = make(map[string]map[string]float64)
+	for k, v := range parents {
+		weights[k] = make(map[string]float64)
+		for _, i := range v {
+			weights[k][i] = 1 / float64(len(v))
+		}
+	}
+
+	// entry -> ion -> adjusted probability
+	var adjusted = make(map[string]map[string]float64)
+	var siblings = make(map[string]map[string]float64)
+
+	for k, v := range entries {
+		adjusted[k] = make(map[string]float64)
+		siblings[k] = make(map[string]float64)
+		for i := range v.Peptides {
+			for _, j := range sequenceIons[i] {
+				adjusted[k][j] = ions[j].probability
+			}
+		}
+	}
+
+	var probabilities = make(map[string]float64)
+
+	iterations := 1
+	if useNSP {
+		iterations = nspIterations
+	}
+
+	for n := 0; n < iterations; n++ {
+
+		if useNSP {
+			adjustNSP(entries, ions, sequenceIons, weights, adjusted, siblings)
+		}
+
+		apportionWeights(entries, parents, sequenceIons, weights, adjusted, probabilities)
+	}
+
+	return buildProtXML(p, minProb, useNSP, groups, ions, sequenceIons, parents, weights, adjusted, siblings, probabilities)
+}
+
+// adjustNSP recalculates the peptide probabilities using the number of sibling peptides, the
+// expected sibling distributions for correct and incorrect peptides are learned from the data
+func adjustNSP(entries map[string]GroupedProtein, ions map[string]*validationIon, sequenceIons map[string][]string, weights map[string]map[string]float64, adjusted, siblings map[string]map[string]float64) {
+
+	var pos = make([]float64, len(nspBins))
+	var neg = make([]float64, len(nspBins))
+	var bins = make(map[string]map[string]int)
+
+	for k, v := range entries {
+
+		// best adjusted probability for each sequence of the protein
+		var best = make(map[string]float64)
+		for i := range v.Peptides {
+			for _, j := range sequenceIons[i] {
+				if adjusted[k][j] > best[i] {
+					best[i] = adjusted[k][j]
+				}
+			}
+		}
+
+		var total float64
+		for i, j := range best {
+			total += weights[i][k] * j
+		}
+
+		bins[k] = make(map[string]int)
+
+		for i := range v.Peptides {
+
+			nsp := total - weights[i][k]*best[i]
+			if nsp < 0 {
+				nsp = 0
+			}
+
+			for _, j := range sequenceIons[i] {
+
+				b := nspBin(nsp)
+				p := ions[j].probability
+
+				siblings[k][j] = nsp
+				bins[k][j] = b
+
+				pos[b] += p
+				neg[b] += 1 - p
+			}
+		}
+	}
+
+	// pseudo counts keep the empty bins neutral
+	var posTotal, negTotal float64
+	for i := range pos {
+		pos[i] += 0.01
+		neg[i] += 0.01
+		posTotal += pos[i]
+		negTotal += neg[i]
+	}
+
+	for k, v := range bins {
+		for j, b := range v {
+
+			p := ions[j].probability
+			fp := pos[b] / posTotal
+			fn := neg[b] / negTotal
+
+			if p*fp+(1-p)*fn > 0 {
+				adjusted[k][j] = (p * fp) / (p*fp + (1-p)*fn)
+			}
+		}
+	}
+
+	return
+}
+
+// apportionWeights iterates between the protein probabilities and the shared peptide weights until
+// the weights converge, proteins with a better support receive a larger share of the shared peptides
+func apportionWeights(entries map[string]GroupedProtein, parents map[string][]string, sequenceIons map[string][]string, weights map[string]map[string]float64, adjusted map[string]map[string]float64, probabilities map[string]float64) {
+
+	for n := 0; n < weightIterations; n++ {
+
+		for k, v := range entries {
+			absent := 1.0
+			for i := range v.Peptides {
+				for _, j := range sequenceIons[i] {
+					absent *= 1 - weights[i][k]*adjusted[k][j]
+				}
+			}
+			probabilities[k] = 1 - absent
+		}
+
+		var delta float64
+
+		for k, v := range parents {
+
+			var total float64
+			for _, i := range v {
+				total += probabilities[i]
+			}
+
+			for _, i := range v {
+
+				w := 1 / float64(len(v))
+				if total > 0 {
+					w = probabilities[i] / total
+				}
+
+				delta = math.Max(delta, math.Abs(w-weights[k][i]))
+				weights[k][i] = w
+			}
+		}
+
+		if delta < weightTolerance {
+			break
+		}
+	}
+
+	return
+}
+
+// nspBin returns the bin index for the given number of sibling peptides
+func nspBin(nsp float64) int {
+
+	var bin int
+	for i, j := range nspBins {
+		if nsp >= j {
+			bin = i
+		}
+	}
+
+	return bin
+}
+
+// buildProtXML organizes the validated proteins in the protXML structure, the groups are ranked by
+// their probabilities
+func buildProtXML(p id.PepXML, minProb float64, useNSP bool, groups []ProteinGroup, ions map[string]*validationIon, sequenceIons, parents map[string][]string, weights, adjusted, siblings map[string]map[string]float64, probabilities map[string]float64) spc.ProtXML {
+
+	var px spc.ProtXML
+	var ps spc.ProteinSummary
+	var predicted float64
+	var totalInstances int
+
+	for _, i := range ions {
+		totalInstances += i.instances
+	}
+
+	var groupProbs = make(map[uint32]float64)
+
+	for _, i := range groups {
+
+		// every ion counts once for the group, with its best adjusted probability
+		var best = make(map[string]float64)
+		for _, j := range i.Proteins {
+			for k := range j.Peptides {
+				for _, l := range sequenceIons[k] {
+					if adjusted[j.ProteinName][l] > best[l] {
+						best[l] = adjusted[j.ProteinName][l]
+					}
+				}
+			}
+		}
+
+		absent := 1.0
+		for _, j := range best {
+			absent *= 1 - j
+		}
+
+		groupProbs[i.Number] = 1 - absent
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groupProbs[groups[i].Number] > groupProbs[groups[j].Number]
+	})
+
+	for gi, i := range groups {
+
+		var grp spc.ProteinGroup
+		grp.GroupNumber = uint32(gi + 1)
+		grp.Probability = groupProbs[i.Number]
+
+		for _, j := range i.Proteins {
+
+			var pro spc.Protein
+
+			pro.ProteinName = []byte(j.ProteinName)
+			pro.NumberIndistinguishableProteins = int16(len(j.IndistinguishableProtein) + 1)
+			pro.Probability = probabilities[j.ProteinName]
+			pro.GroupSiblingID = []byte(j.SiblingID)
+			pro.Parameter = spc.Parameter{Name: "prot_length", Value: "0"}
+
+			predicted += pro.Probability
+
+			for _, k := range j.IndistinguishableProtein {
+				pro.IndistinguishableProtein = append(pro.IndistinguishableProtein, spc.IndistinguishableProtein{ProteinName: k})
+			}
+
+			var sequences []string
+			for k := range j.Peptides {
+				sequences = append(sequences, k)
+			}
+			sort.Strings(sequences)
+
+			pro.UniqueStrippedPeptides = []byte(strings.Join(sequences, "+"))
+			pro.TotalNumberIndPeptides = len(sequences)
+
+			var weightedInstances float64
+
+			for _, k := range sequences {
+				for _, l := range sequenceIons[k] {
+
+					ion := ions[l]
+					w := weights[k][j.ProteinName]
+
+					var pep spc.Peptide
+					pep.PeptideSequence = []byte(ion.sequence)
+					pep.Charge = ion.charge
+					pep.InitialProbability = ion.probability
+					pep.NSPAdjustedPprobability = float32(adjusted[j.ProteinName][l])
+					pep.Weight = w
+					pep.GroupWeight = 1
+					pep.NEnzymaticTermini = ion.ntt
+					pep.NIstances = ion.instances
+					pep.CalcNeutralPepMass = ion.mass
+					pep.ModificationInfo.ModifiedPeptide = []byte(ion.modified)
+
+					if useNSP {
+						pep.NSiblingPeptides = float32(siblings[j.ProteinName][l])
+						pep.NSiblingPeptidesBin = float32(nspBin(siblings[j.ProteinName][l]))
+					}
+
+					if len(parents[k]) == 1 {
+						pep.IsNondegenerateEvidence = []byte("Y")
+					} else {
+						pep.IsNondegenerateEvidence = []byte("N")
+					}
+
+					if w >= 0.5 {
+						pep.IsContributingEvidence = []byte("Y")
+					} else {
+						pep.IsContributingEvidence = []byte("N")
+					}
+
+					for _, m := range parents[k] {
+						if m != j.ProteinName {
+							pep.PeptideParentProtein = append(pep.PeptideParentProtein, spc.PeptideParentProtein{ProteinName: []byte(m)})
+						}
+					}
+
+					pro.TotalNumberPeptides += ion.instances
+					weightedInstances += w * float64(ion.instances)
+
+					pro.Peptide = append(pro.Peptide, pep)
+				}
+			}
+
+			if totalInstances > 0 {
+				pro.PctSpectrumIDs = float32(100 * weightedInstances / float64(totalInstances))
+			}
+
+			grp.Protein = append(grp.Protein, pro)
+		}
+
+		ps.ProteinGroup = append(ps.ProteinGroup, grp)
+	}
+
+	nsp := "N"
+	if useNSP {
+		nsp = "Y"
+	}
+
+	ps.ProteinSummaryHeader.ReferenceDatabase = []byte(p.Database)
+	ps.ProteinSummaryHeader.MinPeptideProbability = float32(minProb)
+	ps.ProteinSummaryHeader.NumPredictedCorrectProteins = float32(predicted)
+	ps.ProteinSummaryHeader.TotalNumberSpectrumIDs = float32(totalInstances)
+	ps.ProteinSummaryHeader.ProgramDetails.Analysis = []byte("proteinprophet")
+	ps.ProteinSummaryHeader.ProgramDetails.Time = []byte(time.Now().Format(time.RFC3339))
+	ps.ProteinSummaryHeader.ProgramDetails.Version = []byte("Philosopher native ProteinProphet")
+	ps.ProteinSummaryHeader.ProgramDetails.ProteinProphetDetails.OccamFlag = []byte("Y")
+	ps.ProteinSummaryHeader.ProgramDetails.ProteinProphetDetails.GroupsFlag = []byte("Y")
+	ps.ProteinSummaryHeader.ProgramDetails.ProteinProphetDetails.DegenFlag = []byte("Y")
+	ps.ProteinSummaryHeader.ProgramDetails.ProteinProphetDetails.NSPFlag = []byte(nsp)
+	ps.ProteinSummaryHeader.ProgramDetails.ProteinProphetDetails.FPKMFlag = []byte("N")
+
+	px.ProteinSummary = ps
+
+	return px
+}
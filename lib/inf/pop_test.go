@@ -0,0 +1,52 @@
+package inf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"philosopher/lib/id"
+	"philosopher/lib/spc"
+)
+
+func TestValidateProteins(t *testing.T) {
+
+	var p id.PepXML
+	p.Database = "test.fas"
+	p.PeptideIdentification = id.PepIDList{
+		{Peptide: "AAAK", Protein: "P1", Probability: 0.99, AssumedCharge: 2},
+		{Peptide: "BBBK", Protein: "P1", Probability: 0.95, AssumedCharge: 2, AlternativeProteinsIndexed: map[string]int{"P2": 1}},
+		{Peptide: "CCCK", Protein: "P3", Probability: 0.30, AssumedCharge: 2},
+		{Peptide: "DDDK", Protein: "P4", Probability: 0.01, AssumedCharge: 2},
+	}
+
+	px := ValidateProteins(p, 0.05, true)
+	groups := px.ProteinSummary.ProteinGroup
+
+	if len(groups) != 2 {
+		t.Fatalf("ValidateProteins() got = %v groups, want %v", len(groups), 2)
+	}
+
+	if string(groups[0].Protein[0].ProteinName) != "P1" || groups[0].Probability < groups[1].Probability {
+		t.Errorf("ValidateProteins() first group got = %s %v, want P1 ranked first", groups[0].Protein[0].ProteinName, groups[0].Probability)
+	}
+
+	// the shared peptide is apportioned to the protein with the unique evidence
+	for _, i := range groups[0].Protein[0].Peptide {
+		if string(i.PeptideSequence) == "BBBK" && i.Weight < 0.5 {
+			t.Errorf("ValidateProteins() shared peptide weight got = %v, want >= 0.5", i.Weight)
+		}
+	}
+
+	f := filepath.Join(os.TempDir(), "philosopher-validate.prot.xml")
+	defer os.Remove(f)
+
+	px.Write(f)
+
+	var parsed spc.ProtXML
+	parsed.Parse(f)
+
+	if len(parsed.ProteinSummary.ProteinGroup) != len(groups) {
+		t.Errorf("Parse() got = %v groups, want %v", len(parsed.ProteinSummary.ProteinGroup), len(groups))
+	}
+}
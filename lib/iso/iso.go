@@ -156,3 +156,43 @@ type Channel16 struct {
 	Mz         float64
 	Intensity  float64
 }
+
+// Add sums the channel intensities from b, the channel names and m/z values are taken from b
+func (a *Labels) Add(b Labels) {
+
+	a.Channel1.Name, a.Channel1.CustomName, a.Channel1.Mz = b.Channel1.Name, b.Channel1.CustomName, b.Channel1.Mz
+	a.Channel2.Name, a.Channel2.CustomName, a.Channel2.Mz = b.Channel2.Name, b.Channel2.CustomName, b.Channel2.Mz
+	a.Channel3.Name, a.Channel3.CustomName, a.Channel3.Mz = b.Channel3.Name, b.Channel3.CustomName, b.Channel3.Mz
+	a.Channel4.Name, a.Channel4.CustomName, a.Channel4.Mz = b.Channel4.Name, b.Channel4.CustomName, b.Channel4.Mz
+	a.Channel5.Name, a.Channel5.CustomName, a.Channel5.Mz = b.Channel5.Name, b.Channel5.CustomName, b.Channel5.Mz
+	a.Channel6.Name, a.Channel6.CustomName, a.Channel6.Mz = b.Channel6.Name, b.Channel6.CustomName, b.Channel6.Mz
+	a.Channel7.Name, a.Channel7.CustomName, a.Channel7.Mz = b.Channel7.Name, b.Channel7.CustomName, b.Channel7.Mz
+	a.Channel8.Name, a.Channel8.CustomName, a.Channel8.Mz = b.Channel8.Name, b.Channel8.CustomName, b.Channel8.Mz
+	a.Channel9.Name, a.Channel9.CustomName, a.Channel9.Mz = b.Channel9.Name, b.Channel9.CustomName, b.Channel9.Mz
+	a.Channel10.Name, a.Channel10.CustomName, a.Channel10.Mz = b.Channel10.Name, b.Channel10.CustomName, b.Channel10.Mz
+	a.Channel11.Name, a.Channel11.CustomName, a.Channel11.Mz = b.Channel11.Name, b.Channel11.CustomName, b.Channel11.Mz
+	a.Channel12.Name, a.Channel12.CustomName, a.Channel12.Mz = b.Channel12.Name, b.Channel12.CustomName, b.Channel12.Mz
+	a.Channel13.Name, a.Channel13.CustomName, a.Channel13.Mz = b.Channel13.Name, b.Channel13.CustomName, b.Channel13.Mz
+	a.Channel14.Name, a.Channel14.CustomName, a.Channel14.Mz = b.Channel14.Name, b.Channel14.CustomName, b.Channel14.Mz
+	a.Channel15.Name, a.Channel15.CustomName, a.Channel15.Mz = b.Channel15.Name, b.Channel15.CustomName, b.Channel15.Mz
+	a.Channel16.Name, a.Channel16.CustomName, a.Channel16.Mz = b.Channel16.Name, b.Channel16.CustomName, b.Channel16.Mz
+
+	a.Channel1.Intensity += b.Channel1.Intensity
+	a.Channel2.Intensity += b.Channel2.Intensity
+	a.Channel3.Intensity += b.Channel3.Intensity
+	a.Channel4.Intensity += b.Channel4.Intensity
+	a.Channel5.Intensity += b.Channel5.Intensity
+	a.Channel6.Intensity += b.Channel6.Intensity
+	a.Channel7.Intensity += b.Channel7.Intensity
+	a.Channel8.Intensity += b.Channel8.Intensity
+	a.Channel9.Intensity += b.Channel9.Intensity
+	a.Channel10.Intensity += b.Channel10.Intensity
+	a.Channel11.Intensity += b.Channel11.Intensity
+	a.Channel12.Intensity += b.Channel12.Intensity
+	a.Channel13.Intensity += b.Channel13.Intensity
+	a.Channel14.Intensity += b.Channel14.Intensity
+	a.Channel15.Intensity += b.Channel15.Intensity
+	a.Channel16.Intensity += b.Channel16.Intensity
+
+	return
+}
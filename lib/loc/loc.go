@@ -0,0 +1,566 @@
+// Package loc (Localization) scores modification sites from the fragment ion spectra
+package loc
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/id"
+	"philosopher/lib/mod"
+	"philosopher/lib/msg"
+	"philosopher/lib/mzn"
+	"philosopher/lib/sys"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+const (
+	// peakDepth is the number of peaks kept on each window of the spectrum
+	peakDepth = 6
+
+	// peakWindow is the m/z width of the peak picking windows
+	peakWindow = 100.0
+
+	// maxPermutations limits the site combinations scored for each peptide
+	maxPermutations = 2000
+)
+
+// Definition is a modification to be localized
+type Definition struct {
+	Key      string
+	Residues string
+	MassDiff float64
+}
+
+// Result is the localization of one modification on a PSM, using the PTMProphet conventions
+type Result struct {
+	Sites      int
+	PTMPeptide string
+}
+
+// Localization has the localization results by PSM
+type Localization map[string]map[string]Result
+
+// localizationFile is the workspace layout of the localization results, keeping the identification
+// files used for scoring the sites
+type localizationFile struct {
+	Sources []string
+	Results Localization
+}
+
+// Peak is a fragment ion peak
+type Peak struct {
+	Mz        float64
+	Intensity float64
+}
+
+// ParseDefinitions reads the modifications using the PTMProphet format, such as STY:79.966331,K:114.0429,
+// terminal modifications are not localized
+func ParseDefinitions(mods string) []Definition {
+
+	var defs []Definition
+
+	if len(strings.TrimSpace(mods)) == 0 {
+		mods = "STY:79.966331"
+	}
+
+	for _, i := range strings.Split(mods, ",") {
+
+		fields := strings.Split(strings.TrimSpace(i), ":")
+		if len(fields) < 2 {
+			continue
+		}
+
+		residues := strings.ToUpper(strings.Trim(fields[0], "nc"))
+		if len(residues) == 0 {
+			continue
+		}
+
+		mass, e := strconv.ParseFloat(fields[1], 64)
+		if e != nil {
+			continue
+		}
+
+		keys := mod.PTMProphetKeys(fields[0] + ":" + fields[1])
+		if len(keys) == 0 {
+			continue
+		}
+
+		defs = append(defs, Definition{Key: keys[0], Residues: residues, MassDiff: mass})
+	}
+
+	return defs
+}
+
+// Key identifies a PSM on the localization results
+func Key(psm id.PeptideIdentification) string {
+
+	peptide := psm.ModifiedPeptide
+	if len(peptide) == 0 {
+		peptide = psm.Peptide
+	}
+
+	return fmt.Sprintf("%s#%s", strings.Split(psm.Spectrum, "#")[0], peptide)
+}
+
+// Localize scores the modification sites for the PSMs with the spectra from the same run
+func Localize(psms id.PepIDList, spectra map[int]mzn.Spectrum, defs []Definition, tol float64, maxFragZ int) Localization {
+
+	var l = make(Localization)
+
+	for _, i := range psms {
+
+		spec, ok := spectra[i.Scan]
+		if !ok {
+			continue
+		}
+
+		peaks := pickPeaks(spec.Mz.DecodedStream, spec.Intensity.DecodedStream)
+		if len(peaks) == 0 {
+			continue
+		}
+
+		// negative limits are subtracted from the precursor charge, as in PTMProphet
+		maxZ := int(i.AssumedCharge) - 1
+		if maxFragZ > 0 && maxZ > maxFragZ {
+			maxZ = maxFragZ
+		} else if maxFragZ < 0 {
+			maxZ = int(i.AssumedCharge) + maxFragZ
+		}
+		if maxZ < 1 {
+			maxZ = 1
+		}
+
+		for _, j := range defs {
+
+			res, ok := Score(i, j, peaks, tol, maxZ)
+			if !ok {
+				continue
+			}
+
+			key := Key(i)
+			if _, ok := l[key]; !ok {
+				l[key] = make(map[string]Result)
+			}
+			l[key][j.Key] = res
+		}
+	}
+
+	return l
+}
+
+// Score calculates the site probabilities of a modification on the PSM using the binomial probability
+// of matching the site-determining b and y ions by chance for each site permutation
+func Score(psm id.PeptideIdentification, def Definition, peaks []Peak, tol float64, maxZ int) (Result, bool) {
+
+	var result Result
+
+	masses, nTerm, cTerm := residueMasses(psm)
+	if len(masses) == 0 {
+		return result, false
+	}
+
+	// the modification is removed from the residues so it can be placed on every candidate
+	var count int
+	for _, i := range psm.Modifications.Index {
+		pos, e := strconv.Atoi(i.Position)
+		if e != nil || pos < 1 || pos > len(masses) {
+			continue
+		}
+		if isDefinition(i, def) {
+			masses[pos-1] -= i.MassDiff
+			count++
+		}
+	}
+
+	if count == 0 {
+		return result, false
+	}
+
+	var candidates []int
+	for i := range psm.Peptide {
+		if strings.IndexByte(def.Residues, psm.Peptide[i]) >= 0 && !modifiedByOther(psm, i+1, def) {
+			candidates = append(candidates, i)
+		}
+	}
+
+	if len(candidates) < count {
+		return result, false
+	}
+
+	probs := make([]float64, len(candidates))
+
+	perms := combinations(len(candidates), count)
+	if len(perms) == 0 || len(perms) > maxPermutations {
+		return result, false
+	}
+
+	if len(perms) == 1 {
+		for i := range probs {
+			probs[i] = 1
+		}
+	} else {
+
+		var ions = make([][]float64, len(perms))
+		for i, p := range perms {
+			modified := make([]float64, len(masses))
+			copy(modified, masses)
+			for _, j := range p {
+				modified[candidates[j]] += def.MassDiff
+			}
+			ions[i] = fragments(modified, nTerm, cTerm, maxZ)
+		}
+
+		ions = siteDetermining(ions)
+
+		scores := make([]float64, len(perms))
+		maxScore := math.Inf(-1)
+		for i := range perms {
+			scores[i] = binomialScore(ions[i], peaks, tol)
+			if scores[i] > maxScore {
+				maxScore = scores[i]
+			}
+		}
+
+		var total float64
+		weights := make([]float64, len(perms))
+		for i := range scores {
+			weights[i] = math.Pow(10, scores[i]-maxScore)
+			total += weights[i]
+		}
+
+		for i, p := range perms {
+			for _, j := range p {
+				probs[j] += weights[i] / total
+			}
+		}
+	}
+
+	var ptmPeptide strings.Builder
+	var c int
+	for i := range psm.Peptide {
+		ptmPeptide.WriteByte(psm.Peptide[i])
+		if c < len(candidates) && candidates[c] == i {
+			ptmPeptide.WriteString(fmt.Sprintf("(%.3f)", probs[c]))
+			c++
+		}
+	}
+
+	result.Sites = len(candidates)
+	result.PTMPeptide = ptmPeptide.String()
+
+	return result, true
+}
+
+// residueMasses returns the modified residue masses and the terminal modification masses of the PSM
+func residueMasses(psm id.PeptideIdentification) ([]float64, float64, float64) {
+
+	var masses = make([]float64, len(psm.Peptide))
+	var nTerm, cTerm float64
+
+	for i := range psm.Peptide {
+		aa := bio.NewFromCode(string(psm.Peptide[i]))
+		if aa.MonoIsotopeMass == 0 {
+			return nil, 0, 0
+		}
+		masses[i] = aa.MonoIsotopeMass
+	}
+
+	for _, i := range psm.Modifications.Index {
+
+		if i.AminoAcid == "N-term" || i.AminoAcid == "n-term" {
+			nTerm += i.MassDiff
+			continue
+		} else if i.AminoAcid == "C-term" || i.AminoAcid == "c-term" {
+			cTerm += i.MassDiff
+			continue
+		}
+
+		pos, e := strconv.Atoi(i.Position)
+		if e != nil || pos < 1 || pos > len(masses) {
+			continue
+		}
+
+		masses[pos-1] += i.MassDiff
+	}
+
+	return masses, nTerm, cTerm
+}
+
+func isDefinition(m mod.Modification, def Definition) bool {
+	return len(m.AminoAcid) > 0 && strings.Contains(def.Residues, m.AminoAcid) && math.Abs(m.MassDiff-def.MassDiff) < 0.01
+}
+
+// modifiedByOther reports if the residue carries a variable modification other than the one being localized
+func modifiedByOther(psm id.PeptideIdentification, pos int, def Definition) bool {
+
+	for _, i := range psm.Modifications.Index {
+		if i.Position == strconv.Itoa(pos) && !isDefinition(i, def) && i.Variable == "Y" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// combinations lists the k-sized subsets of n positions
+func combinations(n, k int) [][]int {
+
+	var list [][]int
+	var current []int
+
+	var walk func(start int)
+	walk = func(start int) {
+		if len(list) > maxPermutations {
+			return
+		}
+		if len(current) == k {
+			c := make([]int, k)
+			copy(c, current)
+			list = append(list, c)
+			return
+		}
+		for i := start; i < n; i++ {
+			current = append(current, i)
+			walk(i + 1)
+			current = current[:len(current)-1]
+		}
+	}
+
+	walk(0)
+
+	return list
+}
+
+// fragments returns the b and y ion m/z values up to the given charge
+func fragments(masses []float64, nTerm, cTerm float64, maxZ int) []float64 {
+
+	var ions []float64
+
+	var total float64
+	for _, i := range masses {
+		total += i
+	}
+
+	b := nTerm
+	for i := 0; i < len(masses)-1; i++ {
+
+		b += masses[i]
+		y := total + nTerm + cTerm + bio.Water - b
+
+		for z := 1; z <= maxZ; z++ {
+			ions = append(ions, (b+float64(z)*bio.Proton)/float64(z))
+			ions = append(ions, (y+float64(z)*bio.Proton)/float64(z))
+		}
+	}
+
+	return ions
+}
+
+// siteDetermining removes the fragments shared by all permutations
+func siteDetermining(ions [][]float64) [][]float64 {
+
+	var shared = make(map[string]int)
+	for _, i := range ions {
+		var seen = make(map[string]uint8)
+		for _, j := range i {
+			k := fmt.Sprintf("%.5f", j)
+			if _, ok := seen[k]; !ok {
+				seen[k] = 0
+				shared[k]++
+			}
+		}
+	}
+
+	var determining = make([][]float64, len(ions))
+	for i := range ions {
+		for _, j := range ions[i] {
+			if shared[fmt.Sprintf("%.5f", j)] < len(ions) {
+				determining[i] = append(determining[i], j)
+			}
+		}
+	}
+
+	return determining
+}
+
+// binomialScore is the -log10 probability of matching at least the observed number of ions by chance
+func binomialScore(ions []float64, peaks []Peak, tol float64) float64 {
+
+	if len(ions) == 0 {
+		return 0
+	}
+
+	var matched int
+	var mzSum float64
+	for _, i := range ions {
+		mzSum += i
+		if matchPeak(peaks, i, tol) {
+			matched++
+		}
+	}
+
+	// chance of a random match for the average fragment tolerance
+	tolDa := (mzSum / float64(len(ions))) * tol * 1e-6
+	p := float64(peakDepth) * 2 * tolDa / peakWindow
+	if p >= 1 {
+		p = 0.99
+	}
+
+	var cumulative float64
+	n := len(ions)
+	for k := matched; k <= n; k++ {
+		cumulative += math.Exp(logChoose(n, k) + float64(k)*math.Log(p) + float64(n-k)*math.Log(1-p))
+	}
+
+	if cumulative <= 0 {
+		return 300
+	}
+
+	return -math.Log10(cumulative)
+}
+
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// matchPeak searches the sorted peaks for the fragment within the ppm tolerance
+func matchPeak(peaks []Peak, mz, tol float64) bool {
+
+	delta := mz * tol * 1e-6
+
+	i := sort.Search(len(peaks), func(i int) bool { return peaks[i].Mz >= mz-delta })
+
+	return i < len(peaks) && peaks[i].Mz <= mz+delta
+}
+
+// pickPeaks keeps the most intense peaks on each window of the spectrum
+func pickPeaks(mz, intensity []float64) []Peak {
+
+	var windows = make(map[int][]Peak)
+
+	for i := range mz {
+		if i >= len(intensity) || intensity[i] <= 0 {
+			continue
+		}
+		w := int(mz[i] / peakWindow)
+		windows[w] = append(windows[w], Peak{Mz: mz[i], Intensity: intensity[i]})
+	}
+
+	var peaks []Peak
+	for _, v := range windows {
+		sort.Slice(v, func(i, j int) bool { return v[i].Intensity > v[j].Intensity })
+		if len(v) > peakDepth {
+			v = v[:peakDepth]
+		}
+		peaks = append(peaks, v...)
+	}
+
+	sort.Slice(peaks, func(i, j int) bool { return peaks[i].Mz < peaks[j].Mz })
+
+	return peaks
+}
+
+// Apply fills the localization of the PSMs that have no PTMProphet results
+func (l Localization) Apply(psms id.PepIDList) {
+
+	if len(l) == 0 {
+		return
+	}
+
+	for i := range psms {
+
+		if len(psms[i].LocalizedPTMSites) > 0 {
+			continue
+		}
+
+		res, ok := l[Key(psms[i])]
+		if !ok {
+			continue
+		}
+
+		psms[i].LocalizedPTMSites = make(map[string]int)
+		psms[i].LocalizedPTMMassDiff = make(map[string]string)
+
+		for k, v := range res {
+			psms[i].LocalizedPTMSites[k] = v.Sites
+			psms[i].LocalizedPTMMassDiff[k] = v.PTMPeptide
+		}
+	}
+
+	return
+}
+
+// Serialize saves the localization results to the workspace, together with the names of the
+// identification files used for scoring
+func (l Localization) Serialize(sources []string) {
+
+	b, e := msgpack.Marshal(&localizationFile{Sources: sources, Results: l})
+	if e != nil {
+		msg.MarshalFile(e, "fatal")
+	}
+
+	e = ioutil.WriteFile(sys.LocBin(), b, sys.FilePermission())
+	if e != nil {
+		msg.SerializeFile(e, "fatal")
+	}
+
+	return
+}
+
+// Restore reads the localization results from the workspace, the results are empty when the
+// native localization was not executed. Results scored from files that are not part of the given
+// PSMs are stale, they are removed from the workspace and not returned
+func Restore(psms id.PepIDList) Localization {
+
+	var l localizationFile
+
+	if _, e := os.Stat(sys.LocBin()); e != nil {
+		return make(Localization)
+	}
+
+	b, e := ioutil.ReadFile(sys.LocBin())
+	if e != nil {
+		msg.ReadFile(e, "warning")
+	}
+
+	e = msgpack.Unmarshal(b, &l)
+	if e != nil {
+		msg.DecodeMsgPck(e, "warning")
+	}
+
+	if !fromSources(psms, l.Sources) {
+		msg.Custom(errors.New("the native site localization was computed for other identification files and was discarded, run ptmprophet again to localize the sites"), "warning")
+		os.Remove(sys.LocBin())
+		return make(Localization)
+	}
+
+	return l.Results
+}
+
+// fromSources checks if the PSMs come from the identification files used for the localization
+func fromSources(psms id.PepIDList, sources []string) bool {
+
+	var files = make(map[string]uint8)
+	for _, i := range sources {
+		files[i] = 0
+	}
+
+	for _, i := range psms {
+		if _, ok := files[i.SpectrumFile]; ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,171 @@
+package loc
+
+import (
+	"strings"
+	"testing"
+
+	"philosopher/lib/id"
+	"philosopher/lib/mod"
+)
+
+func phosphoPSM(peptide string, position string) id.PeptideIdentification {
+
+	var psm id.PeptideIdentification
+
+	psm.Spectrum = "run.01000.01000.2#run.pep.xml"
+	psm.Peptide = peptide
+	psm.AssumedCharge = 2
+	psm.Modifications.Index = map[string]mod.Modification{
+		"S#" + position: {Position: position, AminoAcid: "S", MassDiff: 79.966331, Variable: "Y"},
+	}
+
+	return psm
+}
+
+func TestParseDefinitions(t *testing.T) {
+
+	defs := ParseDefinitions("STY:79.966331,nK:42.010565,n:42.010565")
+
+	if len(defs) != 2 {
+		t.Fatalf("ParseDefinitions() = %v, want 2 definitions", defs)
+	}
+
+	if defs[0].Key != "PTMProphet_STY79.9663" || defs[0].Residues != "STY" {
+		t.Errorf("ParseDefinitions() = %v", defs[0])
+	}
+
+	if defs[1].Residues != "K" {
+		t.Errorf("ParseDefinitions() = %v, want the terminal flag removed", defs[1])
+	}
+
+	if len(ParseDefinitions("")) != 1 {
+		t.Errorf("ParseDefinitions() should default to phosphorylation")
+	}
+}
+
+func Test_combinations(t *testing.T) {
+
+	tests := []struct {
+		name string
+		n    int
+		k    int
+		want int
+	}{
+		{name: "Testing single site", n: 3, k: 1, want: 3},
+		{name: "Testing two sites", n: 4, k: 2, want: 6},
+		{name: "Testing all sites", n: 3, k: 3, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := combinations(tt.n, tt.k); len(got) != tt.want {
+				t.Errorf("combinations() = %v, want %v subsets", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+
+	def := ParseDefinitions("STY:79.966331")[0]
+
+	// the spectrum has the fragments of the phosphorylation on the second residue
+	psm := phosphoPSM("GSAAGAASK", "2")
+
+	masses, nTerm, cTerm := residueMasses(psm)
+
+	var peaks []Peak
+	for _, i := range fragments(masses, nTerm, cTerm, 1) {
+		peaks = append(peaks, Peak{Mz: i, Intensity: 100})
+	}
+	peaks = pickPeaks(mzList(peaks), intensityList(peaks))
+
+	got, ok := Score(psm, def, peaks, 10, 1)
+	if !ok {
+		t.Fatalf("Score() did not localize the PSM")
+	}
+
+	if got.Sites != 2 || !strings.HasPrefix(got.PTMPeptide, "GS(1.000)") || !strings.HasSuffix(got.PTMPeptide, "S(0.000)K") {
+		t.Errorf("Score() = %v", got)
+	}
+
+	// a single candidate is localized without spectral evidence
+	single, ok := Score(phosphoPSM("GSAAGAAGK", "2"), def, nil, 10, 1)
+	if !ok || single.PTMPeptide != "GS(1.000)AAGAAGK" {
+		t.Errorf("Score() = %v", single)
+	}
+}
+
+func TestLocalization_Apply(t *testing.T) {
+
+	psm := phosphoPSM("GSAAGAASK", "2")
+	done := phosphoPSM("GSAAGAASK", "2")
+	done.Spectrum = "run.02000.02000.2#run.pep.xml"
+	done.LocalizedPTMSites = map[string]int{"PTMProphet_STY79.9663": 2}
+
+	l := Localization{
+		Key(psm):  {"PTMProphet_STY79.9663": {Sites: 2, PTMPeptide: "GS(0.900)AAGAAS(0.100)K"}},
+		Key(done): {"PTMProphet_STY79.9663": {Sites: 2, PTMPeptide: "GS(0.500)AAGAAS(0.500)K"}},
+	}
+
+	psms := id.PepIDList{psm, done}
+	l.Apply(psms)
+
+	if psms[0].LocalizedPTMMassDiff["PTMProphet_STY79.9663"] != "GS(0.900)AAGAAS(0.100)K" {
+		t.Errorf("Apply() = %v", psms[0].LocalizedPTMMassDiff)
+	}
+
+	if _, ok := psms[1].LocalizedPTMMassDiff["PTMProphet_STY79.9663"]; ok {
+		t.Errorf("Apply() should keep the PTMProphet results")
+	}
+}
+
+func mzList(peaks []Peak) []float64 {
+	var list []float64
+	for _, i := range peaks {
+		list = append(list, i.Mz)
+	}
+	return list
+}
+
+func intensityList(peaks []Peak) []float64 {
+	var list []float64
+	for _, i := range peaks {
+		list = append(list, i.Intensity)
+	}
+	return list
+}
+
+func Test_isDefinition(t *testing.T) {
+
+	def := Definition{Key: "STY:79.966331", Residues: "STY", MassDiff: 79.966331}
+
+	tests := []struct {
+		name string
+		m    mod.Modification
+		want bool
+	}{
+		{"Testing a matching residue", mod.Modification{AminoAcid: "S", MassDiff: 79.9663}, true},
+		{"Testing another residue", mod.Modification{AminoAcid: "K", MassDiff: 79.9663}, false},
+		{"Testing a modification without residue", mod.Modification{MassDiff: 79.9663}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDefinition(tt.m, def); got != tt.want {
+				t.Errorf("isDefinition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fromSources(t *testing.T) {
+
+	psms := id.PepIDList{{SpectrumFile: "run.pep.xml"}}
+
+	if !fromSources(psms, []string{"other.pep.xml", "run.pep.xml"}) {
+		t.Errorf("fromSources() = false, want true")
+	}
+
+	if fromSources(psms, []string{"other.pep.xml"}) || fromSources(psms, nil) {
+		t.Errorf("fromSources() = true, want false")
+	}
+}
@@ -36,6 +36,7 @@ type Data struct {
 	Msconvert      Msconvert
 	Idconvert      Idconvert
 	Database       Database
+	Digest         Digest
 	MSFragger      MSFragger
 	Comet          Comet
 	PeptideProphet PeptideProphet
@@ -46,6 +47,7 @@ type Data struct {
 	Quantify       Quantify
 	BioQuant       BioQuant
 	Abacus         Abacus
+	Crosslink      Crosslink
 	Report         Report
 	TMTIntegrator  TMTIntegrator
 	Index          Index
@@ -69,17 +71,50 @@ type Idconvert struct {
 
 // Database options and parameters
 type Database struct {
-	ID        string `yaml:"id"`
-	Annot     string `yaml:"protein_database"`
-	Enz       string `yaml:"enzyme"`
-	Tag       string `yaml:"decoy_tag"`
-	Add       string `yaml:"add"`
-	Custom    string `yaml:"custom"`
-	TimeStamp string `yaml:"timestamp"`
-	Crap      bool   `yaml:"contam"`
-	Rev       bool   `yaml:"reviewed"`
-	Iso       bool   `yaml:"isoform"`
-	NoD       bool   `yaml:"nodecoys"`
+	ID          string         `yaml:"id"`
+	Annot       string         `yaml:"protein_database"`
+	Enz         string         `yaml:"enzyme"`
+	EnzymeRules string         `yaml:"enzyme_rules"`
+	Tag         string         `yaml:"decoy_tag"`
+	Decoy       string         `yaml:"decoy_method"`
+	Seed        int64          `yaml:"decoy_seed"`
+	Add         string         `yaml:"add"`
+	Variants    string         `yaml:"variants"`
+	Custom      string         `yaml:"custom"`
+	Translate   string         `yaml:"translate"`
+	Frames      string         `yaml:"translation"`
+	GeneticCode int            `yaml:"genetic_code"`
+	MinORF      int            `yaml:"min_orf"`
+	TimeStamp   string         `yaml:"timestamp"`
+	Crap        bool           `yaml:"contam"`
+	Rev         bool           `yaml:"reviewed"`
+	Iso         bool           `yaml:"isoform"`
+	NoD         bool           `yaml:"nodecoys"`
+	Cache       bool           `yaml:"cache"`
+	List        bool           `yaml:"-" msgpack:"-"`
+	CacheDir    string         `yaml:"cache_dir"`
+	URL         string         `yaml:"url"`
+	Hash        string         `yaml:"hash"`
+	Header      HeaderTemplate `yaml:"header_template"`
+}
+
+// HeaderTemplate has the regular expressions used to parse custom FASTA headers
+type HeaderTemplate struct {
+	ID          string `yaml:"id"`
+	Gene        string `yaml:"gene"`
+	Description string `yaml:"description"`
+	Organism    string `yaml:"organism"`
+}
+
+// Digest options and parameters
+type Digest struct {
+	Enzyme          string  `yaml:"enzyme"`
+	Specificity     string  `yaml:"specificity"`
+	MissedCleavages int     `yaml:"missed_cleavages"`
+	MinLength       int     `yaml:"min_length"`
+	MaxLength       int     `yaml:"max_length"`
+	MinMass         float64 `yaml:"min_mass"`
+	MaxMass         float64 `yaml:"max_mass"`
 }
 
 // Comet options and parameters
@@ -282,6 +317,7 @@ type ProteinProphet struct {
 	Asapprophet bool    `yaml:"asapprophet"`
 	Delude      bool    `yaml:"delude"`
 	Excludemods bool    `yaml:"excludemods"`
+	Native      bool    `yaml:"native"`
 }
 
 // PTMProphet options and parameters
@@ -304,6 +340,7 @@ type PTMProphet struct {
 	KeepOld      bool    `yaml:"keepold"`
 	Verbose      bool    `yaml:"verbose"`
 	MassDiffMode bool    `yaml:"massdiffmode"`
+	Native       bool    `yaml:"native"`
 	Lability     bool    `yaml:"lability"`
 	Direct       bool    `yaml:"direct"`
 	Ifrags       bool    `yaml:"ifrags"`
@@ -315,21 +352,31 @@ type PTMProphet struct {
 type Filter struct {
 	Pex       string  `yaml:"pepxml"`
 	Pox       string  `yaml:"protxml"`
+	Mzid      string  `yaml:"mzid"`
+	Tsv       string  `yaml:"tsv"`
+	Combine   string  `yaml:"combine"`
 	Tag       string  `yaml:"tag"`
 	Mods      string  `yaml:"mods"`
+	Ranks     int     `yaml:"ranks"`
 	PsmFDR    float64 `yaml:"psmFDR"`
 	PepFDR    float64 `yaml:"peptideFDR"`
 	IonFDR    float64 `yaml:"ionFDR"`
+	ModPepFDR float64 `yaml:"modifiedPeptideFDR"`
 	PtFDR     float64 `yaml:"proteinFDR"`
+	GeneFDR   float64 `yaml:"geneFDR"`
 	ProtProb  float64 `yaml:"proteinProbability"`
 	PepProb   float64 `yaml:"peptideProbability"`
 	Weight    float64 `yaml:"peptideWeight"`
 	Model     bool    `yaml:"models"`
 	Razor     bool    `yaml:"razor"`
+	Bayes     bool    `yaml:"bayes"`
 	Picked    bool    `yaml:"picked"`
 	Seq       bool    `yaml:"sequential"`
 	TwoD      bool    `yaml:"two-dimensional"`
 	Mapmods   bool    `yaml:"mapMods"`
+	Glycan    string  `yaml:"glycanDatabase"`
+	GlycoDir  string  `yaml:"glycoSpectra"`
+	GlycanTol float64 `yaml:"glycanTolerance"`
 	Fo        bool
 	Inference bool
 }
@@ -370,6 +417,16 @@ type Abacus struct {
 	Reprint  bool    `yaml:"reprint"`
 }
 
+// Crosslink options and parameters
+type Crosslink struct {
+	Csv      string  `yaml:"csv"`
+	Mzid     string  `yaml:"mzid"`
+	Tag      string  `yaml:"tag"`
+	Score    string  `yaml:"score"`
+	FDR      float64 `yaml:"fdr"`
+	LowScore bool    `yaml:"lowScore"`
+}
+
 // BioQuant options and parameters
 type BioQuant struct {
 	UID   string  `yaml:"organismUniProtID"`
@@ -381,6 +438,7 @@ type Report struct {
 	Decoys  bool `yaml:"withDecoys"`
 	MSstats bool `yaml:"msstats"`
 	MZID    bool `yaml:"mzID"`
+	QC      bool `yaml:"qc"`
 }
 
 // TMTIntegrator options and parameters
@@ -1,5 +1,14 @@
 package mod
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/prf"
+)
+
 // Modifications is a collections of modification
 type Modifications struct {
 	Index map[string]Modification
@@ -22,3 +31,86 @@ type Modification struct {
 	Terminus          string
 	IsobaricMods      map[string]float64
 }
+
+// PhosphoKey is the localization key for the default PTMProphet STY:79.966331 modification
+const PhosphoKey = "PTMProphet_STY79.9663"
+
+// PTMProphetKeys converts the PTMProphet modification definitions, such as STY:79.966331,K:114.0429,
+// to the keys used on the localization results
+func PTMProphetKeys(mods string) []string {
+
+	var keys []string
+
+	for _, i := range strings.Split(mods, ",") {
+
+		def := strings.Split(strings.TrimSpace(i), ":")
+		if len(def) < 2 || len(def[0]) == 0 {
+			continue
+		}
+
+		mass, e := strconv.ParseFloat(def[1], 64)
+		if e != nil {
+			continue
+		}
+
+		keys = append(keys, fmt.Sprintf("PTMProphet_%s%.4f", def[0], mass))
+	}
+
+	return keys
+}
+
+// PTMName is the short label of a localization key used on report columns, the default
+// STY:79.966331 phosphorylation keeps the Phospho label used by the previous reports
+func PTMName(key string) string {
+
+	if key == PhosphoKey {
+		return "Phospho"
+	}
+
+	return strings.TrimPrefix(key, "PTMProphet_")
+}
+
+// IsResolved reports if the modification was mapped to a UniMod entry
+func (m Modification) IsResolved() bool {
+	return strings.HasPrefix(m.ID, "UNIMOD:") && len(m.Name) > 0 && m.Name != "Unknown"
+}
+
+// ProForma writes the peptide with the assigned modifications using the ProForma notation with the
+// mass shifts, such as [+42.0106]-PEPT[+79.9663]IDE, the same notation is used on every report.
+// Observed mass differences are not localized and are not included
+func (m Modifications) ProForma(peptide string) string {
+
+	p := prf.New(peptide)
+
+	var keys []string
+	for k := range m.Index {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+
+		i := m.Index[k]
+
+		if i.Type == "Observed" || i.MassDiff == 0 {
+			continue
+		}
+
+		tag := prf.NewMass(i.MassDiff)
+
+		switch strings.ToLower(i.AminoAcid) {
+		case "n-term":
+			p.NTerm = append(p.NTerm, tag)
+		case "c-term":
+			p.CTerm = append(p.CTerm, tag)
+		default:
+			pos, e := strconv.Atoi(i.Position)
+			if e != nil || pos < 1 || pos > len(peptide) {
+				continue
+			}
+			p.Residues[pos] = append(p.Residues[pos], tag)
+		}
+	}
+
+	return p.String()
+}
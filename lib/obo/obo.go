@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -179,6 +180,45 @@ func (m *Onto) Parse() {
 	return
 }
 
+// Match returns the term with the closest mass to the modification that is allowed on the site,
+// the site is an amino acid code, N-term or C-term
+func (m Onto) Match(site string, mass, tolerance float64) (Term, bool) {
+
+	var best Term
+	var found bool
+	var bestError = tolerance
+
+	if strings.EqualFold(site, "n-term") {
+		site = "N-term"
+	} else if strings.EqualFold(site, "c-term") {
+		site = "C-term"
+	}
+
+	for _, i := range m.Terms {
+
+		if i.MonoIsotopicMass == 0 {
+			continue
+		}
+
+		if _, ok := i.Sites[site]; !ok {
+			continue
+		}
+
+		diff := math.Abs(i.MonoIsotopicMass - mass)
+		if diff <= bestError {
+			// the lowest record ID wins on ties, those are the most common entries
+			if found && diff == bestError && i.RecordID > best.RecordID {
+				continue
+			}
+			best = i
+			bestError = diff
+			found = true
+		}
+	}
+
+	return best, found
+}
+
 // Serialize UniMod data structure
 func (m Onto) Serialize() {
 
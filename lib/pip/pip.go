@@ -19,6 +19,7 @@ import (
 	"philosopher/lib/ext/proteinprophet"
 	"philosopher/lib/ext/ptmprophet"
 	"philosopher/lib/fil"
+	"philosopher/lib/qcm"
 	"philosopher/lib/qua"
 	"philosopher/lib/rep"
 
@@ -26,6 +27,7 @@ import (
 	"philosopher/lib/ext/comet"
 	"philosopher/lib/ext/msfragger"
 	"philosopher/lib/met"
+	"philosopher/lib/mod"
 	"philosopher/lib/sys"
 	"philosopher/lib/wrk"
 
@@ -597,7 +599,7 @@ func FilterQuantifyReport(meta met.Data, p Directives, dir string, data []string
 			meta.Quantify.Format = "mzML"
 			meta.Quantify.Brand = p.LabelQuant.Brand
 
-			meta.Quantify = qua.RunIsobaricLabelQuantification(meta.Quantify, meta.Filter.Mapmods)
+			meta.Quantify = qua.RunIsobaricLabelQuantification(meta.Quantify, meta.Filter.Mapmods, mod.PTMProphetKeys(meta.PTMProphet.Mods))
 
 			meta.Serialize()
 		}
@@ -610,6 +612,11 @@ func FilterQuantifyReport(meta met.Data, p Directives, dir string, data []string
 			meta.Report = p.Report
 
 			rep.Run(meta)
+
+			if meta.Report.QC == true {
+				qcm.Run()
+			}
+
 			meta.Serialize()
 		}
 
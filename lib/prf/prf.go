@@ -0,0 +1,435 @@
+// Package prf (ProForma) parses and writes modified peptide sequences using the ProForma 2.0 notation
+package prf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/bio"
+)
+
+// Modification is the content of a ProForma tag, such as Phospho, UNIMOD:21, MOD:00046, +79.9663
+// or Phospho#g1(0.90). The tag text is kept as written so sequences are rendered back unchanged
+type Modification struct {
+	Value string
+}
+
+// Range is a modification placed on a stretch of residues, the positions are 1-based and inclusive
+type Range struct {
+	Start         int
+	End           int
+	Modifications []Modification
+}
+
+// Peptide is a parsed ProForma sequence
+type Peptide struct {
+	Sequence    string
+	Residues    map[int][]Modification
+	NTerm       []Modification
+	CTerm       []Modification
+	Labile      []Modification
+	Unlocalized []Modification
+	Ranges      []Range
+	Charge      int
+}
+
+// New creates an unmodified peptide
+func New(sequence string) Peptide {
+
+	var p Peptide
+
+	p.Sequence = sequence
+	p.Residues = make(map[int][]Modification)
+
+	return p
+}
+
+// NewMass creates a modification from a mass shift
+func NewMass(mass float64) Modification {
+	return Modification{Value: fmt.Sprintf("%+.4f", mass)}
+}
+
+// Name returns the modification name or accession without the localization group and score
+func (m Modification) Name() string {
+
+	v := m.Value
+
+	if i := strings.IndexByte(v, '#'); i >= 0 {
+		v = v[:i]
+	}
+
+	return v
+}
+
+// Mass returns the mass shift when the modification is given as a number
+func (m Modification) Mass() (float64, bool) {
+
+	v := strings.TrimPrefix(m.Name(), "Obs:")
+
+	if len(v) == 0 || (v[0] != '+' && v[0] != '-') {
+		return 0, false
+	}
+
+	mass, e := strconv.ParseFloat(v, 64)
+	if e != nil {
+		return 0, false
+	}
+
+	return mass, true
+}
+
+// Group returns the ambiguity group label, such as g1 from Phospho#g1(0.90)
+func (m Modification) Group() string {
+
+	i := strings.IndexByte(m.Value, '#')
+	if i < 0 {
+		return ""
+	}
+
+	g := m.Value[i+1:]
+	if j := strings.IndexByte(g, '('); j >= 0 {
+		g = g[:j]
+	}
+
+	return g
+}
+
+// Score returns the localization score given in parentheses after the group label
+func (m Modification) Score() (float64, bool) {
+
+	i := strings.LastIndexByte(m.Value, '(')
+	if i < 0 || !strings.HasSuffix(m.Value, ")") {
+		return 0, false
+	}
+
+	s, e := strconv.ParseFloat(m.Value[i+1:len(m.Value)-1], 64)
+	if e != nil {
+		return 0, false
+	}
+
+	return s, true
+}
+
+// Parse reads a ProForma 2.0 sequence
+func Parse(s string) (Peptide, error) {
+
+	p := New("")
+
+	// charge state
+	if i := strings.LastIndexByte(s, '/'); i >= 0 && !insideTag(s, i) {
+		z, e := strconv.Atoi(strings.TrimPrefix(s[i+1:], "+"))
+		if e != nil {
+			return p, fmt.Errorf("invalid charge state in %s", s)
+		}
+		p.Charge = z
+		s = s[:i]
+	}
+
+	pos := 0
+
+	// labile modifications
+	for pos < len(s) && s[pos] == '{' {
+		v, next, e := readTag(s, pos, '{', '}')
+		if e != nil {
+			return p, e
+		}
+		p.Labile = append(p.Labile, Modification{Value: v})
+		pos = next
+	}
+
+	// unlocalized and N-terminal modifications are both written before the sequence
+	var leading []Modification
+	for pos < len(s) && s[pos] == '[' {
+		v, next, e := readTag(s, pos, '[', ']')
+		if e != nil {
+			return p, e
+		}
+		pos = next
+
+		count := 1
+		if pos < len(s) && s[pos] == '^' {
+			end := pos + 1
+			for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+				end++
+			}
+			count, e = strconv.Atoi(s[pos+1 : end])
+			if e != nil {
+				return p, fmt.Errorf("invalid modification count in %s", s)
+			}
+			pos = end
+		}
+
+		for i := 0; i < count; i++ {
+			leading = append(leading, Modification{Value: v})
+		}
+
+		if pos < len(s) && s[pos] == '?' {
+			p.Unlocalized = append(p.Unlocalized, leading...)
+			leading = nil
+			pos++
+		}
+	}
+
+	if len(leading) > 0 {
+		if pos >= len(s) || s[pos] != '-' {
+			return p, fmt.Errorf("missing terminal separator in %s", s)
+		}
+		p.NTerm = leading
+		pos++
+	}
+
+	var seq strings.Builder
+	var rangeStart = -1
+
+	for pos < len(s) {
+
+		c := s[pos]
+
+		switch {
+		case c >= 'A' && c <= 'Z':
+			seq.WriteByte(c)
+			pos++
+
+		case c == '[':
+			v, next, e := readTag(s, pos, '[', ']')
+			if e != nil {
+				return p, e
+			}
+			if seq.Len() == 0 {
+				return p, fmt.Errorf("modification without residue in %s", s)
+			}
+			p.Residues[seq.Len()] = append(p.Residues[seq.Len()], Modification{Value: v})
+			pos = next
+
+		case c == '(':
+			if rangeStart >= 0 {
+				return p, fmt.Errorf("nested ranges are not supported in %s", s)
+			}
+			rangeStart = seq.Len() + 1
+			pos++
+
+		case c == ')':
+			if rangeStart < 0 {
+				return p, fmt.Errorf("unbalanced range in %s", s)
+			}
+			r := Range{Start: rangeStart, End: seq.Len()}
+			pos++
+			for pos < len(s) && s[pos] == '[' {
+				v, next, e := readTag(s, pos, '[', ']')
+				if e != nil {
+					return p, e
+				}
+				r.Modifications = append(r.Modifications, Modification{Value: v})
+				pos = next
+			}
+			p.Ranges = append(p.Ranges, r)
+			rangeStart = -1
+
+		case c == '-':
+			pos++
+			for pos < len(s) && s[pos] == '[' {
+				v, next, e := readTag(s, pos, '[', ']')
+				if e != nil {
+					return p, e
+				}
+				p.CTerm = append(p.CTerm, Modification{Value: v})
+				pos = next
+			}
+			if pos != len(s) || len(p.CTerm) == 0 {
+				return p, fmt.Errorf("invalid C-terminal modification in %s", s)
+			}
+
+		default:
+			return p, fmt.Errorf("unexpected character %q in %s", c, s)
+		}
+	}
+
+	if rangeStart >= 0 {
+		return p, fmt.Errorf("unbalanced range in %s", s)
+	}
+
+	p.Sequence = seq.String()
+	if len(p.Sequence) == 0 {
+		return p, errors.New("empty ProForma sequence")
+	}
+
+	return p, nil
+}
+
+// readTag returns the text between the delimiters starting at pos, nested delimiters are allowed
+// inside names such as Glycan:Hex(1)
+func readTag(s string, pos int, open, close byte) (string, int, error) {
+
+	depth := 0
+	for i := pos; i < len(s); i++ {
+		if s[i] == open {
+			depth++
+		} else if s[i] == close {
+			depth--
+			if depth == 0 {
+				return s[pos+1 : i], i + 1, nil
+			}
+		}
+	}
+
+	return "", pos, fmt.Errorf("unterminated modification in %s", s)
+}
+
+// insideTag reports if the position is enclosed by brackets or braces
+func insideTag(s string, pos int) bool {
+
+	depth := 0
+	for i := 0; i < pos; i++ {
+		switch s[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		}
+	}
+
+	return depth > 0
+}
+
+// String writes the peptide using the ProForma 2.0 notation
+func (p Peptide) String() string {
+
+	var b strings.Builder
+
+	for _, i := range p.Labile {
+		b.WriteString("{" + i.Value + "}")
+	}
+
+	// repeated unlocalized modifications are collapsed with a count
+	for i := 0; i < len(p.Unlocalized); {
+		j := i
+		for j < len(p.Unlocalized) && p.Unlocalized[j] == p.Unlocalized[i] {
+			j++
+		}
+		b.WriteString("[" + p.Unlocalized[i].Value + "]")
+		if j-i > 1 {
+			b.WriteString(fmt.Sprintf("^%d", j-i))
+		}
+		i = j
+	}
+	if len(p.Unlocalized) > 0 {
+		b.WriteString("?")
+	}
+
+	if len(p.NTerm) > 0 {
+		writeTags(&b, p.NTerm)
+		b.WriteString("-")
+	}
+
+	var starts = make(map[int]uint8)
+	var ends = make(map[int]Range)
+	for _, i := range p.Ranges {
+		starts[i.Start] = 0
+		ends[i.End] = i
+	}
+
+	for i := range p.Sequence {
+
+		pos := i + 1
+
+		if _, ok := starts[pos]; ok {
+			b.WriteString("(")
+		}
+
+		b.WriteByte(p.Sequence[i])
+		writeTags(&b, p.Residues[pos])
+
+		if r, ok := ends[pos]; ok {
+			b.WriteString(")")
+			writeTags(&b, r.Modifications)
+		}
+	}
+
+	if len(p.CTerm) > 0 {
+		b.WriteString("-")
+		writeTags(&b, p.CTerm)
+	}
+
+	if p.Charge != 0 {
+		b.WriteString(fmt.Sprintf("/%d", p.Charge))
+	}
+
+	return b.String()
+}
+
+func writeTags(b *strings.Builder, mods []Modification) {
+	for _, i := range mods {
+		b.WriteString("[" + i.Value + "]")
+	}
+}
+
+// Positions returns the modified residue positions in order
+func (p Peptide) Positions() []int {
+
+	var list []int
+	for k := range p.Residues {
+		list = append(list, k)
+	}
+
+	sort.Ints(list)
+
+	return list
+}
+
+// IonKey identifies a peptide ion by its sequence, charge and total mass, the mass difference from the
+// unmodified sequence is written as an unlocalized modification, such as [+79.9663]?PEPTIDE/2
+func IonKey(sequence string, charge uint8, mass float64) string {
+
+	p := New(sequence)
+	p.Charge = int(charge)
+
+	shift := mass - unmodifiedMass(sequence)
+	if math.Abs(shift) >= 0.00005 {
+		p.Unlocalized = append(p.Unlocalized, NewMass(shift))
+	}
+
+	return p.String()
+}
+
+// IsLegacyIonKey reports if the ion key uses the sequence#charge#mass form written by previous versions
+func IsLegacyIonKey(key string) bool {
+	return strings.Count(key, "#") == 2
+}
+
+// MigrateIonKey converts an ion key written by previous versions into the ProForma ion key, the
+// other keys are returned unchanged
+func MigrateIonKey(key string) string {
+
+	if !IsLegacyIonKey(key) {
+		return key
+	}
+
+	f := strings.Split(key, "#")
+
+	charge, e := strconv.Atoi(f[1])
+	if e != nil || charge < 0 || charge > 255 {
+		return key
+	}
+
+	mass, e := strconv.ParseFloat(f[2], 64)
+	if e != nil {
+		return key
+	}
+
+	return IonKey(f[0], uint8(charge), mass)
+}
+
+// unmodifiedMass is the neutral monoisotopic mass of the sequence
+func unmodifiedMass(sequence string) float64 {
+
+	mass := bio.Water
+	for _, i := range sequence {
+		mass += bio.NewFromCode(string(i)).MonoIsotopeMass
+	}
+
+	return mass
+}
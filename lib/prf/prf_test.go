@@ -0,0 +1,101 @@
+package prf
+
+import (
+	"testing"
+)
+
+func TestParse_roundTrip(t *testing.T) {
+
+	tests := []struct {
+		name string
+		seq  string
+	}{
+		{name: "Testing unmodified", seq: "PEPTIDE"},
+		{name: "Testing named modification", seq: "PEPT[Phospho]IDE"},
+		{name: "Testing accession", seq: "PEPT[UNIMOD:21]IDE"},
+		{name: "Testing PSI-MOD accession", seq: "PEPT[MOD:00046]IDE"},
+		{name: "Testing mass shift", seq: "PEPT[+79.9663]IDE"},
+		{name: "Testing negative mass shift", seq: "PEPTIDE[-18.0106]"},
+		{name: "Testing terminal modifications", seq: "[Acetyl]-PEPTIDE-[Amidated]"},
+		{name: "Testing multiple modifications", seq: "PEPK[Acetyl][Methyl]IDE"},
+		{name: "Testing labile modification", seq: "{Glycan:Hex(1)HexNAc(2)}PEPTIDE"},
+		{name: "Testing unlocalized modification", seq: "[Phospho]^2?PEPTSIDE"},
+		{name: "Testing ambiguity group", seq: "PEPS[Phospho#g1(0.90)]T[#g1(0.10)]IDE"},
+		{name: "Testing range", seq: "PR(ESFRMS)[+19.0523]ISK"},
+		{name: "Testing charge", seq: "[Acetyl]-PEPT[Phospho]IDE/2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, e := Parse(tt.seq)
+			if e != nil {
+				t.Fatalf("Parse() error = %v", e)
+			}
+			if got := p.String(); got != tt.seq {
+				t.Errorf("String() = %v, want %v", got, tt.seq)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+
+	p, e := Parse("[Acetyl]-PEPS[Phospho#g1(0.90)]T[#g1(0.10)]IDE/3")
+	if e != nil {
+		t.Fatalf("Parse() error = %v", e)
+	}
+
+	if p.Sequence != "PEPSTIDE" || p.Charge != 3 || len(p.NTerm) != 1 || p.NTerm[0].Name() != "Acetyl" {
+		t.Errorf("Parse() = %+v", p)
+	}
+
+	mod := p.Residues[4][0]
+	score, ok := mod.Score()
+	if mod.Name() != "Phospho" || mod.Group() != "g1" || !ok || score != 0.9 {
+		t.Errorf("Parse() modification = %v %v %v", mod.Name(), mod.Group(), score)
+	}
+
+	shift, _ := Parse("PEPT[+79.9663]IDE")
+	if m, ok := shift.Residues[4][0].Mass(); !ok || m != 79.9663 {
+		t.Errorf("Mass() = %v", m)
+	}
+
+	for _, i := range []string{"PEPT[Phospho", "PEP(TIDE", "PEPTIDE/x", "[Acetyl]PEPTIDE", "pEPTIDE", ""} {
+		if _, e := Parse(i); e == nil {
+			t.Errorf("Parse() should reject %s", i)
+		}
+	}
+}
+
+func TestIonKey(t *testing.T) {
+
+	// PEPTIDE has a monoisotopic mass of 799.3600
+	unmodified := IonKey("PEPTIDE", 2, 799.3600)
+	if unmodified != "PEPTIDE/2" {
+		t.Errorf("IonKey() = %v", unmodified)
+	}
+
+	modified := IonKey("PEPTIDE", 2, 879.3263)
+	if modified != "[+79.9663]?PEPTIDE/2" {
+		t.Errorf("IonKey() = %v", modified)
+	}
+
+	p, e := Parse(modified)
+	if e != nil || p.String() != modified || p.Charge != 2 {
+		t.Errorf("Parse() = %v, %v", p.String(), e)
+	}
+
+	if IsLegacyIonKey(modified) || !IsLegacyIonKey("PEPTIDE#2#879.3263") {
+		t.Errorf("IsLegacyIonKey() does not tell the key forms apart")
+	}
+
+	for k, v := range map[string]string{
+		"PEPTIDE#2#879.3263": "[+79.9663]?PEPTIDE/2",
+		"PEPTIDE#3#799.3600": "PEPTIDE/3",
+		"PEPTIDE#x#799.3600": "PEPTIDE#x#799.3600",
+		modified:             modified,
+	} {
+		if got := MigrateIonKey(k); got != v {
+			t.Errorf("MigrateIonKey(%s) = %v, want %v", k, got, v)
+		}
+	}
+}
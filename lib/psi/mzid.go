@@ -664,6 +664,8 @@ type SpectrumIdentificationResult struct {
 	SpectraDataRef             string                       `xml:"spectraData_ref,attr,omitempty"`
 	SpectrumID                 string                       `xml:"spectrumID,attr,omitempty"`
 	SpectrumIdentificationItem []SpectrumIdentificationItem `xml:"SpectrumIdentificationItem"`
+	CVParam                    []CVParam                    `xml:"cvParam"`
+	UserParam                  []UserParam                  `xml:"userParam"`
 }
 
 // SpectrumIdentificationItem is an identification of a single (poly)peptide,
@@ -0,0 +1,411 @@
+// Package qcm (Quality Control Metrics) reports the labeling efficiency and the digestion quality of the runs
+package qcm
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/mod"
+	"philosopher/lib/msg"
+	"philosopher/lib/rep"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+)
+
+// massTolerance is the Dalton tolerance used to recognize the modifications
+const massTolerance = 0.001
+
+// Carbamidomethyl and Oxidation mass shifts
+const (
+	Carbamidomethyl = 57.021464
+	Oxidation       = 15.994915
+)
+
+// Labels are the isobaric tag mass shifts
+var Labels = map[string]float64{
+	"TMT":     229.162932,
+	"TMTpro":  304.207146,
+	"TMT0":    224.152478,
+	"TMT2":    225.155833,
+	"iTRAQ4":  144.102063,
+	"iTRAQ8":  304.205360,
+	"mTRAQ":   140.094963,
+	"TMTpro0": 295.189592,
+}
+
+// Label is the isobaric tag searched as a variable modification
+type Label struct {
+	Name string
+	Mass float64
+}
+
+// Metrics are the quality control counts for a group of PSMs
+type Metrics struct {
+	Run                 string
+	PSMs                int
+	NTermini            int
+	LabeledNTermini     int
+	Lysines             int
+	LabeledLysines      int
+	FullyLabeled        int
+	MissedCleavages     [4]int
+	EnzymaticTermini    [3]int
+	OverAlkylated       int
+	Methionines         int
+	OxidizedMethionines int
+	MethioninePSMs      int
+	OxidizedPSMs        int
+}
+
+// Run computes the quality control metrics from the report evidence and writes the qc.tsv and qc.html files
+func Run() {
+
+	var evi = rep.New()
+	evi.RestoreGranular()
+
+	label, hasLabel := FindLabel(evi.Mods)
+	if hasLabel {
+		logrus.Info("Measuring the labeling efficiency for ", label.Name, " (", fmt.Sprintf("%.4f", label.Mass), ")")
+	} else {
+		logrus.Info("No isobaric tag was searched as a variable modification, skipping the labeling efficiency")
+	}
+
+	metrics := Assemble(evi.PSM, label, hasLabel)
+
+	Report(metrics, hasLabel)
+	HTMLReport(metrics, label, hasLabel)
+
+	return
+}
+
+// FindLabel looks for an isobaric tag searched as a variable modification on lysines or peptide N-termini
+func FindLabel(mods mod.Modifications) (Label, bool) {
+
+	var keys []string
+	for k := range mods.Index {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+
+		i := mods.Index[k]
+		if i.Variable != "Y" || (i.AminoAcid != "K" && i.AminoAcid != "N-term" && i.AminoAcid != "n-term") {
+			continue
+		}
+
+		// the closest tag is used since TMTpro and iTRAQ 8-plex are only 0.0018 Da apart
+		var best Label
+		for name, mass := range Labels {
+			if math.Abs(i.MassDiff-mass) <= massTolerance && (len(best.Name) == 0 || math.Abs(i.MassDiff-mass) < math.Abs(i.MassDiff-best.Mass)) {
+				best = Label{Name: name, Mass: mass}
+			}
+		}
+
+		if len(best.Name) > 0 {
+			return best, true
+		}
+	}
+
+	return Label{}, false
+}
+
+// Assemble counts the metrics for each run and for all runs combined, decoys are not included
+func Assemble(psms rep.PSMEvidenceList, label Label, hasLabel bool) []Metrics {
+
+	var runs = make(map[string]*Metrics)
+	var all = Metrics{Run: "All"}
+
+	for _, i := range psms {
+
+		if i.IsDecoy {
+			continue
+		}
+
+		run := strings.Split(i.Spectrum, ".")[0]
+		if _, ok := runs[run]; !ok {
+			runs[run] = &Metrics{Run: run}
+		}
+
+		runs[run].add(i, label, hasLabel)
+		all.add(i, label, hasLabel)
+	}
+
+	var names []string
+	for k := range runs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var list []Metrics
+	for _, i := range names {
+		list = append(list, *runs[i])
+	}
+
+	// the combined row is only useful when there is more than one run
+	if len(list) != 1 {
+		list = append(list, all)
+	}
+
+	return list
+}
+
+// add counts a single PSM
+func (q *Metrics) add(p rep.PSMEvidence, label Label, hasLabel bool) {
+
+	q.PSMs++
+
+	mc := p.NumberOfMissedCleavages
+	if mc > 3 {
+		mc = 3
+	}
+	if mc >= 0 {
+		q.MissedCleavages[mc]++
+	}
+
+	if p.NumberOfEnzymaticTermini >= 0 && p.NumberOfEnzymaticTermini <= 2 {
+		q.EnzymaticTermini[p.NumberOfEnzymaticTermini]++
+	}
+
+	var nLabeled, nBlocked, overAlkylated bool
+	var lysines = make(map[string]uint8)
+	var oxidized = make(map[string]uint8)
+
+	for _, i := range p.Modifications.Index {
+
+		if i.Type == "Observed" {
+			if math.Abs(i.MassDiff-Carbamidomethyl) <= 0.01 {
+				overAlkylated = true
+			}
+			continue
+		}
+
+		isNTerm := i.AminoAcid == "N-term" || i.AminoAcid == "n-term"
+
+		switch {
+		case hasLabel && isNTerm && math.Abs(i.MassDiff-label.Mass) <= massTolerance:
+			nLabeled = true
+		case i.AminoAcid != "C" && math.Abs(i.MassDiff-Carbamidomethyl) <= massTolerance:
+			// alkylated N-termini are over-alkylation, not blocked N-termini
+			overAlkylated = true
+		case isNTerm:
+			nBlocked = true
+		case hasLabel && i.AminoAcid == "K" && math.Abs(i.MassDiff-label.Mass) <= massTolerance:
+			lysines[i.Position] = 0
+		case i.AminoAcid == "M" && math.Abs(i.MassDiff-Oxidation) <= massTolerance:
+			oxidized[i.Position] = 0
+		}
+	}
+
+	if hasLabel {
+
+		// N-termini carrying other modifications, such as acetylation, cannot be labeled
+		if !nBlocked || nLabeled {
+			q.NTermini++
+			if nLabeled {
+				q.LabeledNTermini++
+			}
+		}
+
+		k := strings.Count(p.Peptide, "K")
+		q.Lysines += k
+		q.LabeledLysines += len(lysines)
+
+		if (nLabeled || nBlocked) && len(lysines) == k {
+			q.FullyLabeled++
+		}
+	}
+
+	if overAlkylated {
+		q.OverAlkylated++
+	}
+
+	if n := strings.Count(p.Peptide, "M"); n > 0 {
+		q.Methionines += n
+		q.OxidizedMethionines += len(oxidized)
+		q.MethioninePSMs++
+		if len(oxidized) > 0 {
+			q.OxidizedPSMs++
+		}
+	}
+
+	return
+}
+
+// ratio returns the fraction or zero when there are no observations
+func ratio(n, total int) float64 {
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(n) / float64(total)
+}
+
+// Columns are the report headers and the values for each group
+func (q Metrics) Columns(hasLabel bool) ([]string, []string) {
+
+	var headers []string
+	var values []string
+
+	add := func(h string, v string) {
+		headers = append(headers, h)
+		values = append(values, v)
+	}
+
+	add("Run", q.Run)
+	add("PSMs", fmt.Sprintf("%d", q.PSMs))
+
+	if hasLabel {
+		add("Labeled N-termini", fmt.Sprintf("%.4f", ratio(q.LabeledNTermini, q.NTermini)))
+		add("Labeled Lysines", fmt.Sprintf("%.4f", ratio(q.LabeledLysines, q.Lysines)))
+		add("Fully Labeled PSMs", fmt.Sprintf("%.4f", ratio(q.FullyLabeled, q.PSMs)))
+	}
+
+	for i, j := range q.MissedCleavages {
+		h := fmt.Sprintf("Missed Cleavages %d", i)
+		if i == len(q.MissedCleavages)-1 {
+			h += "+"
+		}
+		add(h, fmt.Sprintf("%.4f", ratio(j, q.PSMs)))
+	}
+
+	add("Fully Enzymatic", fmt.Sprintf("%.4f", ratio(q.EnzymaticTermini[2], q.PSMs)))
+	add("Semi Enzymatic", fmt.Sprintf("%.4f", ratio(q.EnzymaticTermini[1], q.PSMs)))
+	add("Non Enzymatic", fmt.Sprintf("%.4f", ratio(q.EnzymaticTermini[0], q.PSMs)))
+	add("Over-alkylated PSMs", fmt.Sprintf("%.4f", ratio(q.OverAlkylated, q.PSMs)))
+	add("Oxidized Methionines", fmt.Sprintf("%.4f", ratio(q.OxidizedMethionines, q.Methionines)))
+	add("Oxidized Methionine PSMs", fmt.Sprintf("%.4f", ratio(q.OxidizedPSMs, q.MethioninePSMs)))
+
+	return headers, values
+}
+
+// Report writes the metrics to the qc.tsv file
+func Report(metrics []Metrics, hasLabel bool) {
+
+	output := fmt.Sprintf("%s%sqc.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create the quality control report"), "fatal")
+	}
+	defer file.Close()
+
+	for i, j := range metrics {
+
+		headers, values := j.Columns(hasLabel)
+
+		if i == 0 {
+			_, e = io.WriteString(file, strings.Join(headers, "\t")+"\n")
+			if e != nil {
+				msg.WriteToFile(e, "fatal")
+			}
+		}
+
+		_, e = io.WriteString(file, strings.Join(values, "\t")+"\n")
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
+
+// HTMLReport writes the metrics to the qc.html file, one section for each group of metrics
+func HTMLReport(metrics []Metrics, label Label, hasLabel bool) {
+
+	output := fmt.Sprintf("%s%sqc.html", sys.MetaDir(), string(filepath.Separator))
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create the quality control report"), "fatal")
+	}
+	defer file.Close()
+
+	_, e = io.WriteString(file, htmlDocument(metrics, label, hasLabel))
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
+
+// htmlDocument writes the quality control tables as a standalone HTML page
+func htmlDocument(metrics []Metrics, label Label, hasLabel bool) string {
+
+	var b strings.Builder
+
+	b.WriteString("<!DOCTYPE html>\n")
+	b.WriteString("<html lang=\"en\">\n")
+	b.WriteString("<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString("<title>Quality control</title>\n")
+	b.WriteString("<style>table {border-collapse: collapse;} th, td {border: 1px solid #aaa; padding: 4px 8px; text-align: right;}</style>\n")
+	b.WriteString("</head>\n")
+	b.WriteString("<body>\n")
+
+	if hasLabel {
+		writeSection(&b, fmt.Sprintf("Labeling efficiency (%s, %.4f)", label.Name, label.Mass), metrics, hasLabel,
+			"Labeled N-termini", "Labeled Lysines", "Fully Labeled PSMs")
+	}
+
+	writeSection(&b, "Digestion", metrics, hasLabel,
+		"Missed Cleavages 0", "Missed Cleavages 1", "Missed Cleavages 2", "Missed Cleavages 3+", "Fully Enzymatic", "Semi Enzymatic", "Non Enzymatic")
+	writeSection(&b, "Modifications", metrics, hasLabel,
+		"Over-alkylated PSMs", "Oxidized Methionines", "Oxidized Methionine PSMs")
+
+	b.WriteString("</body>\n")
+	b.WriteString("</html>\n")
+
+	return b.String()
+}
+
+// writeSection adds a table with the run names, the number of PSMs and the given columns
+func writeSection(b *strings.Builder, title string, metrics []Metrics, hasLabel bool, columns ...string) {
+
+	b.WriteString(fmt.Sprintf("<section>\n<h2>%s</h2>\n<table>\n", html.EscapeString(title)))
+
+	for i, j := range metrics {
+
+		headers, values := j.Columns(hasLabel)
+
+		var cols = []int{0, 1}
+		for k, h := range headers {
+			for _, c := range columns {
+				if h == c {
+					cols = append(cols, k)
+				}
+			}
+		}
+
+		if i == 0 {
+			b.WriteString("<tr>")
+			for _, k := range cols {
+				b.WriteString("<th>" + html.EscapeString(headers[k]) + "</th>")
+			}
+			b.WriteString("</tr>\n")
+		}
+
+		b.WriteString("<tr>")
+		for _, k := range cols {
+			b.WriteString("<td>" + html.EscapeString(values[k]) + "</td>")
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("</table>\n</section>\n")
+}
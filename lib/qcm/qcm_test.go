@@ -0,0 +1,151 @@
+package qcm
+
+import (
+	"strings"
+	"testing"
+
+	"philosopher/lib/mod"
+	"philosopher/lib/rep"
+)
+
+func newPSM(spectrum, peptide string, mc, ntt int, mods ...mod.Modification) rep.PSMEvidence {
+
+	var p rep.PSMEvidence
+
+	p.Spectrum = spectrum
+	p.Peptide = peptide
+	p.NumberOfMissedCleavages = mc
+	p.NumberOfEnzymaticTermini = ntt
+	p.Modifications.Index = make(map[string]mod.Modification)
+
+	for _, i := range mods {
+		p.Modifications.Index[i.AminoAcid+"#"+i.Position] = i
+	}
+
+	return p
+}
+
+func TestFindLabel(t *testing.T) {
+
+	var mods mod.Modifications
+	mods.Index = map[string]mod.Modification{
+		"K#357.2579":      {AminoAcid: "K", MassDiff: 229.162932, Variable: "N"},
+		"M#147.0354":      {AminoAcid: "M", MassDiff: 15.9949, Variable: "Y"},
+		"N-term#305.2146": {AminoAcid: "n-term", MassDiff: 304.2071, Variable: "Y"},
+	}
+
+	label, ok := FindLabel(mods)
+	if !ok || label.Name != "TMTpro" {
+		t.Errorf("FindLabel() = %v, %v", label, ok)
+	}
+
+	delete(mods.Index, "N-term#305.2146")
+	if label, ok := FindLabel(mods); ok {
+		t.Errorf("FindLabel() should ignore fixed labels, got %v", label)
+	}
+}
+
+func TestAssemble(t *testing.T) {
+
+	label := Label{Name: "TMT", Mass: Labels["TMT"]}
+
+	nterm := mod.Modification{AminoAcid: "N-term", Type: "Assigned", MassDiff: label.Mass}
+	acetyl := mod.Modification{AminoAcid: "N-term", Type: "Assigned", MassDiff: 42.010565}
+	lysine := mod.Modification{AminoAcid: "K", Position: "7", Type: "Assigned", MassDiff: label.Mass}
+	oxidation := mod.Modification{AminoAcid: "M", Position: "2", Type: "Assigned", MassDiff: Oxidation}
+	alkylation := mod.Modification{AminoAcid: "K", Position: "3", Type: "Assigned", MassDiff: Carbamidomethyl}
+
+	psms := rep.PSMEvidenceList{
+		newPSM("run1.100.100.2", "PEPTIDK", 0, 2, nterm, lysine),
+		newPSM("run1.101.101.2", "PMPKIDK", 1, 2, nterm, oxidation, alkylation),
+		newPSM("run2.100.100.2", "AMPTIDE", 0, 1, acetyl),
+		newPSM("run2.101.101.2", "PEPTIDR", 4, 0),
+	}
+
+	decoy := newPSM("run2.102.102.2", "KEDITPEP", 0, 2)
+	decoy.IsDecoy = true
+	psms = append(psms, decoy)
+
+	list := Assemble(psms, label, true)
+	if len(list) != 3 || list[0].Run != "run1" || list[2].Run != "All" {
+		t.Fatalf("Assemble() = %v", list)
+	}
+
+	all := list[2]
+
+	if all.PSMs != 4 {
+		t.Errorf("PSMs = %d, want 4", all.PSMs)
+	}
+
+	// the acetylated N-terminus is not counted
+	if all.NTermini != 3 || all.LabeledNTermini != 2 {
+		t.Errorf("N-termini = %d/%d, want 2/3", all.LabeledNTermini, all.NTermini)
+	}
+
+	if all.Lysines != 3 || all.LabeledLysines != 1 {
+		t.Errorf("Lysines = %d/%d, want 1/3", all.LabeledLysines, all.Lysines)
+	}
+
+	if all.FullyLabeled != 2 {
+		t.Errorf("FullyLabeled = %d, want 2", all.FullyLabeled)
+	}
+
+	if all.MissedCleavages != [4]int{2, 1, 0, 1} || all.EnzymaticTermini != [3]int{1, 1, 2} {
+		t.Errorf("Digestion = %v %v", all.MissedCleavages, all.EnzymaticTermini)
+	}
+
+	if all.OverAlkylated != 1 || all.Methionines != 2 || all.OxidizedMethionines != 1 || all.OxidizedPSMs != 1 {
+		t.Errorf("Modifications = %+v", all)
+	}
+
+	headers, values := all.Columns(true)
+	if len(headers) != len(values) || headers[2] != "Labeled N-termini" || values[2] != "0.6667" {
+		t.Errorf("Columns() = %v %v", headers, values)
+	}
+
+	if headers, _ := all.Columns(false); headers[2] != "Missed Cleavages 0" {
+		t.Errorf("Columns() without labels = %v", headers)
+	}
+}
+
+func TestAssembleAlkylatedNTerminus(t *testing.T) {
+
+	label := Label{Name: "TMT", Mass: Labels["TMT"]}
+	alkylation := mod.Modification{AminoAcid: "N-term", Type: "Assigned", MassDiff: Carbamidomethyl}
+
+	list := Assemble(rep.PSMEvidenceList{newPSM("run1.100.100.2", "PEPTIDR", 0, 2, alkylation)}, label, true)
+	all := list[len(list)-1]
+
+	// the alkylated N-terminus is over-alkylation and remains an unlabeled N-terminus
+	if all.OverAlkylated != 1 || all.NTermini != 1 || all.LabeledNTermini != 0 {
+		t.Errorf("OverAlkylated = %d, N-termini = %d/%d", all.OverAlkylated, all.LabeledNTermini, all.NTermini)
+	}
+}
+
+func TestHTMLDocument(t *testing.T) {
+
+	label := Label{Name: "TMT", Mass: Labels["TMT"]}
+
+	psms := rep.PSMEvidenceList{
+		newPSM("R&D.100.100.2", "PEPTIDK", 0, 2),
+	}
+
+	doc := htmlDocument(Assemble(psms, label, false), label, false)
+
+	if !strings.HasPrefix(doc, "<!DOCTYPE html>\n<html") || !strings.HasSuffix(doc, "</body>\n</html>\n") {
+		t.Errorf("htmlDocument() is not a complete document: %s", doc)
+	}
+
+	if !strings.Contains(doc, "<title>") || !strings.Contains(doc, "<meta charset=\"utf-8\">") {
+		t.Errorf("htmlDocument() has no title or charset: %s", doc)
+	}
+
+	// the run names are escaped
+	if strings.Contains(doc, "R&D<") || !strings.Contains(doc, "R&amp;D") {
+		t.Errorf("htmlDocument() = %s", doc)
+	}
+
+	if strings.Contains(doc, "Labeling efficiency") {
+		t.Errorf("htmlDocument() reports the labeling without a label")
+	}
+}
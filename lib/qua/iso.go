@@ -480,7 +480,7 @@ func mapLabeledSpectra(labels map[string]iso.Labels, purity float64, evi []rep.P
 	return evi
 }
 
-// the assignment of usage is only done for general PSM, not for phosphoPSMs
+// the assignment of usage is only done for general PSM, not for the modified PSMs
 func assignUsage(evi rep.Evidence, spectrumMap map[string]iso.Labels) rep.Evidence {
 
 	for i := range evi.PSM {
@@ -549,7 +549,7 @@ func correctUnlabelledSpectra(evi rep.Evidence) rep.Evidence {
 }
 
 // rollUpPeptides gathers PSM info and filters them before summing the instensities to the peptide level
-func rollUpPeptides(evi rep.Evidence, spectrumMap map[string]iso.Labels, phosphoSpectrumMap map[string]iso.Labels) rep.Evidence {
+func rollUpPeptides(evi rep.Evidence, spectrumMap map[string]iso.Labels, modSpectrumMap map[string]map[string]iso.Labels) rep.Evidence {
 
 	for j := range evi.Peptides {
 		for k := range evi.Peptides[j].Spectra {
@@ -638,88 +638,11 @@ func rollUpPeptides(evi rep.Evidence, spectrumMap map[string]iso.Labels, phospho
 				evi.Peptides[j].Labels.Channel16.Intensity += i.Channel16.Intensity
 			}
 
-			i, ok = phosphoSpectrumMap[k]
-			if ok {
-
-				evi.Peptides[j].PhosphoLabels.Channel1.Name = i.Channel1.Name
-				evi.Peptides[j].PhosphoLabels.Channel1.CustomName = i.Channel1.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel1.Mz = i.Channel1.Mz
-				evi.Peptides[j].PhosphoLabels.Channel1.Intensity += i.Channel1.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel2.Name = i.Channel2.Name
-				evi.Peptides[j].PhosphoLabels.Channel2.CustomName = i.Channel2.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel2.Mz = i.Channel2.Mz
-				evi.Peptides[j].PhosphoLabels.Channel2.Intensity += i.Channel2.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel3.Name = i.Channel3.Name
-				evi.Peptides[j].PhosphoLabels.Channel3.CustomName = i.Channel3.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel3.Mz = i.Channel3.Mz
-				evi.Peptides[j].PhosphoLabels.Channel3.Intensity += i.Channel3.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel4.Name = i.Channel4.Name
-				evi.Peptides[j].PhosphoLabels.Channel4.CustomName = i.Channel4.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel4.Mz = i.Channel4.Mz
-				evi.Peptides[j].PhosphoLabels.Channel4.Intensity += i.Channel4.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel5.Name = i.Channel5.Name
-				evi.Peptides[j].PhosphoLabels.Channel5.CustomName = i.Channel5.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel5.Mz = i.Channel5.Mz
-				evi.Peptides[j].PhosphoLabels.Channel5.Intensity += i.Channel5.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel6.Name = i.Channel6.Name
-				evi.Peptides[j].PhosphoLabels.Channel6.CustomName = i.Channel6.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel6.Mz = i.Channel6.Mz
-				evi.Peptides[j].PhosphoLabels.Channel6.Intensity += i.Channel6.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel7.Name = i.Channel7.Name
-				evi.Peptides[j].PhosphoLabels.Channel7.CustomName = i.Channel7.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel7.Mz = i.Channel7.Mz
-				evi.Peptides[j].PhosphoLabels.Channel7.Intensity += i.Channel7.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel8.Name = i.Channel8.Name
-				evi.Peptides[j].PhosphoLabels.Channel8.CustomName = i.Channel8.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel8.Mz = i.Channel8.Mz
-				evi.Peptides[j].PhosphoLabels.Channel8.Intensity += i.Channel8.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel9.Name = i.Channel9.Name
-				evi.Peptides[j].PhosphoLabels.Channel9.CustomName = i.Channel9.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel9.Mz = i.Channel9.Mz
-				evi.Peptides[j].PhosphoLabels.Channel9.Intensity += i.Channel9.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel10.Name = i.Channel10.Name
-				evi.Peptides[j].PhosphoLabels.Channel10.CustomName = i.Channel10.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel10.Mz = i.Channel10.Mz
-				evi.Peptides[j].PhosphoLabels.Channel10.Intensity += i.Channel10.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel11.Name = i.Channel11.Name
-				evi.Peptides[j].PhosphoLabels.Channel11.CustomName = i.Channel11.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel11.Mz = i.Channel11.Mz
-				evi.Peptides[j].PhosphoLabels.Channel11.Intensity += i.Channel11.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel12.Name = i.Channel12.Name
-				evi.Peptides[j].PhosphoLabels.Channel12.CustomName = i.Channel12.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel12.Mz = i.Channel12.Mz
-				evi.Peptides[j].PhosphoLabels.Channel12.Intensity += i.Channel12.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel13.Name = i.Channel13.Name
-				evi.Peptides[j].PhosphoLabels.Channel13.CustomName = i.Channel13.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel13.Mz = i.Channel13.Mz
-				evi.Peptides[j].PhosphoLabels.Channel13.Intensity += i.Channel13.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel14.Name = i.Channel14.Name
-				evi.Peptides[j].PhosphoLabels.Channel14.CustomName = i.Channel14.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel14.Mz = i.Channel14.Mz
-				evi.Peptides[j].PhosphoLabels.Channel14.Intensity += i.Channel14.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel15.Name = i.Channel15.Name
-				evi.Peptides[j].PhosphoLabels.Channel15.CustomName = i.Channel15.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel15.Mz = i.Channel15.Mz
-				evi.Peptides[j].PhosphoLabels.Channel15.Intensity += i.Channel15.Intensity
-
-				evi.Peptides[j].PhosphoLabels.Channel16.Name = i.Channel16.Name
-				evi.Peptides[j].PhosphoLabels.Channel16.CustomName = i.Channel16.CustomName
-				evi.Peptides[j].PhosphoLabels.Channel16.Mz = i.Channel16.Mz
-				evi.Peptides[j].PhosphoLabels.Channel16.Intensity += i.Channel16.Intensity
+			for mod, spectra := range modSpectrumMap {
+				i, ok = spectra[k]
+				if ok {
+					evi.Peptides[j].ModLabels = addModLabels(evi.Peptides[j].ModLabels, mod, i)
+				}
 			}
 
 		}
@@ -728,8 +651,23 @@ func rollUpPeptides(evi rep.Evidence, spectrumMap map[string]iso.Labels, phospho
 	return evi
 }
 
+// rollUpModifiedPeptides sums the labels from the spectra supporting each peptide modified form
+func rollUpModifiedPeptides(evi rep.Evidence, spectrumMap map[string]iso.Labels) rep.Evidence {
+
+	for j := range evi.ModifiedPeptides {
+		for k := range evi.ModifiedPeptides[j].Spectra {
+			i, ok := spectrumMap[k]
+			if ok {
+				evi.ModifiedPeptides[j].Labels.Add(i)
+			}
+		}
+	}
+
+	return evi
+}
+
 // rollUpPeptideIons gathers PSM info and filters them before summing the instensities to the peptide ION level
-func rollUpPeptideIons(evi rep.Evidence, spectrumMap map[string]iso.Labels, phosphoSpectrumMap map[string]iso.Labels) rep.Evidence {
+func rollUpPeptideIons(evi rep.Evidence, spectrumMap map[string]iso.Labels, modSpectrumMap map[string]map[string]iso.Labels) rep.Evidence {
 
 	for j := range evi.Ions {
 		for k := range evi.Ions[j].Spectra {
@@ -818,88 +756,11 @@ func rollUpPeptideIons(evi rep.Evidence, spectrumMap map[string]iso.Labels, phos
 				evi.Ions[j].Labels.Channel16.Intensity += i.Channel16.Intensity
 			}
 
-			i, ok = phosphoSpectrumMap[k]
-			if ok {
-
-				evi.Ions[j].PhosphoLabels.Channel1.Name = i.Channel1.Name
-				evi.Ions[j].Labels.Channel1.CustomName = i.Channel1.CustomName
-				evi.Ions[j].PhosphoLabels.Channel1.Mz = i.Channel1.Mz
-				evi.Ions[j].PhosphoLabels.Channel1.Intensity += i.Channel1.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel2.Name = i.Channel2.Name
-				evi.Ions[j].Labels.Channel2.CustomName = i.Channel2.CustomName
-				evi.Ions[j].PhosphoLabels.Channel2.Mz = i.Channel2.Mz
-				evi.Ions[j].PhosphoLabels.Channel2.Intensity += i.Channel2.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel3.Name = i.Channel3.Name
-				evi.Ions[j].Labels.Channel3.CustomName = i.Channel3.CustomName
-				evi.Ions[j].PhosphoLabels.Channel3.Mz = i.Channel3.Mz
-				evi.Ions[j].PhosphoLabels.Channel3.Intensity += i.Channel3.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel4.Name = i.Channel4.Name
-				evi.Ions[j].Labels.Channel4.CustomName = i.Channel4.CustomName
-				evi.Ions[j].PhosphoLabels.Channel4.Mz = i.Channel4.Mz
-				evi.Ions[j].PhosphoLabels.Channel4.Intensity += i.Channel4.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel5.Name = i.Channel5.Name
-				evi.Ions[j].Labels.Channel5.CustomName = i.Channel5.CustomName
-				evi.Ions[j].PhosphoLabels.Channel5.Mz = i.Channel5.Mz
-				evi.Ions[j].PhosphoLabels.Channel5.Intensity += i.Channel5.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel6.Name = i.Channel6.Name
-				evi.Ions[j].Labels.Channel6.CustomName = i.Channel6.CustomName
-				evi.Ions[j].PhosphoLabels.Channel6.Mz = i.Channel6.Mz
-				evi.Ions[j].PhosphoLabels.Channel6.Intensity += i.Channel6.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel7.Name = i.Channel7.Name
-				evi.Ions[j].Labels.Channel7.CustomName = i.Channel7.CustomName
-				evi.Ions[j].PhosphoLabels.Channel7.Mz = i.Channel7.Mz
-				evi.Ions[j].PhosphoLabels.Channel7.Intensity += i.Channel7.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel8.Name = i.Channel8.Name
-				evi.Ions[j].Labels.Channel8.CustomName = i.Channel8.CustomName
-				evi.Ions[j].PhosphoLabels.Channel8.Mz = i.Channel8.Mz
-				evi.Ions[j].PhosphoLabels.Channel8.Intensity += i.Channel8.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel9.Name = i.Channel9.Name
-				evi.Ions[j].Labels.Channel9.CustomName = i.Channel9.CustomName
-				evi.Ions[j].PhosphoLabels.Channel9.Mz = i.Channel9.Mz
-				evi.Ions[j].PhosphoLabels.Channel9.Intensity += i.Channel9.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel10.Name = i.Channel10.Name
-				evi.Ions[j].Labels.Channel10.CustomName = i.Channel10.CustomName
-				evi.Ions[j].PhosphoLabels.Channel10.Mz = i.Channel10.Mz
-				evi.Ions[j].PhosphoLabels.Channel10.Intensity += i.Channel10.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel11.Name = i.Channel11.Name
-				evi.Ions[j].Labels.Channel11.CustomName = i.Channel11.CustomName
-				evi.Ions[j].PhosphoLabels.Channel11.Mz = i.Channel11.Mz
-				evi.Ions[j].PhosphoLabels.Channel11.Intensity += i.Channel11.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel12.Name = i.Channel12.Name
-				evi.Ions[j].Labels.Channel12.CustomName = i.Channel12.CustomName
-				evi.Ions[j].PhosphoLabels.Channel12.Mz = i.Channel12.Mz
-				evi.Ions[j].PhosphoLabels.Channel12.Intensity += i.Channel12.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel13.Name = i.Channel13.Name
-				evi.Ions[j].Labels.Channel13.CustomName = i.Channel13.CustomName
-				evi.Ions[j].PhosphoLabels.Channel13.Mz = i.Channel13.Mz
-				evi.Ions[j].PhosphoLabels.Channel13.Intensity += i.Channel13.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel14.Name = i.Channel14.Name
-				evi.Ions[j].Labels.Channel14.CustomName = i.Channel14.CustomName
-				evi.Ions[j].PhosphoLabels.Channel14.Mz = i.Channel14.Mz
-				evi.Ions[j].PhosphoLabels.Channel14.Intensity += i.Channel14.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel15.Name = i.Channel15.Name
-				evi.Ions[j].Labels.Channel15.CustomName = i.Channel15.CustomName
-				evi.Ions[j].PhosphoLabels.Channel15.Mz = i.Channel15.Mz
-				evi.Ions[j].PhosphoLabels.Channel15.Intensity += i.Channel15.Intensity
-
-				evi.Ions[j].PhosphoLabels.Channel16.Name = i.Channel16.Name
-				evi.Ions[j].Labels.Channel16.CustomName = i.Channel16.CustomName
-				evi.Ions[j].PhosphoLabels.Channel16.Mz = i.Channel16.Mz
-				evi.Ions[j].PhosphoLabels.Channel16.Intensity += i.Channel16.Intensity
+			for mod, spectra := range modSpectrumMap {
+				i, ok = spectra[k]
+				if ok {
+					evi.Ions[j].ModLabels = addModLabels(evi.Ions[j].ModLabels, mod, i)
+				}
 			}
 
 		}
@@ -909,7 +770,7 @@ func rollUpPeptideIons(evi rep.Evidence, spectrumMap map[string]iso.Labels, phos
 }
 
 // rollUpProteins gathers PSM info and filters them before summing the instensities to the peptide ION level
-func rollUpProteins(evi rep.Evidence, spectrumMap map[string]iso.Labels, phosphoSpectrumMap map[string]iso.Labels) rep.Evidence {
+func rollUpProteins(evi rep.Evidence, spectrumMap map[string]iso.Labels, modSpectrumMap map[string]map[string]iso.Labels) rep.Evidence {
 
 	for j := range evi.Proteins {
 		for _, k := range evi.Proteins[j].TotalPeptideIons {
@@ -1163,251 +1024,19 @@ func rollUpProteins(evi rep.Evidence, spectrumMap map[string]iso.Labels, phospho
 					}
 				}
 
-				i, ok = phosphoSpectrumMap[l]
-				if ok {
-					evi.Proteins[j].PhosphoTotalLabels.Channel1.Name = i.Channel1.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel1.CustomName = i.Channel1.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel1.Mz = i.Channel1.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel1.Intensity += i.Channel1.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel2.Name = i.Channel2.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel2.CustomName = i.Channel2.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel2.Mz = i.Channel2.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel2.Intensity += i.Channel2.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel3.Name = i.Channel3.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel3.CustomName = i.Channel3.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel3.Mz = i.Channel3.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel3.Intensity += i.Channel3.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel4.Name = i.Channel4.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel4.CustomName = i.Channel4.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel4.Mz = i.Channel4.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel4.Intensity += i.Channel4.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel5.Name = i.Channel5.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel5.CustomName = i.Channel5.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel5.Mz = i.Channel5.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel5.Intensity += i.Channel5.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel6.Name = i.Channel6.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel6.CustomName = i.Channel6.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel6.Mz = i.Channel6.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel6.Intensity += i.Channel6.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel7.Name = i.Channel7.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel7.CustomName = i.Channel7.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel7.Mz = i.Channel7.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel7.Intensity += i.Channel7.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel8.Name = i.Channel8.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel8.CustomName = i.Channel8.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel8.Mz = i.Channel8.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel8.Intensity += i.Channel8.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel9.Name = i.Channel9.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel9.CustomName = i.Channel9.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel9.Mz = i.Channel9.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel9.Intensity += i.Channel9.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel10.Name = i.Channel10.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel10.CustomName = i.Channel10.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel10.Mz = i.Channel10.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel10.Intensity += i.Channel10.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel11.Name = i.Channel11.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel11.CustomName = i.Channel11.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel11.Mz = i.Channel11.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel11.Intensity += i.Channel11.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel12.Name = i.Channel12.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel12.CustomName = i.Channel12.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel12.Mz = i.Channel12.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel12.Intensity += i.Channel12.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel13.Name = i.Channel13.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel13.CustomName = i.Channel13.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel13.Mz = i.Channel13.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel13.Intensity += i.Channel13.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel14.Name = i.Channel14.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel14.CustomName = i.Channel14.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel14.Mz = i.Channel14.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel14.Intensity += i.Channel14.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel15.Name = i.Channel15.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel15.CustomName = i.Channel15.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel15.Mz = i.Channel15.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel15.Intensity += i.Channel15.Intensity
-
-					evi.Proteins[j].PhosphoTotalLabels.Channel16.Name = i.Channel16.Name
-					evi.Proteins[j].PhosphoTotalLabels.Channel16.CustomName = i.Channel16.CustomName
-					evi.Proteins[j].PhosphoTotalLabels.Channel16.Mz = i.Channel16.Mz
-					evi.Proteins[j].PhosphoTotalLabels.Channel16.Intensity += i.Channel16.Intensity
+				for mod, spectra := range modSpectrumMap {
+
+					i, ok = spectra[l]
+					if !ok {
+						continue
+					}
 
-					//if k.IsNondegenerateEvidence {
 					if k.IsUnique {
-						evi.Proteins[j].PhosphoUniqueLabels.Channel1.Name = i.Channel1.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel1.CustomName = i.Channel1.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel1.Mz = i.Channel1.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel1.Intensity += i.Channel1.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel2.Name = i.Channel2.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel2.CustomName = i.Channel2.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel2.Mz = i.Channel2.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel2.Intensity += i.Channel2.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel3.Name = i.Channel3.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel3.CustomName = i.Channel3.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel3.Mz = i.Channel3.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel3.Intensity += i.Channel3.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel4.Name = i.Channel4.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel4.CustomName = i.Channel4.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel4.Mz = i.Channel4.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel4.Intensity += i.Channel4.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel5.Name = i.Channel5.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel5.CustomName = i.Channel5.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel5.Mz = i.Channel5.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel5.Intensity += i.Channel5.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel6.Name = i.Channel6.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel6.CustomName = i.Channel6.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel6.Mz = i.Channel6.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel6.Intensity += i.Channel6.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel7.Name = i.Channel7.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel7.CustomName = i.Channel7.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel7.Mz = i.Channel7.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel7.Intensity += i.Channel7.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel8.Name = i.Channel8.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel8.CustomName = i.Channel8.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel8.Mz = i.Channel8.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel8.Intensity += i.Channel8.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel9.Name = i.Channel9.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel9.CustomName = i.Channel9.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel9.Mz = i.Channel9.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel9.Intensity += i.Channel9.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel10.Name = i.Channel10.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel10.CustomName = i.Channel10.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel10.Mz = i.Channel10.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel10.Intensity += i.Channel10.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel11.Name = i.Channel11.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel11.CustomName = i.Channel11.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel11.Mz = i.Channel11.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel11.Intensity += i.Channel11.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel12.Name = i.Channel12.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel12.CustomName = i.Channel12.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel12.Mz = i.Channel12.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel12.Intensity += i.Channel12.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel13.Name = i.Channel13.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel13.CustomName = i.Channel13.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel13.Mz = i.Channel13.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel13.Intensity += i.Channel13.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel14.Name = i.Channel14.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel14.CustomName = i.Channel14.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel14.Mz = i.Channel14.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel14.Intensity += i.Channel14.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel15.Name = i.Channel15.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel15.CustomName = i.Channel15.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel15.Mz = i.Channel15.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel15.Intensity += i.Channel15.Intensity
-
-						evi.Proteins[j].PhosphoUniqueLabels.Channel16.Name = i.Channel16.Name
-						evi.Proteins[j].PhosphoUniqueLabels.Channel16.CustomName = i.Channel16.CustomName
-						evi.Proteins[j].PhosphoUniqueLabels.Channel16.Mz = i.Channel16.Mz
-						evi.Proteins[j].PhosphoUniqueLabels.Channel16.Intensity += i.Channel16.Intensity
+						evi.Proteins[j].ModUniqueLabels = addModLabels(evi.Proteins[j].ModUniqueLabels, mod, i)
 					}
 
 					if k.IsURazor {
-						evi.Proteins[j].PhosphoURazorLabels.Channel1.Name = i.Channel1.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel1.CustomName = i.Channel1.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel1.Mz = i.Channel1.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel1.Intensity += i.Channel1.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel2.Name = i.Channel2.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel2.CustomName = i.Channel2.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel2.Mz = i.Channel2.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel2.Intensity += i.Channel2.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel3.Name = i.Channel3.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel3.CustomName = i.Channel3.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel3.Mz = i.Channel3.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel3.Intensity += i.Channel3.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel4.Name = i.Channel4.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel4.CustomName = i.Channel4.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel4.Mz = i.Channel4.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel4.Intensity += i.Channel4.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel5.Name = i.Channel5.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel5.CustomName = i.Channel5.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel5.Mz = i.Channel5.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel5.Intensity += i.Channel5.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel6.Name = i.Channel6.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel6.CustomName = i.Channel6.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel6.Mz = i.Channel6.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel6.Intensity += i.Channel6.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel7.Name = i.Channel7.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel7.CustomName = i.Channel7.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel7.Mz = i.Channel7.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel7.Intensity += i.Channel7.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel8.Name = i.Channel8.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel8.CustomName = i.Channel8.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel8.Mz = i.Channel8.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel8.Intensity += i.Channel8.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel9.Name = i.Channel9.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel9.CustomName = i.Channel9.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel9.Mz = i.Channel9.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel9.Intensity += i.Channel9.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel10.Name = i.Channel10.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel10.CustomName = i.Channel10.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel10.Mz = i.Channel10.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel10.Intensity += i.Channel10.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel11.Name = i.Channel11.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel11.CustomName = i.Channel11.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel11.Mz = i.Channel11.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel11.Intensity += i.Channel11.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel12.Name = i.Channel12.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel12.CustomName = i.Channel12.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel12.Mz = i.Channel12.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel12.Intensity += i.Channel12.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel13.Name = i.Channel13.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel13.CustomName = i.Channel13.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel13.Mz = i.Channel13.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel13.Intensity += i.Channel13.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel14.Name = i.Channel14.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel14.CustomName = i.Channel14.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel14.Mz = i.Channel14.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel14.Intensity += i.Channel14.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel15.Name = i.Channel15.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel15.CustomName = i.Channel15.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel15.Mz = i.Channel15.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel15.Intensity += i.Channel15.Intensity
-
-						evi.Proteins[j].PhosphoURazorLabels.Channel16.Name = i.Channel16.Name
-						evi.Proteins[j].PhosphoURazorLabels.Channel16.CustomName = i.Channel16.CustomName
-						evi.Proteins[j].PhosphoURazorLabels.Channel16.Mz = i.Channel16.Mz
-						evi.Proteins[j].PhosphoURazorLabels.Channel16.Intensity += i.Channel16.Intensity
+						evi.Proteins[j].ModURazorLabels = addModLabels(evi.Proteins[j].ModURazorLabels, mod, i)
 					}
 				}
 
@@ -1509,3 +1138,17 @@ func calculateRatios(evi rep.Evidence) rep.Evidence {
 
 	return evi
 }
+
+// addModLabels adds the PSM labels to the roll-up of the given modification
+func addModLabels(labels map[string]iso.Labels, mod string, l iso.Labels) map[string]iso.Labels {
+
+	if labels == nil {
+		labels = make(map[string]iso.Labels)
+	}
+
+	sum := labels[mod]
+	sum.Add(l)
+	labels[mod] = sum
+
+	return labels
+}
@@ -179,6 +179,7 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 	}
 
 	var peptideIntMap = make(map[string]float64)
+	var modPeptideIntMap = make(map[string]float64)
 	var ionIntMap = make(map[string]float64)
 
 	for _, i := range e.PSM {
@@ -191,6 +192,9 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 			peptideIntMap[i.Peptide] += i.Intensity
 		}
 
+		// modified peptide intensity : sum of all
+		modPeptideIntMap[i.ModifiedForm] += i.Intensity
+
 		// ion intensity : most intense ion
 		ionV, ok := ionIntMap[i.IonForm]
 		if ok {
@@ -210,6 +214,13 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 		}
 	}
 
+	for i := range e.ModifiedPeptides {
+		v, ok := modPeptideIntMap[e.ModifiedPeptides[i].ModifiedSequence]
+		if ok {
+			e.ModifiedPeptides[i].Intensity = v
+		}
+	}
+
 	for i := range e.Ions {
 		v, ok := ionIntMap[e.Ions[i].IonForm]
 		if ok {
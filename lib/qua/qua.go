@@ -48,7 +48,7 @@ func RunLabelFreeQuantification(p met.Quantify) {
 }
 
 // RunIsobaricLabelQuantification is the top function for label quantification
-func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
+func RunIsobaricLabelQuantification(p met.Quantify, mods bool, ptms []string) met.Quantify {
 
 	var psmMap = make(map[string]rep.PSMEvidence)
 	var sourceMap = make(map[string][]rep.PSMEvidence)
@@ -145,7 +145,7 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 
 	// classification and filtering based on quality filters
 	logrus.Info("Filtering spectra for label quantification")
-	spectrumMap, phosphoSpectrumMap := classification(evi, mods, p.BestPSM, p.RemoveLow, p.Purity, p.MinProb)
+	spectrumMap, modSpectrumMap := classification(evi, mods, ptms, p.BestPSM, p.RemoveLow, p.Purity, p.MinProb)
 
 	// assignment happens only for general PSMs
 	evi = assignUsage(evi, spectrumMap)
@@ -153,11 +153,13 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 	// forces psms with no label to have 0 intensities
 	evi = correctUnlabelledSpectra(evi)
 
-	evi = rollUpPeptides(evi, spectrumMap, phosphoSpectrumMap)
+	evi = rollUpPeptides(evi, spectrumMap, modSpectrumMap)
 
-	evi = rollUpPeptideIons(evi, spectrumMap, phosphoSpectrumMap)
+	evi = rollUpModifiedPeptides(evi, spectrumMap)
 
-	evi = rollUpProteins(evi, spectrumMap, phosphoSpectrumMap)
+	evi = rollUpPeptideIons(evi, spectrumMap, modSpectrumMap)
+
+	evi = rollUpProteins(evi, spectrumMap, modSpectrumMap)
 
 	// normalize to the total protein levels
 	logrus.Info("Calculating normalized protein levels")
@@ -174,6 +176,9 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 	// create EV Peptides
 	rep.SerializeEVPeptides(&evi)
 
+	// create EV Modified Peptides
+	rep.SerializeEVModifiedPeptides(&evi)
+
 	// create EV Ion
 	rep.SerializeEVProteins(&evi)
 
@@ -224,6 +229,7 @@ func cleanPreviousData(evi rep.Evidence, brand, plex string) rep.Evidence {
 		} else if brand == "itraq" {
 			evi.Ions[i].Labels = trq.New(plex)
 		}
+		evi.Ions[i].ModLabels = nil
 	}
 
 	for i := range evi.Proteins {
@@ -236,6 +242,20 @@ func cleanPreviousData(evi rep.Evidence, brand, plex string) rep.Evidence {
 			evi.Proteins[i].UniqueLabels = trq.New(plex)
 			evi.Proteins[i].URazorLabels = trq.New(plex)
 		}
+		evi.Proteins[i].ModUniqueLabels = nil
+		evi.Proteins[i].ModURazorLabels = nil
+	}
+
+	for i := range evi.Peptides {
+		evi.Peptides[i].ModLabels = nil
+	}
+
+	for i := range evi.ModifiedPeptides {
+		if brand == "tmt" {
+			evi.ModifiedPeptides[i].Labels = tmt.New(plex)
+		} else if brand == "itraq" {
+			evi.ModifiedPeptides[i].Labels = trq.New(plex)
+		}
 	}
 
 	return evi
@@ -428,10 +448,12 @@ func assignLabelNames(labels map[string]iso.Labels, labelNames map[string]string
 	return labels
 }
 
-func classification(evi rep.Evidence, mods, best bool, remove, purity, probability float64) (map[string]iso.Labels, map[string]iso.Labels) {
+// classification selects the spectra used for quantification, the localized PSMs are also collected
+// by modification, all localized modifications are used when no modification is given
+func classification(evi rep.Evidence, mods bool, ptms []string, best bool, remove, purity, probability float64) (map[string]iso.Labels, map[string]map[string]iso.Labels) {
 
 	var spectrumMap = make(map[string]iso.Labels)
-	var phosphoSpectrumMap = make(map[string]iso.Labels)
+	var modSpectrumMap = make(map[string]map[string]iso.Labels)
 
 	var bestMap = make(map[string]uint8)
 
@@ -445,9 +467,11 @@ func classification(evi rep.Evidence, mods, best bool, remove, purity, probabili
 			bestMap[i.Spectrum] = 0
 
 			if mods == true {
-				_, ok := i.LocalizedPTMSites["PTMProphet_STY79.9663"]
-				if ok {
-					phosphoSpectrumMap[i.Spectrum] = i.Labels
+				for _, j := range localizedModifications(i, ptms) {
+					if _, ok := modSpectrumMap[j]; !ok {
+						modSpectrumMap[j] = make(map[string]iso.Labels)
+					}
+					modSpectrumMap[j][i.Spectrum] = i.Labels
 				}
 			}
 
@@ -524,7 +548,7 @@ func classification(evi rep.Evidence, mods, best bool, remove, purity, probabili
 	}
 
 	var toDelete = make(map[string]uint8)
-	var toDeletePhospho = make(map[string]uint8)
+	var toDeleteMods = make(map[string]uint8)
 
 	// 3rd check: remove the lower 3%
 	// Ignore all PSMs that fall under the lower 3% based on their summed TMT labels
@@ -535,7 +559,7 @@ func classification(evi rep.Evidence, mods, best bool, remove, purity, probabili
 
 		for i := 0; i <= lowerFiveInt; i++ {
 			toDelete[psmLabelSumList[i].Key] = 0
-			toDeletePhospho[psmLabelSumList[i].Key] = 0
+			toDeleteMods[psmLabelSumList[i].Key] = 0
 		}
 	}
 
@@ -546,21 +570,55 @@ func classification(evi rep.Evidence, mods, best bool, remove, purity, probabili
 		}
 	}
 
+	// 4th check: chimeric spectra
+	// the reporter ions from chimeric spectra are a mixture from the co-isolated peptides
+	for _, i := range evi.PSM {
+		if i.IsChimeric == true {
+			toDelete[i.Spectrum] = 0
+			toDeleteMods[i.Spectrum] = 0
+		}
+	}
+
 	for i := range toDelete {
 		delete(spectrumMap, i)
 	}
 
-	for k := range phosphoSpectrumMap {
-		_, ok := bestMap[k]
-		if !ok {
-			toDeletePhospho[k] = 0
+	for _, spectra := range modSpectrumMap {
+		for k := range spectra {
+			_, ok := bestMap[k]
+			if !ok {
+				toDeleteMods[k] = 0
+			}
 		}
 	}
 
 	logrus.Info("Removing ", len(toDelete), " PSMs from isobaric quantification")
-	for i := range toDeletePhospho {
-		delete(phosphoSpectrumMap, i)
+	for i := range toDeleteMods {
+		for _, spectra := range modSpectrumMap {
+			delete(spectra, i)
+		}
+	}
+
+	return spectrumMap, modSpectrumMap
+}
+
+// localizedModifications lists the modifications of interest localized on the PSM
+func localizedModifications(psm rep.PSMEvidence, ptms []string) []string {
+
+	var list []string
+
+	if len(ptms) == 0 {
+		for k := range psm.LocalizedPTMSites {
+			list = append(list, k)
+		}
+		return list
+	}
+
+	for _, i := range ptms {
+		if _, ok := psm.LocalizedPTMSites[i]; ok {
+			list = append(list, i)
+		}
 	}
 
-	return spectrumMap, phosphoSpectrumMap
+	return list
 }
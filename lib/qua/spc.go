@@ -29,18 +29,29 @@ func CalculateSpectralCounts(e rep.Evidence) rep.Evidence {
 
 		e.Proteins[i].TotalSpC = len(e.Proteins[i].SupportingSpectra)
 
+		// chimeric spectra can support more than one ion from the same protein, so the
+		// spectra are counted only once
+		var uniqueSpectra = make(map[string]uint8)
+		var razorSpectra = make(map[string]uint8)
+
 		for _, j := range e.Proteins[i].TotalPeptideIons {
 
 			if j.IsUnique == true {
-				e.Proteins[i].UniqueSpC += len(j.Spectra)
+				for k := range j.Spectra {
+					uniqueSpectra[k] = 0
+				}
 			}
 
 			if j.IsURazor == true {
-				e.Proteins[i].URazorSpC += len(j.Spectra)
+				for k := range j.Spectra {
+					razorSpectra[k] = 0
+				}
 			}
 
 		}
 
+		e.Proteins[i].UniqueSpC = len(uniqueSpectra)
+		e.Proteins[i].URazorSpC = len(razorSpectra)
 	}
 
 	return e
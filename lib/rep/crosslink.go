@@ -0,0 +1,272 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+)
+
+// CrosslinkChain is one of the two peptides of a crosslinked spectrum match
+type CrosslinkChain struct {
+	Peptide         string
+	ModifiedPeptide string
+	Protein         string
+	MappedProteins  map[string]int
+	PeptidePosition int
+	ProteinPosition int
+	IsDecoy         bool
+}
+
+// CrosslinkEvidence is a spectrum matched to two crosslinked peptides
+type CrosslinkEvidence struct {
+	Spectrum       string
+	Scan           int
+	AssumedCharge  uint8
+	Crosslinker    string
+	Score          float64
+	QValue         float64
+	IsIntraProtein bool
+	Chains         [2]CrosslinkChain
+}
+
+// CrosslinkEvidenceList ...
+type CrosslinkEvidenceList []CrosslinkEvidence
+
+func (a CrosslinkEvidenceList) Len() int           { return len(a) }
+func (a CrosslinkEvidenceList) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a CrosslinkEvidenceList) Less(i, j int) bool { return a[i].Spectrum < a[j].Spectrum }
+
+// ResiduePairEvidence groups the crosslinks connecting the same two protein residues
+type ResiduePairEvidence struct {
+	Proteins       [2]string
+	Positions      [2]int
+	Residues       [2]string
+	Crosslinker    string
+	DecoyClass     string
+	IsIntraProtein bool
+	Score          float64
+	QValue         float64
+	Spectra        map[string]int
+	Peptides       map[string]int
+}
+
+// ResiduePairEvidenceList ...
+type ResiduePairEvidenceList []ResiduePairEvidence
+
+// DecoyClass labels the match as TT, TD or DD according to the number of decoy chains
+func (c CrosslinkEvidence) DecoyClass() string {
+
+	switch {
+	case c.Chains[0].IsDecoy && c.Chains[1].IsDecoy:
+		return "DD"
+	case c.Chains[0].IsDecoy || c.Chains[1].IsDecoy:
+		return "TD"
+	}
+
+	return "TT"
+}
+
+// linkType is the label used on the reports for intra- and inter-protein links
+func linkType(intra bool) string {
+
+	if intra {
+		return "intra"
+	}
+
+	return "inter"
+}
+
+// AssembleResiduePairs groups the crosslinked spectrum matches by the linked protein residues, the pair
+// keeps the best score, the higher one unless ascending is set for scores where lower is better
+func (evi *Evidence) AssembleResiduePairs(ascending bool) {
+
+	var pairs = make(map[string]*ResiduePairEvidence)
+	var keys []string
+
+	for _, i := range evi.Crosslinks {
+
+		a, b := i.Chains[0], i.Chains[1]
+
+		// the same residue pair can be reported on both orders
+		if a.Protein > b.Protein || (a.Protein == b.Protein && a.ProteinPosition > b.ProteinPosition) {
+			a, b = b, a
+		}
+
+		key := fmt.Sprintf("%s#%d#%s#%d", a.Protein, a.ProteinPosition, b.Protein, b.ProteinPosition)
+
+		rp, ok := pairs[key]
+		if !ok {
+			rp = &ResiduePairEvidence{
+				Proteins:       [2]string{a.Protein, b.Protein},
+				Positions:      [2]int{a.ProteinPosition, b.ProteinPosition},
+				Residues:       [2]string{linkedResidue(a), linkedResidue(b)},
+				Crosslinker:    i.Crosslinker,
+				DecoyClass:     i.DecoyClass(),
+				IsIntraProtein: i.IsIntraProtein,
+				Score:          i.Score,
+				Spectra:        make(map[string]int),
+				Peptides:       make(map[string]int),
+			}
+			pairs[key] = rp
+			keys = append(keys, key)
+		}
+
+		if (!ascending && i.Score > rp.Score) || (ascending && i.Score < rp.Score) {
+			rp.Score = i.Score
+		}
+
+		rp.Spectra[i.Spectrum]++
+		rp.Peptides[fmt.Sprintf("%s(%d)-%s(%d)", a.Peptide, a.PeptidePosition, b.Peptide, b.PeptidePosition)]++
+	}
+
+	sort.Strings(keys)
+
+	evi.ResiduePairs = nil
+	for _, i := range keys {
+		evi.ResiduePairs = append(evi.ResiduePairs, *pairs[i])
+	}
+
+	return
+}
+
+// linkedResidue is the amino acid carrying the crosslinker
+func linkedResidue(c CrosslinkChain) string {
+
+	if c.PeptidePosition < 1 || c.PeptidePosition > len(c.Peptide) {
+		return ""
+	}
+
+	return string(c.Peptide[c.PeptidePosition-1])
+}
+
+// CrosslinkReport creates the crosslinked spectrum match report
+func (evi *Evidence) CrosslinkReport(hasDecoys bool) {
+
+	output := fmt.Sprintf("%s%scrosslink.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	// create result file
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create crosslink report"), "error")
+	}
+	defer file.Close()
+
+	_, e = io.WriteString(file, "Spectrum\tScan\tCharge\tCrosslinker\tScore\tQ-Value\tDecoy Class\tLink Type\tPeptide 1\tModified Peptide 1\tLink Position 1\tProtein 1\tProtein Position 1\tMapped Proteins 1\tPeptide 2\tModified Peptide 2\tLink Position 2\tProtein 2\tProtein Position 2\tMapped Proteins 2\n")
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	sort.Sort(evi.Crosslinks)
+
+	for _, i := range evi.Crosslinks {
+
+		if hasDecoys == false && i.DecoyClass() != "TT" {
+			continue
+		}
+
+		line := fmt.Sprintf("%s\t%d\t%d\t%s\t%.4f\t%.4f\t%s\t%s",
+			i.Spectrum,
+			i.Scan,
+			i.AssumedCharge,
+			i.Crosslinker,
+			i.Score,
+			i.QValue,
+			i.DecoyClass(),
+			linkType(i.IsIntraProtein),
+		)
+
+		for _, j := range i.Chains {
+
+			var mapped []string
+			for k := range j.MappedProteins {
+				if k != j.Protein {
+					mapped = append(mapped, k)
+				}
+			}
+			sort.Strings(mapped)
+
+			line += fmt.Sprintf("\t%s\t%s\t%d\t%s\t%d\t%s",
+				j.Peptide,
+				j.ModifiedPeptide,
+				j.PeptidePosition,
+				j.Protein,
+				j.ProteinPosition,
+				strings.Join(mapped, ", "),
+			)
+		}
+
+		_, e = io.WriteString(file, line+"\n")
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
+
+// ResiduePairReport creates the residue pair report with the linked protein positions
+func (evi *Evidence) ResiduePairReport(hasDecoys bool) {
+
+	output := fmt.Sprintf("%s%sresidue_pair.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	// create result file
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create residue pair report"), "error")
+	}
+	defer file.Close()
+
+	_, e = io.WriteString(file, "Protein 1\tPosition 1\tResidue 1\tProtein 2\tPosition 2\tResidue 2\tCrosslinker\tLink Type\tDecoy Class\tScore\tQ-Value\tSpectral Count\tPeptides\n")
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	for _, i := range evi.ResiduePairs {
+
+		if hasDecoys == false && i.DecoyClass != "TT" {
+			continue
+		}
+
+		var peptides []string
+		for j := range i.Peptides {
+			peptides = append(peptides, j)
+		}
+		sort.Strings(peptides)
+
+		line := fmt.Sprintf("%s\t%d\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%.4f\t%.4f\t%d\t%s\n",
+			i.Proteins[0],
+			i.Positions[0],
+			i.Residues[0],
+			i.Proteins[1],
+			i.Positions[1],
+			i.Residues[1],
+			i.Crosslinker,
+			linkType(i.IsIntraProtein),
+			i.DecoyClass,
+			i.Score,
+			i.QValue,
+			len(i.Spectra),
+			strings.Join(peptides, ", "),
+		)
+
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
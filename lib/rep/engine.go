@@ -0,0 +1,113 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+)
+
+// SearchEngineReport creates the TSV report with the contribution from each search engine
+// to the combined PSM list
+func (evi Evidence) SearchEngineReport(hasDecoys bool) {
+
+	output := fmt.Sprintf("%s%ssearch_engines.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create search engine report"), "error")
+	}
+	defer file.Close()
+
+	var engines = make(map[string]uint8)
+	var psms = make(map[string]int)
+	var exclusive = make(map[string]int)
+	var shared = make(map[string]int)
+	var conflicts = make(map[string]int)
+	var peptides = make(map[string]map[string]uint8)
+	var peptideEngines = make(map[string]map[string]uint8)
+
+	for _, i := range evi.PSM {
+
+		if i.IsDecoy == true && hasDecoys == false {
+			continue
+		}
+
+		for _, j := range i.SearchEngines {
+
+			engines[j] = 0
+			psms[j]++
+
+			if len(i.SearchEngines) == 1 {
+				exclusive[j]++
+			} else {
+				shared[j]++
+			}
+
+			_, ok := peptides[j]
+			if !ok {
+				peptides[j] = make(map[string]uint8)
+			}
+			peptides[j][i.Peptide] = 0
+
+			_, ok = peptideEngines[i.Peptide]
+			if !ok {
+				peptideEngines[i.Peptide] = make(map[string]uint8)
+			}
+			peptideEngines[i.Peptide][j] = 0
+		}
+
+		for _, j := range i.ConflictingEngines {
+			engines[j] = 0
+			conflicts[j]++
+		}
+	}
+
+	var list []string
+	for i := range engines {
+		list = append(list, i)
+	}
+	sort.Strings(list)
+
+	header := "Search Engine\tPSMs\tExclusive PSMs\tShared PSMs\tConflicting PSMs\tPeptides\tExclusive Peptides\n"
+
+	_, e = io.WriteString(file, header)
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	for _, i := range list {
+
+		var exclusivePeptides int
+		for j := range peptides[i] {
+			if len(peptideEngines[j]) == 1 {
+				exclusivePeptides++
+			}
+		}
+
+		line := fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			i,                 // Search Engine
+			psms[i],           // PSMs
+			exclusive[i],      // Exclusive PSMs
+			shared[i],         // Shared PSMs
+			conflicts[i],      // Conflicting PSMs
+			len(peptides[i]),  // Peptides
+			exclusivePeptides, // Exclusive Peptides
+		)
+
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
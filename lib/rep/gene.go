@@ -0,0 +1,329 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+)
+
+// AssembleGeneReport collapses the identified peptide ions by gene, only genes that passed
+// the gene-level FDR are kept. Proteins without a gene annotation are kept as their own entries.
+func (evi *Evidence) AssembleGeneReport(genes map[string]float64, proteinGenes map[string]string, decoyTag string) {
+
+	var list GeneEvidenceList
+	var geneMap = make(map[string]GeneEvidence)
+
+	geneOf := func(protein string) string {
+		gene, ok := proteinGenes[protein]
+		if !ok || len(gene) == 0 {
+			return protein
+		}
+		return gene
+	}
+
+	for _, i := range evi.Ions {
+
+		var ionGenes = make(map[string][]string)
+
+		ionGenes[geneOf(i.Protein)] = append(ionGenes[geneOf(i.Protein)], i.Protein)
+		for j := range i.MappedProteins {
+			ionGenes[geneOf(j)] = append(ionGenes[geneOf(j)], j)
+		}
+
+		// an ion is unique when all of its mapped proteins belong to the same gene
+		isUnique := len(ionGenes) == 1
+
+		for j, k := range ionGenes {
+
+			score, ok := genes[j]
+			if !ok {
+				continue
+			}
+
+			g, ok := geneMap[j]
+			if !ok {
+				g.GeneName = j
+				g.Probability = score
+				g.Proteins = make(map[string]uint8)
+				g.PeptideIons = make(map[string]bool)
+				g.StrippedPeptides = make(map[string]uint8)
+				g.SupportingSpectra = make(map[string]int)
+				g.UniqueSpectra = make(map[string]int)
+
+				if strings.HasPrefix(j, decoyTag) {
+					g.IsDecoy = true
+				}
+			}
+
+			for _, l := range k {
+				g.Proteins[l] = 0
+			}
+
+			g.PeptideIons[i.IonForm] = isUnique
+			g.StrippedPeptides[i.Sequence] = 0
+
+			for l := range i.Spectra {
+				g.SupportingSpectra[l]++
+				if isUnique {
+					g.UniqueSpectra[l]++
+				}
+			}
+
+			if i.Probability > g.TopPepProb {
+				g.TopPepProb = i.Probability
+			}
+
+			geneMap[j] = g
+		}
+	}
+
+	for _, i := range geneMap {
+		list = append(list, i)
+	}
+
+	sort.Sort(list)
+	evi.Genes = list
+
+	return
+}
+
+// rollUpGenes sums the spectral counts, intensities and isobaric labels from the peptide ions to
+// the genes, the roll up happens on report time so the quantification steps are all considered
+func (evi *Evidence) rollUpGenes() {
+
+	var ionIntMap = make(map[string]float64)
+	for _, i := range evi.Ions {
+		ionIntMap[i.IonForm] = i.Intensity
+	}
+
+	var labelMap = make(map[string]iso.Labels)
+	for _, i := range evi.PSM {
+		if i.Labels.IsUsed {
+			labelMap[i.Spectrum] = i.Labels
+		}
+	}
+
+	for i := range evi.Genes {
+
+		var totalInt []float64
+		var uniqueInt []float64
+
+		for j, k := range evi.Genes[i].PeptideIons {
+			totalInt = append(totalInt, ionIntMap[j])
+			if k {
+				uniqueInt = append(uniqueInt, ionIntMap[j])
+			}
+		}
+
+		// gene intensities : top 3 most intense ions
+		evi.Genes[i].TotalIntensity = topThreeSum(totalInt)
+		evi.Genes[i].UniqueIntensity = topThreeSum(uniqueInt)
+
+		evi.Genes[i].TotalSpC = len(evi.Genes[i].SupportingSpectra)
+		evi.Genes[i].UniqueSpC = len(evi.Genes[i].UniqueSpectra)
+
+		evi.Genes[i].TotalLabels = iso.Labels{}
+		evi.Genes[i].UniqueLabels = iso.Labels{}
+
+		for j := range evi.Genes[i].SupportingSpectra {
+			v, ok := labelMap[j]
+			if ok {
+				evi.Genes[i].TotalLabels.Add(v)
+			}
+		}
+
+		for j := range evi.Genes[i].UniqueSpectra {
+			v, ok := labelMap[j]
+			if ok {
+				evi.Genes[i].UniqueLabels.Add(v)
+			}
+		}
+	}
+
+	return
+}
+
+// MetaGeneReport creates the TSV Gene report
+func (evi Evidence) MetaGeneReport(brand string, channels int, hasDecoys, uniqueOnly bool) {
+
+	output := fmt.Sprintf("%s%sgene.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	evi.rollUpGenes()
+
+	// create result file
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create gene report"), "error")
+	}
+	defer file.Close()
+
+	// building the printing set tat may or not contain decoys
+	var printSet GeneEvidenceList
+	for _, i := range evi.Genes {
+		if hasDecoys == false {
+			if i.IsDecoy == false {
+				printSet = append(printSet, i)
+			}
+		} else {
+			printSet = append(printSet, i)
+		}
+	}
+
+	header := "Gene\tGene Probability\tTop Peptide Probability\tStripped Peptides\tTotal Peptide Ions\tUnique Peptide Ions\tTotal Spectral Count\tUnique Spectral Count\tTotal Intensity\tUnique Intensity\tNumber of Proteins\tProteins"
+
+	// the channel names are taken from the first quantified spectrum
+	if len(brand) > 0 {
+		for _, i := range evi.PSM {
+			if i.Labels.IsUsed {
+				labelNames := labelsToNames(i.Labels)
+				for j := 0; j < channels && j < len(labelNames); j++ {
+					header += "\t" + labelNames[j]
+				}
+				break
+			}
+		}
+	}
+
+	header += "\n"
+
+	_, e = io.WriteString(file, header)
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	for _, i := range printSet {
+
+		var proteins []string
+		for j := range i.Proteins {
+			proteins = append(proteins, j)
+		}
+		sort.Strings(proteins)
+
+		var uniqIons int
+		for _, j := range i.PeptideIons {
+			if j == true {
+				uniqIons++
+			}
+		}
+
+		line := fmt.Sprintf("%s\t%.4f\t%.4f\t%d\t%d\t%d\t%d\t%d\t%6.f\t%6.f\t%d\t%s",
+			i.GeneName,                   // Gene
+			i.Probability,                // Gene Probability
+			i.TopPepProb,                 // Top Peptide Probability
+			len(i.StrippedPeptides),      // Stripped Peptides
+			len(i.PeptideIons),           // Total Peptide Ions
+			uniqIons,                     // Unique Peptide Ions
+			i.TotalSpC,                   // Total Spectral Count
+			i.UniqueSpC,                  // Unique Spectral Count
+			i.TotalIntensity,             // Total Intensity
+			i.UniqueIntensity,            // Unique Intensity
+			len(proteins),                // Number of Proteins
+			strings.Join(proteins, ", "), // Proteins
+		)
+
+		if len(brand) > 0 {
+
+			reportIntensities := labelsToIntensities(i.TotalLabels)
+			if uniqueOnly == true {
+				reportIntensities = labelsToIntensities(i.UniqueLabels)
+			}
+
+			for j := 0; j < channels && j < len(reportIntensities); j++ {
+				line = fmt.Sprintf("%s\t%.4f", line, reportIntensities[j])
+			}
+		}
+
+		line += "\n"
+
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
+
+// topThreeSum adds the three highest values from the given list
+func topThreeSum(values []float64) float64 {
+
+	var sum float64
+
+	sort.Sort(sort.Reverse(sort.Float64Slice(values)))
+
+	for i := 0; i < len(values) && i < 3; i++ {
+		sum += values[i]
+	}
+
+	return sum
+}
+
+// labelsToIntensities lists the channel intensities in the channel order
+func labelsToIntensities(l iso.Labels) [16]float64 {
+
+	return [16]float64{
+		l.Channel1.Intensity,
+		l.Channel2.Intensity,
+		l.Channel3.Intensity,
+		l.Channel4.Intensity,
+		l.Channel5.Intensity,
+		l.Channel6.Intensity,
+		l.Channel7.Intensity,
+		l.Channel8.Intensity,
+		l.Channel9.Intensity,
+		l.Channel10.Intensity,
+		l.Channel11.Intensity,
+		l.Channel12.Intensity,
+		l.Channel13.Intensity,
+		l.Channel14.Intensity,
+		l.Channel15.Intensity,
+		l.Channel16.Intensity,
+	}
+}
+
+// labelsToNames lists the channel names in the channel order, custom names from the
+// annotation file replace the original channel names
+func labelsToNames(l iso.Labels) [16]string {
+
+	var names [16]string
+
+	channels := [16][2]string{
+		{l.Channel1.Name, l.Channel1.CustomName},
+		{l.Channel2.Name, l.Channel2.CustomName},
+		{l.Channel3.Name, l.Channel3.CustomName},
+		{l.Channel4.Name, l.Channel4.CustomName},
+		{l.Channel5.Name, l.Channel5.CustomName},
+		{l.Channel6.Name, l.Channel6.CustomName},
+		{l.Channel7.Name, l.Channel7.CustomName},
+		{l.Channel8.Name, l.Channel8.CustomName},
+		{l.Channel9.Name, l.Channel9.CustomName},
+		{l.Channel10.Name, l.Channel10.CustomName},
+		{l.Channel11.Name, l.Channel11.CustomName},
+		{l.Channel12.Name, l.Channel12.CustomName},
+		{l.Channel13.Name, l.Channel13.CustomName},
+		{l.Channel14.Name, l.Channel14.CustomName},
+		{l.Channel15.Name, l.Channel15.CustomName},
+		{l.Channel16.Name, l.Channel16.CustomName},
+	}
+
+	for i, j := range channels {
+		if len(j[1]) > 3 {
+			names[i] = j[1]
+		} else {
+			names[i] = "Channel " + j[0]
+		}
+	}
+
+	return names
+}
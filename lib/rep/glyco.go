@@ -0,0 +1,109 @@
+package rep
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/dat"
+	"philosopher/lib/gly"
+	"philosopher/lib/mzn"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AssignGlycans matches the PSM mass differences to the glycan database, annotates the N-glycosites
+// from the protein sequences and checks the MS2 spectra for oxonium ions when the spectra are available
+func (evi *Evidence) AssignGlycans(database, dir string, tol float64) {
+
+	comps := gly.ReadDatabase(database)
+
+	logrus.Info("Matching ", len(evi.PSM), " PSMs to ", len(comps), " glycan compositions")
+
+	var dtb dat.Base
+	dtb.Restore()
+
+	var sequences = make(map[string]string)
+	for _, i := range dtb.Records {
+		sequences[i.PartHeader] = i.Sequence
+	}
+
+	var sourceMap = make(map[string][]int)
+	var matched int
+
+	for i := range evi.PSM {
+
+		evi.PSM[i].GlycanComposition = ""
+		evi.PSM[i].Glycosites = ""
+		evi.PSM[i].HasOxonium = false
+
+		a, ok := gly.Match(evi.PSM[i].Massdiff, evi.PSM[i].PrecursorNeutralMass, comps, tol)
+		if ok {
+			evi.PSM[i].GlycanComposition = a.Composition.Name
+			if a.Isotope != 0 {
+				evi.PSM[i].GlycanComposition = fmt.Sprintf("%s isotope %+d", a.Composition.Name, a.Isotope)
+			}
+			matched++
+
+			// the sequons are reported only for the glycopeptides
+			var sites []string
+			for _, j := range gly.Glycosites(evi.PSM[i].Peptide, sequences[evi.PSM[i].Protein]) {
+				sites = append(sites, "N"+strconv.Itoa(j))
+			}
+			evi.PSM[i].Glycosites = strings.Join(sites, ", ")
+		}
+
+		source := strings.Split(evi.PSM[i].Spectrum, ".")[0]
+		sourceMap[source] = append(sourceMap[source], i)
+	}
+
+	logrus.Info("Assigned glycan compositions to ", matched, " PSMs")
+
+	if len(dir) == 0 {
+		return
+	}
+
+	var sourceList []string
+	for i := range sourceMap {
+		sourceList = append(sourceList, i)
+	}
+	sort.Strings(sourceList)
+
+	for _, s := range sourceList {
+
+		fileName := fmt.Sprintf("%s%s%s.mzML", dir, string(filepath.Separator), s)
+		if _, e := os.Stat(fileName); e != nil {
+			logrus.Warning("Cannot find ", fileName, ", skipping the oxonium ion check")
+			continue
+		}
+
+		logrus.Info("Checking oxonium ions on ", s)
+
+		var mz mzn.MsData
+		mz.Read(fileName, true, false, true)
+
+		var spectra = make(map[int]int)
+		for j := range mz.Spectra {
+			scan, e := strconv.Atoi(mz.Spectra[j].Scan)
+			if e == nil {
+				spectra[scan] = j
+			}
+		}
+
+		for _, i := range sourceMap[s] {
+
+			j, ok := spectra[evi.PSM[i].Scan]
+			if !ok {
+				continue
+			}
+
+			mz.Spectra[j].Decode()
+			evi.PSM[i].HasOxonium = gly.HasOxonium(mz.Spectra[j].Mz.DecodedStream, tol)
+		}
+	}
+
+	return
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/vmihailenco/msgpack"
+	"philosopher/lib/prf"
 	"philosopher/lib/sys"
 )
 
@@ -41,6 +42,9 @@ func (evi *Evidence) SerializeGranular() {
 	// create EV Peptides
 	SerializeEVPeptides(evi)
 
+	// create EV Modified Peptides
+	SerializeEVModifiedPeptides(evi)
+
 	// create EV Ion
 	SerializeEVProteins(evi)
 
@@ -53,6 +57,14 @@ func (evi *Evidence) SerializeGranular() {
 	// create EV Combined
 	SerializeEVCombined(evi)
 
+	// create EV Genes
+	SerializeEVGenes(evi)
+
+	// create EV Crosslinks, imported crosslinks are kept when the evidence is rebuilt without them
+	if len(evi.Crosslinks) > 0 {
+		SerializeEVCrosslinks(evi)
+	}
+
 	return
 }
 
@@ -120,6 +132,22 @@ func SerializeEVPeptides(evi *Evidence) {
 	return
 }
 
+// SerializeEVModifiedPeptides creates an ev serial with Evidence data
+func SerializeEVModifiedPeptides(evi *Evidence) {
+
+	b, e := msgpack.Marshal(&evi.ModifiedPeptides)
+	if e != nil {
+		logrus.Trace("Cannot marshal Modified Peptides data:", e)
+	}
+
+	e = ioutil.WriteFile(sys.EvModifiedPeptideBin(), b, sys.FilePermission())
+	if e != nil {
+		logrus.Trace("Cannot serialize Modified Peptides data:", e)
+	}
+
+	return
+}
+
 // SerializeEVProteins creates an ev serial with Evidence data
 func SerializeEVProteins(evi *Evidence) {
 
@@ -184,6 +212,48 @@ func SerializeEVCombined(evi *Evidence) {
 	return
 }
 
+// SerializeEVGenes creates an ev serial with Evidence data
+func SerializeEVGenes(evi *Evidence) {
+
+	b, e := msgpack.Marshal(&evi.Genes)
+	if e != nil {
+		logrus.Trace("Cannot marshal Genes data:", e)
+	}
+
+	e = ioutil.WriteFile(sys.EvGeneBin(), b, sys.FilePermission())
+	if e != nil {
+		logrus.Trace("Cannot serialize Genes data:", e)
+	}
+
+	return
+}
+
+// SerializeEVCrosslinks creates an ev serial with the crosslinks and the residue pairs
+func SerializeEVCrosslinks(evi *Evidence) {
+
+	b, e := msgpack.Marshal(&evi.Crosslinks)
+	if e != nil {
+		logrus.Trace("Cannot marshal Crosslinks data:", e)
+	}
+
+	e = ioutil.WriteFile(sys.EvCrosslinkBin(), b, sys.FilePermission())
+	if e != nil {
+		logrus.Trace("Cannot serialize Crosslinks data:", e)
+	}
+
+	b, e = msgpack.Marshal(&evi.ResiduePairs)
+	if e != nil {
+		logrus.Trace("Cannot marshal Residue Pairs data:", e)
+	}
+
+	e = ioutil.WriteFile(sys.EvResiduePairBin(), b, sys.FilePermission())
+	if e != nil {
+		logrus.Trace("Cannot serialize Residue Pairs data:", e)
+	}
+
+	return
+}
+
 // Restore reads philosopher results files and restore the data sctructure
 func (evi *Evidence) Restore() {
 
@@ -215,6 +285,9 @@ func (evi *Evidence) RestoreGranular() {
 
 	RestoreEVPeptide(evi)
 
+	// Modified Peptides
+	RestoreEVModifiedPeptides(evi)
+
 	// Protein
 	RestoreEVProtein(evi)
 
@@ -227,6 +300,12 @@ func (evi *Evidence) RestoreGranular() {
 	// Combined
 	RestoreEVCombined(evi)
 
+	// Genes
+	RestoreEVGenes(evi)
+
+	// Crosslinks
+	RestoreEVCrosslinks(evi)
+
 	return
 }
 
@@ -257,6 +336,9 @@ func RestoreEVPSM(evi *Evidence) {
 	if e != nil {
 		logrus.Fatal("Cannot unmarshal file:", e)
 	}
+
+	migratePSMIonKeys(evi.PSM)
+
 	return
 }
 
@@ -273,6 +355,58 @@ func RestoreEVIon(evi *Evidence) {
 		logrus.Fatal("Cannot unmarshal file:", e)
 	}
 
+	migrateIonKeys(evi.Ions)
+
+	return
+}
+
+// migrateIonKeys rewrites the sequence#charge#mass ion keys written by previous versions with the
+// ProForma ion keys, so the layers from older workspaces can still be joined by ion
+func migrateIonKeys(ions IonEvidenceList) {
+
+	for i := range ions {
+		ions[i].IonForm = prf.MigrateIonKey(ions[i].IonForm)
+	}
+
+	return
+}
+
+// migratePSMIonKeys rewrites the PSM ion keys written by previous versions
+func migratePSMIonKeys(psm PSMEvidenceList) {
+
+	for i := range psm {
+		psm[i].IonForm = prf.MigrateIonKey(psm[i].IonForm)
+	}
+
+	return
+}
+
+// migrateProteinIonKeys rewrites the protein ion keys written by previous versions
+func migrateProteinIonKeys(proteins ProteinEvidenceList) {
+
+	for i := range proteins {
+		var ions = make(map[string]IonEvidence)
+		for k, v := range proteins[i].TotalPeptideIons {
+			v.IonForm = prf.MigrateIonKey(v.IonForm)
+			ions[prf.MigrateIonKey(k)] = v
+		}
+		proteins[i].TotalPeptideIons = ions
+	}
+
+	return
+}
+
+// migrateGeneIonKeys rewrites the gene ion keys written by previous versions
+func migrateGeneIonKeys(genes GeneEvidenceList) {
+
+	for i := range genes {
+		var ions = make(map[string]bool)
+		for k, v := range genes[i].PeptideIons {
+			ions[prf.MigrateIonKey(k)] = v
+		}
+		genes[i].PeptideIons = ions
+	}
+
 	return
 }
 
@@ -292,6 +426,23 @@ func RestoreEVPeptide(evi *Evidence) {
 	return
 }
 
+// RestoreEVModifiedPeptides restores Ev Modified Peptide data, the layer does not exist on
+// workspaces filtered before it was introduced
+func RestoreEVModifiedPeptides(evi *Evidence) {
+
+	b, e := ioutil.ReadFile(sys.EvModifiedPeptideBin())
+	if e != nil {
+		return
+	}
+
+	e = msgpack.Unmarshal(b, &evi.ModifiedPeptides)
+	if e != nil {
+		logrus.Fatal("Cannot unmarshal file:", e)
+	}
+
+	return
+}
+
 // RestoreEVProtein restores Ev Protein data
 func RestoreEVProtein(evi *Evidence) {
 
@@ -305,6 +456,8 @@ func RestoreEVProtein(evi *Evidence) {
 		logrus.Fatal("Cannot unmarshal file:", e)
 	}
 
+	migrateProteinIonKeys(evi.Proteins)
+
 	return
 }
 
@@ -356,6 +509,52 @@ func RestoreEVCombined(evi *Evidence) {
 	return
 }
 
+// RestoreEVGenes restores Ev Gene data, the gene layer is optional and may not
+// exist on workspaces processed without the gene-level FDR
+func RestoreEVGenes(evi *Evidence) {
+
+	b, e := ioutil.ReadFile(sys.EvGeneBin())
+	if e != nil {
+		return
+	}
+
+	e = msgpack.Unmarshal(b, &evi.Genes)
+	if e != nil {
+		logrus.Fatal("Cannot unmarshal file:", e)
+	}
+
+	migrateGeneIonKeys(evi.Genes)
+
+	return
+}
+
+// RestoreEVCrosslinks restores Ev Crosslink data, the crosslinks are optional and only
+// exist on workspaces where crosslinking results were imported
+func RestoreEVCrosslinks(evi *Evidence) {
+
+	b, e := ioutil.ReadFile(sys.EvCrosslinkBin())
+	if e != nil {
+		return
+	}
+
+	e = msgpack.Unmarshal(b, &evi.Crosslinks)
+	if e != nil {
+		logrus.Fatal("Cannot unmarshal file:", e)
+	}
+
+	b, e = ioutil.ReadFile(sys.EvResiduePairBin())
+	if e != nil {
+		return
+	}
+
+	e = msgpack.Unmarshal(b, &evi.ResiduePairs)
+	if e != nil {
+		logrus.Fatal("Cannot unmarshal file:", e)
+	}
+
+	return
+}
+
 // RestoreGranularWithPath reads philosopher results files and restore the data sctructure
 func (evi *Evidence) RestoreGranularWithPath(p string) {
 
@@ -419,6 +618,8 @@ func RestoreEVPSMWithPath(evi *Evidence, p string) {
 		logrus.Fatal("Cannot unmarshal file:", e)
 	}
 
+	migratePSMIonKeys(evi.PSM)
+
 	return
 }
 
@@ -437,6 +638,8 @@ func RestoreEVIonWithPath(evi *Evidence, p string) {
 		logrus.Fatal("Cannot unmarshal file:", e)
 	}
 
+	migrateIonKeys(evi.Ions)
+
 	return
 }
 
@@ -473,6 +676,8 @@ func RestoreEVProteinWithPath(evi *Evidence, p string) {
 		logrus.Fatal("Cannot unmarshal file:", e)
 	}
 
+	migrateProteinIonKeys(evi.Proteins)
+
 	return
 }
 
@@ -0,0 +1,32 @@
+package rep
+
+import "testing"
+
+func Test_migrateIonKeys(t *testing.T) {
+
+	legacy := "PEPTIDE#2#879.3263"
+	current := "[+79.9663]?PEPTIDE/2"
+
+	psm := PSMEvidenceList{{IonForm: legacy}}
+	ions := IonEvidenceList{{IonForm: legacy}, {IonForm: current}}
+	proteins := ProteinEvidenceList{{TotalPeptideIons: map[string]IonEvidence{legacy: {IonForm: legacy}}}}
+	genes := GeneEvidenceList{{PeptideIons: map[string]bool{legacy: true}}}
+
+	migratePSMIonKeys(psm)
+	migrateIonKeys(ions)
+	migrateProteinIonKeys(proteins)
+	migrateGeneIonKeys(genes)
+
+	if psm[0].IonForm != current || ions[0].IonForm != current || ions[1].IonForm != current {
+		t.Errorf("migrated ions = %v, %v, %v", psm[0].IonForm, ions[0].IonForm, ions[1].IonForm)
+	}
+
+	ion, ok := proteins[0].TotalPeptideIons[current]
+	if !ok || ion.IonForm != current || len(proteins[0].TotalPeptideIons) != 1 {
+		t.Errorf("migrated protein ions = %v", proteins[0].TotalPeptideIons)
+	}
+
+	if !genes[0].PeptideIons[current] || len(genes[0].PeptideIons) != 1 {
+		t.Errorf("migrated gene ions = %v", genes[0].PeptideIons)
+	}
+}
@@ -14,7 +14,9 @@ import (
 	"philosopher/lib/bio"
 	"philosopher/lib/cla"
 	"philosopher/lib/id"
+	"philosopher/lib/iso"
 	"philosopher/lib/mod"
+	"philosopher/lib/prf"
 	"philosopher/lib/sys"
 	"philosopher/lib/uti"
 )
@@ -52,7 +54,7 @@ func (evi *Evidence) AssembleIonReport(ion id.PepIDList, decoyTag string) {
 	for _, i := range ion {
 		var pr IonEvidence
 
-		pr.IonForm = fmt.Sprintf("%s#%d#%.4f", i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
+		pr.IonForm = prf.IonKey(i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
 
 		pr.Spectra = make(map[string]int)
 		pr.MappedGenes = make(map[string]int)
@@ -135,7 +137,7 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys bool) {
 		}
 	}
 
-	header = "Peptide Sequence\tModified Sequence\tPeptide Length\tM/Z\tCharge\tObserved Mass\tProbability\tExpectation\tSpectral Count\tIntensity\tAssigned Modifications\tObserved Modifications\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
+	header = "Peptide Sequence\tModified Sequence\tPeptide Length\tM/Z\tCharge\tObserved Mass\tProbability\tExpectation\tSpectral Count\tIntensity\tAssigned Modifications\tObserved Modifications\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins\tProForma"
 
 	if brand == "tmt" {
 		switch channels {
@@ -161,6 +163,14 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys bool) {
 		}
 	}
 
+	// channel intensities from the spectra localized for each modification
+	var modLabels []map[string]iso.Labels
+	for _, i := range printSet {
+		modLabels = append(modLabels, i.ModLabels)
+	}
+	ptms := modifiedLabelKeys(modLabels...)
+	header += modifiedLabelsHeader(ptms, printSet[0].Labels, channels)
+
 	header += "\n"
 
 	// verify if the structure has labels, if so, replace the original channel names by them.
@@ -210,7 +220,7 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys bool) {
 		sort.Strings(assL)
 		sort.Strings(obs)
 
-		line := fmt.Sprintf("%s\t%s\t%d\t%.4f\t%d\t%.4f\t%.4f\t%.4f\t%d\t%.4f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+		line := fmt.Sprintf("%s\t%s\t%d\t%.4f\t%d\t%.4f\t%.4f\t%.4f\t%d\t%.4f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
 			i.Sequence,
 			i.ModifiedSequence,
 			len(i.Sequence),
@@ -230,6 +240,7 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys bool) {
 			i.ProteinDescription,
 			strings.Join(mappedGenes, ","),
 			strings.Join(mappedProteins, ","),
+			i.Modifications.ProForma(i.Sequence),
 		)
 
 		switch channels {
@@ -316,6 +327,8 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys bool) {
 			header += ""
 		}
 
+		line += modifiedLabelsLine(ptms, i.ModLabels, channels)
+
 		line += "\n"
 
 		_, e = io.WriteString(file, line)
@@ -6,7 +6,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"philosopher/lib/mod"
 	"philosopher/lib/msg"
 
 	"philosopher/lib/obo"
@@ -158,6 +161,82 @@ func (evi *Evidence) MapMods(hasObserved bool) {
 	return
 }
 
+// ResolveModifications maps the fixed and variable modifications on every layer to their UniMod
+// entries, each residue and mass combination is resolved once
+func (evi *Evidence) ResolveModifications() {
+
+	var tolerance = 0.01
+
+	o := obo.NewUniModOntology()
+
+	var resolved = make(map[string]obo.Term)
+	var unresolved = make(map[string]uint8)
+
+	resolve := func(mods map[string]mod.Modification) {
+		for k, v := range mods {
+
+			if v.Type != "Assigned" {
+				continue
+			}
+
+			key := fmt.Sprintf("%s#%.4f", v.AminoAcid, v.MassDiff)
+
+			t, ok := resolved[key]
+			if !ok {
+				if _, ok := unresolved[key]; ok {
+					continue
+				}
+
+				t, ok = o.Match(v.AminoAcid, v.MassDiff, tolerance)
+				if !ok {
+					unresolved[key] = 0
+					continue
+				}
+				resolved[key] = t
+			}
+
+			v.ID = t.ID
+			v.Name = t.Name
+			v.Definition = t.Definition
+			v.AverageMass = t.AverageMass
+			mods[k] = v
+		}
+	}
+
+	resolve(evi.Mods.Index)
+
+	for i := range evi.PSM {
+		resolve(evi.PSM[i].Modifications.Index)
+	}
+
+	for i := range evi.Ions {
+		resolve(evi.Ions[i].Modifications.Index)
+	}
+
+	for i := range evi.Peptides {
+		resolve(evi.Peptides[i].Modifications.Index)
+	}
+
+	for i := range evi.ModifiedPeptides {
+		resolve(evi.ModifiedPeptides[i].Modifications.Index)
+	}
+
+	for i := range evi.Proteins {
+		resolve(evi.Proteins[i].Modifications.Index)
+	}
+
+	if len(unresolved) > 0 {
+		var keys []string
+		for k := range unresolved {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		msg.Custom(fmt.Errorf("No UniMod entry found for the modifications %s", strings.Join(keys, ", ")), "warning")
+	}
+
+	return
+}
+
 // AssembleModificationReport cretaes the modifications lists
 func (evi *Evidence) AssembleModificationReport() {
 
@@ -0,0 +1,209 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/cla"
+	"philosopher/lib/id"
+	"philosopher/lib/mod"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+)
+
+// AssembleModifiedPeptideReport groups the PSMs by peptide modified form, all charge states together
+func (evi *Evidence) AssembleModifiedPeptideReport(pep id.PepIDList, decoyTag string) {
+
+	var list ModifiedPeptideEvidenceList
+	var decoyMap = make(map[string]bool)
+	var bestProb = make(map[string]float64)
+	var mpepMap = make(map[string]*ModifiedPeptideEvidence)
+
+	for _, i := range pep {
+		decoyMap[i.ModifiedForm] = cla.IsDecoyPSM(i, decoyTag)
+	}
+
+	for _, i := range evi.PSM {
+
+		key := i.ModifiedForm
+
+		// only the forms that passed the FDR are reported
+		isDecoy, ok := decoyMap[key]
+		if !ok {
+			continue
+		}
+
+		if i.Probability > bestProb[key] {
+			bestProb[key] = i.Probability
+		}
+
+		mp, ok := mpepMap[key]
+		if !ok {
+			mp = &ModifiedPeptideEvidence{
+				ModifiedSequence: key,
+				Sequence:         i.Peptide,
+				Protein:          i.Protein,
+				IsDecoy:          isDecoy,
+				Spectra:          make(map[string]uint8),
+				ChargeState:      make(map[uint8]uint8),
+				MappedGenes:      make(map[string]int),
+				MappedProteins:   make(map[string]int),
+			}
+			mp.Modifications.Index = make(map[string]mod.Modification)
+			mpepMap[key] = mp
+		}
+
+		mp.Spectra[i.Spectrum] = 0
+		mp.ChargeState[i.AssumedCharge] = 0
+		mp.Protein = i.Protein
+
+		// the intensity is the sum of all PSMs, the same used for the peptides by the label-free quantification
+		mp.Intensity += i.Intensity
+
+		for j := range i.MappedProteins {
+			mp.MappedProteins[j] = 0
+		}
+
+		for j := range i.MappedGenes {
+			mp.MappedGenes[j] = 0
+		}
+
+		for _, j := range i.Modifications.Index {
+			_, okMod := mp.Modifications.Index[j.Index]
+			if !okMod {
+				mp.Modifications.Index[j.Index] = j
+			}
+		}
+	}
+
+	for k, v := range mpepMap {
+		v.Probability = bestProb[k]
+		v.Spc = len(v.Spectra)
+		list = append(list, *v)
+	}
+
+	sort.Sort(list)
+	evi.ModifiedPeptides = list
+
+	return
+}
+
+// MetaModifiedPeptideReport reports the peptide modified forms
+func (evi Evidence) MetaModifiedPeptideReport(brand string, channels int, hasDecoys bool) {
+
+	output := fmt.Sprintf("%s%smodified_peptide.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("modified peptide output file"), "fatal")
+	}
+	defer file.Close()
+
+	// building the printing set tat may or not contain decoys
+	var printSet ModifiedPeptideEvidenceList
+	for _, i := range evi.ModifiedPeptides {
+		// forms without surviving spectra are left out, the same as on the peptide report
+		if i.Probability > 0 {
+			if hasDecoys == false && i.IsDecoy == true {
+				continue
+			}
+			printSet = append(printSet, i)
+		}
+	}
+
+	header := "Modified Peptide\tPeptide\tPeptide Length\tCharges\tProbability\tSpectral Count\tIntensity\tAssigned Modifications\tObserved Modifications\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
+
+	// the channel names are taken from the first quantified modified form
+	if len(brand) > 0 {
+		for _, i := range printSet {
+			if len(i.Labels.Channel1.Name) > 0 {
+				labelNames := labelsToNames(i.Labels)
+				for j := 0; j < channels && j < len(labelNames); j++ {
+					header += "\t" + labelNames[j]
+				}
+				break
+			}
+		}
+	}
+
+	header += "\n"
+
+	_, e = io.WriteString(file, header)
+	if e != nil {
+		msg.WriteToFile(errors.New("Cannot print Modified Peptides to file"), "fatal")
+	}
+
+	for _, i := range printSet {
+
+		assL, obs := getModsList(i.Modifications.Index)
+
+		var mappedProteins []string
+		for j := range i.MappedProteins {
+			if j != i.Protein {
+				mappedProteins = append(mappedProteins, j)
+			}
+		}
+
+		var cs []string
+		for j := range i.ChargeState {
+			cs = append(cs, strconv.Itoa(int(j)))
+		}
+
+		var mappedGenes []string
+		for j := range i.MappedGenes {
+			if j != i.GeneName && len(j) > 0 {
+				mappedGenes = append(mappedGenes, j)
+			}
+		}
+
+		sort.Strings(mappedGenes)
+		sort.Strings(mappedProteins)
+		sort.Strings(assL)
+		sort.Strings(obs)
+		sort.Strings(cs)
+
+		line := fmt.Sprintf("%s\t%s\t%d\t%s\t%.4f\t%d\t%f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+			i.ModifiedSequence,
+			i.Sequence,
+			len(i.Sequence),
+			strings.Join(cs, ", "),
+			i.Probability,
+			i.Spc,
+			i.Intensity,
+			strings.Join(assL, ", "),
+			strings.Join(obs, ", "),
+			i.Protein,
+			i.ProteinID,
+			i.EntryName,
+			i.GeneName,
+			i.ProteinDescription,
+			strings.Join(mappedGenes, ", "),
+			strings.Join(mappedProteins, ", "),
+		)
+
+		if len(brand) > 0 {
+			intensities := labelsToIntensities(i.Labels)
+			for j := 0; j < channels && j < len(intensities); j++ {
+				line = fmt.Sprintf("%s\t%.4f", line, intensities[j])
+			}
+		}
+
+		line += "\n"
+
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(errors.New("Cannot print Modified Peptides to file"), "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
@@ -0,0 +1,71 @@
+package rep
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/id"
+)
+
+func TestAssembleModifiedPeptideReport(t *testing.T) {
+
+	unmodified := "PEPTIDEK"
+	acetyl := "PEPTIDEK[+42.0106]"
+	trimethyl := "PEPTIDEK[+42.0470]"
+	decoy := "EDITPEPK"
+
+	// only the forms on the identification list passed the modified peptide FDR
+	pep := id.PepIDList{
+		{ModifiedForm: unmodified, Protein: "sp|P1|A"},
+		{ModifiedForm: acetyl, Protein: "sp|P1|A"},
+		{ModifiedForm: decoy, Protein: "rev_sp|P2|B"},
+	}
+
+	var evi Evidence
+	evi.PSM = PSMEvidenceList{
+		{Spectrum: "run1.100.100.2", Peptide: "PEPTIDEK", ModifiedForm: unmodified, AssumedCharge: 2, Probability: 0.90, Intensity: 100, Protein: "sp|P1|A"},
+		{Spectrum: "run1.101.101.3", Peptide: "PEPTIDEK", ModifiedForm: unmodified, AssumedCharge: 3, Probability: 0.99, Intensity: 50, Protein: "sp|P1|A"},
+		{Spectrum: "run1.102.102.2", Peptide: "PEPTIDEK", ModifiedForm: acetyl, AssumedCharge: 2, Probability: 0.80, Intensity: 30, Protein: "sp|P1|A"},
+		{Spectrum: "run1.103.103.2", Peptide: "PEPTIDEK", ModifiedForm: trimethyl, AssumedCharge: 2, Probability: 0.999, Intensity: 70, Protein: "sp|P1|A"},
+		{Spectrum: "run1.104.104.2", Peptide: "EDITPEPK", ModifiedForm: decoy, AssumedCharge: 2, Probability: 0.70, Intensity: 10, Protein: "rev_sp|P2|B"},
+	}
+
+	evi.AssembleModifiedPeptideReport(pep, "rev_")
+
+	var forms = make(map[string]ModifiedPeptideEvidence)
+	for _, i := range evi.ModifiedPeptides {
+		forms[i.ModifiedSequence] = i
+	}
+
+	// the forms with the same nominal mass are not merged and the rejected form is left out
+	if len(forms) != 3 {
+		t.Fatalf("AssembleModifiedPeptideReport() = %d forms, want 3", len(forms))
+	}
+
+	if _, ok := forms[trimethyl]; ok {
+		t.Errorf("the form rejected by the FDR is reported")
+	}
+
+	tests := []struct {
+		form        string
+		spc         int
+		charges     int
+		intensity   float64
+		probability float64
+		isDecoy     bool
+	}{
+		{unmodified, 2, 2, 150, 0.99, false},
+		{acetyl, 1, 1, 30, 0.80, false},
+		{decoy, 1, 1, 10, 0.70, true},
+	}
+
+	for _, tt := range tests {
+		i := forms[tt.form]
+		if i.Spc != tt.spc || len(i.ChargeState) != tt.charges || math.Abs(i.Intensity-tt.intensity) > 1e-9 {
+			t.Errorf("%s: spc = %d, charges = %v, intensity = %v", tt.form, i.Spc, i.ChargeState, i.Intensity)
+		}
+		if i.Probability != tt.probability || i.IsDecoy != tt.isDecoy {
+			t.Errorf("%s: probability = %v, decoy = %v", tt.form, i.Probability, i.IsDecoy)
+		}
+	}
+}
@@ -12,7 +12,7 @@ import (
 )
 
 // MzIdentMLReport creates a MzIdentML structure to be encoded
-func (e Evidence) MzIdentMLReport(version, database string) {
+func (e Evidence) MzIdentMLReport(version, database, hash string) {
 
 	var mzid psi.MzIdentML
 
@@ -213,27 +213,46 @@ func (e Evidence) MzIdentMLReport(version, database string) {
 		}
 
 		for _, j := range i.Modifications.Index {
-			if j.Name != "Unknown" {
-				mod := psi.Modification{
-					AvgMassDelta:          j.AverageMass,
-					MonoIsotopicMassDelta: j.MonoIsotopicMass,
-					Residues:              j.AminoAcid,
-					Location:              j.Position,
-					CVParam: []psi.CVParam{
-						{
-							CVRef:     "UNIMOD",
-							Accession: j.ID,
-							Name:      j.Name,
-						},
+
+			if j.Type == "Observed" || j.MassDiff == 0 {
+				continue
+			}
+
+			mod := psi.Modification{
+				AvgMassDelta:          j.AverageMass,
+				MonoIsotopicMassDelta: j.MassDiff,
+				Residues:              j.AminoAcid,
+				Location:              j.Position,
+			}
+
+			if j.IsResolved() {
+				mod.CVParam = []psi.CVParam{
+					{
+						CVRef:     "UNIMOD",
+						Accession: j.ID,
+						Name:      j.Name,
 					},
 				}
-
-				if mod.Residues == "N-term" {
-					mod.Residues = ""
+			} else {
+				mod.CVParam = []psi.CVParam{
+					{
+						CVRef:     "PSI-MS",
+						Accession: "MS:1001460",
+						Name:      "unknown modification",
+					},
 				}
+			}
 
-				p.Modification = append(p.Modification, mod)
+			// terminal modifications are located before the first and after the last residue
+			if strings.EqualFold(mod.Residues, "N-term") {
+				mod.Residues = ""
+				mod.Location = "0"
+			} else if strings.EqualFold(mod.Residues, "C-term") {
+				mod.Residues = ""
+				mod.Location = strconv.Itoa(len(i.Sequence) + 1)
 			}
+
+			p.Modification = append(p.Modification, mod)
 		}
 
 		peps = append(peps, p)
@@ -681,6 +700,17 @@ func (e Evidence) MzIdentMLReport(version, database string) {
 			},
 		},
 	}
+
+	// the database checksum is reported as a cvParam, the version attribute is meant for the database release
+	if len(hash) > 0 {
+		sdb.CVParam = append(sdb.CVParam, psi.CVParam{
+			CVRef:     "PSI-MS",
+			Accession: "MS:1003151",
+			Name:      "SHA-256",
+			Value:     hash,
+		})
+	}
+
 	mzid.DataCollection.Inputs.SearchDatabase = append(mzid.DataCollection.Inputs.SearchDatabase, *sdb)
 
 	// DataCollection - Input - SpectraData
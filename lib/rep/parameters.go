@@ -1,7 +1,16 @@
 package rep
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
 	"philosopher/lib/spc"
+	"philosopher/lib/sys"
 )
 
 // AssembleSearchParameters organizes the aprameters defined by the search engine
@@ -165,3 +174,46 @@ func (e *Evidence) AssembleSearchParameters(params []spc.Parameter) {
 
 	return
 }
+
+// MetadataReport writes the program version and the database used for the analysis, the database
+// checksum allows the results to be traced back to the exact sequences
+func (e *Evidence) MetadataReport(version string, db met.Database) {
+
+	output := fmt.Sprintf("%s%smetadata.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	file, err := os.Create(output)
+	if err != nil {
+		msg.WriteFile(errors.New("Cannot create metadata report"), "error")
+	}
+	defer file.Close()
+
+	database := db.Annot
+	if len(database) == 0 {
+		database = db.ID
+	}
+
+	lines := [][2]string{
+		{"Philosopher Version", version},
+		{"Database", database},
+		{"Database Timestamp", db.TimeStamp},
+		{"Database Checksum (SHA-256)", db.Hash},
+		{"Search Database", e.Parameters.DatabaseName},
+	}
+
+	_, err = io.WriteString(file, "Key\tValue\n")
+	if err != nil {
+		msg.WriteToFile(err, "fatal")
+	}
+
+	for _, i := range lines {
+		_, err = io.WriteString(file, fmt.Sprintf("%s\t%s\n", i[0], i[1]))
+		if err != nil {
+			msg.WriteToFile(err, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
@@ -12,6 +12,7 @@ import (
 
 	"philosopher/lib/cla"
 	"philosopher/lib/id"
+	"philosopher/lib/iso"
 	"philosopher/lib/mod"
 	"philosopher/lib/msg"
 	"philosopher/lib/sys"
@@ -160,6 +161,10 @@ func (evi Evidence) MetaPeptideReport(brand string, channels int, hasDecoys bool
 
 	header = "Peptide\tPeptide Length\tCharges\tProbability\tSpectral Count\tIntensity\tAssigned Modifications\tObserved Modifications\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
 
+	if evi.Digested == true {
+		header += "\tIs Proteotypic"
+	}
+
 	if brand == "tmt" {
 		switch channels {
 		case 6:
@@ -184,6 +189,14 @@ func (evi Evidence) MetaPeptideReport(brand string, channels int, hasDecoys bool
 		}
 	}
 
+	// channel intensities from the spectra localized for each modification
+	var modLabels []map[string]iso.Labels
+	for _, i := range printSet {
+		modLabels = append(modLabels, i.ModLabels)
+	}
+	ptms := modifiedLabelKeys(modLabels...)
+	header += modifiedLabelsHeader(ptms, printSet[0].Labels, channels)
+
 	header += "\n"
 
 	// verify if the structure has labels, if so, replace the original channel names by them.
@@ -257,6 +270,10 @@ func (evi Evidence) MetaPeptideReport(brand string, channels int, hasDecoys bool
 			strings.Join(mappedProteins, ", "),
 		)
 
+		if evi.Digested == true {
+			line = fmt.Sprintf("%s\t%t", line, i.IsProteotypic)
+		}
+
 		switch channels {
 		case 4:
 			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f",
@@ -341,6 +358,8 @@ func (evi Evidence) MetaPeptideReport(brand string, channels int, hasDecoys bool
 			header += ""
 		}
 
+		line += modifiedLabelsLine(ptms, i.ModLabels, channels)
+
 		line += "\n"
 
 		_, e = io.WriteString(file, line)
@@ -12,8 +12,10 @@ import (
 
 	"philosopher/lib/dat"
 	"philosopher/lib/id"
+	"philosopher/lib/iso"
 	"philosopher/lib/mod"
 	"philosopher/lib/msg"
+	"philosopher/lib/prf"
 	"philosopher/lib/sys"
 )
 
@@ -64,7 +66,7 @@ func (evi *Evidence) AssembleProteinReport(pro id.ProtIDList, weight float64, de
 
 		for _, k := range i.PeptideIons {
 
-			ion := fmt.Sprintf("%s#%d#%.4f", k.PeptideSequence, k.Charge, k.CalcNeutralPepMass)
+			ion := prf.IonKey(k.PeptideSequence, k.Charge, k.CalcNeutralPepMass)
 
 			v, ok := evidenceIons[ion]
 			if ok {
@@ -255,6 +257,10 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 
 	header = fmt.Sprintf("Group\tSubGroup\tProtein\tProtein ID\tEntry Name\tGene\tLength\tPercent Coverage\tOrganism\tProtein Description\tProtein Existence\tProtein Probability\tTop Peptide Probability\tStripped Peptides\tTotal Peptide Ions\tUnique Peptide Ions\tRazor Peptide Ions\tTotal Spectral Count\tUnique Spectral Count\tRazor Spectral Count\tTotal Intensity\tUnique Intensity\tRazor Intensity\tRazor Assigned Modifications\tRazor Observed Modifications\tIndistinguishable Proteins")
 
+	if evi.Digested == true {
+		header += "\tTheoretical Peptides"
+	}
+
 	if brand == "tmt" {
 		switch channels {
 		case 6:
@@ -279,6 +285,14 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 		}
 	}
 
+	// channel intensities from the spectra localized for each modification
+	var modLabels []map[string]iso.Labels
+	for _, i := range printSet {
+		modLabels = append(modLabels, i.ModUniqueLabels, i.ModURazorLabels)
+	}
+	ptms := modifiedLabelKeys(modLabels...)
+	header += modifiedLabelsHeader(ptms, printSet[0].UniqueLabels, channels)
+
 	header += "\n"
 
 	// verify if the structure has labels, if so, replace the original channel names by them.
@@ -403,6 +417,10 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 			strings.Join(ip, ", "),   // Indistinguishable Proteins
 		)
 
+		if evi.Digested == true {
+			line = fmt.Sprintf("%s\t%d", line, i.TheoreticalPeptides)
+		}
+
 		switch channels {
 		case 4:
 			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f",
@@ -487,6 +505,12 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 			header += ""
 		}
 
+		modLabels := i.ModURazorLabels
+		if uniqueOnly == true || hasRazor == false {
+			modLabels = i.ModUniqueLabels
+		}
+		line += modifiedLabelsLine(ptms, modLabels, channels)
+
 		line += "\n"
 
 		_, e = io.WriteString(file, line)
@@ -15,6 +15,8 @@ import (
 	"philosopher/lib/cla"
 	"philosopher/lib/dat"
 	"philosopher/lib/id"
+	"philosopher/lib/mod"
+	"philosopher/lib/prf"
 	"philosopher/lib/sys"
 )
 
@@ -29,9 +31,13 @@ func (evi *Evidence) AssemblePSMReport(pep id.PepIDList, decoyTag string) {
 
 	var genes = make(map[string]string)
 	var ptid = make(map[string]string)
+	var variants = make(map[string]dat.Record)
 	for _, j := range dtb.Records {
 		genes[j.PartHeader] = j.GeneNames
 		ptid[j.PartHeader] = j.ID
+		if len(j.Variant) > 0 && !j.IsDecoy {
+			variants[j.PartHeader] = j
+		}
 	}
 
 	for _, i := range pep {
@@ -49,9 +55,10 @@ func (evi *Evidence) AssemblePSMReport(pep id.PepIDList, decoyTag string) {
 		p.NumberOfEnzymaticTermini = int(i.NumberOfEnzymaticTermini)
 		p.NumberOfMissedCleavages = i.NumberofMissedCleavages
 		p.Peptide = i.Peptide
-		p.IonForm = fmt.Sprintf("%s#%d#%.4f", i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
+		p.IonForm = prf.IonKey(i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
 		p.Protein = i.Protein
 		p.ModifiedPeptide = i.ModifiedPeptide
+		p.ModifiedForm = i.ModifiedForm
 		p.AssumedCharge = i.AssumedCharge
 		p.HitRank = i.HitRank
 		p.PrecursorExpMass = i.PrecursorExpMass
@@ -73,6 +80,9 @@ func (evi *Evidence) AssemblePSMReport(pep id.PepIDList, decoyTag string) {
 		p.MappedGenes = make(map[string]int)
 		p.MappedProteins = make(map[string]int)
 		p.Modifications = i.Modifications
+		p.SearchEngines = i.SearchEngines
+		p.ConflictingEngines = i.ConflictingEngines
+		p.IsChimeric = i.IsChimeric
 
 		if i.UncalibratedPrecursorNeutralMass > 0 {
 			p.PrecursorNeutralMass = i.PrecursorNeutralMass
@@ -107,6 +117,10 @@ func (evi *Evidence) AssemblePSMReport(pep id.PepIDList, decoyTag string) {
 			p.IsUnique = false
 		}
 
+		if len(variants) > 0 {
+			p.Variant = variantSites(i.Peptide, append([]string{i.Protein}, i.AlternativeProteins...), variants)
+		}
+
 		list = append(list, p)
 	}
 
@@ -116,8 +130,59 @@ func (evi *Evidence) AssemblePSMReport(pep id.PepIDList, decoyTag string) {
 	return
 }
 
+// variantSites lists the variant entries where the peptide covers the changed residues
+func variantSites(peptide string, proteins []string, variants map[string]dat.Record) string {
+
+	var sites = make(map[string]uint8)
+
+	for _, i := range proteins {
+
+		rec, ok := variants[i]
+		if !ok {
+			continue
+		}
+
+		// the peptide may appear more than once on the protein
+		offset := 0
+		for {
+			idx := strings.Index(rec.Sequence[offset:], peptide)
+			if idx < 0 {
+				break
+			}
+
+			start := offset + idx + 1
+			if rec.SpansVariant(start, start+len(peptide)-1) {
+				sites[rec.ID] = 0
+				break
+			}
+
+			offset += idx + 1
+		}
+	}
+
+	var list []string
+	for k := range sites {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+
+	return strings.Join(list, ", ")
+}
+
+// PSMReportOptions defines the optional columns and the label channels on the PSM report
+type PSMReportOptions struct {
+	Brand       string
+	Channels    int
+	HasDecoys   bool
+	IsComet     bool
+	PTMs        []string
+	IsChimeric  bool
+	HasVariants bool
+	HasGlycans  bool
+}
+
 // MetaPSMReport report all psms from study that passed the FDR filter
-func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet, hasLoc bool) {
+func (evi Evidence) MetaPSMReport(o PSMReportOptions) {
 
 	var header string
 	output := fmt.Sprintf("%s%spsm.tsv", sys.MetaDir(), string(filepath.Separator))
@@ -136,7 +201,7 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 		compositeName := strings.Split(evi.PSM[i].Spectrum, "#")
 		evi.PSM[i].Spectrum = compositeName[0]
 
-		if hasDecoys == false {
+		if o.HasDecoys == false {
 			if evi.PSM[i].IsDecoy == false {
 				printSet = append(printSet, evi.PSM[i])
 			}
@@ -147,20 +212,37 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 
 	header = "Spectrum\tSpectrum File\tPeptide\tModified Peptide\tPeptide Length\tCharge\tRetention\tObserved Mass\tCalibrated Observed Mass\tObserved M/Z\tCalibrated Observed M/Z\tCalculated Peptide Mass\tCalculated M/Z\tDelta Mass"
 
-	if isComet == true {
+	if o.IsComet == true {
 		header += "\tXCorr\tDeltaCN\tDeltaCNStar\tSPScore\tSPRank"
 	}
 
 	header += "\tExpectation\tHyperscore\tNextscore\tPeptideProphet Probability\tNumber of Enzymatic Termini\tNumber of Missed Cleavages\tIntensity\tIon Mobility\tAssigned Modifications\tObserved Modifications"
 
-	if hasLoc == true {
-		header += "\tNumber of Phospho Sites\tPhospho Site Localization"
+	hasShifts := len(evi.Modifications.MassShifts) > 0
+	if hasShifts == true {
+		header += "\tMass Shift Annotation"
+	}
+
+	for _, i := range o.PTMs {
+		header += fmt.Sprintf("\tNumber of %s Sites\t%s Site Localization", mod.PTMName(i), mod.PTMName(i))
 	}
 
-	header += "\tIs Unique\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
+	if o.IsChimeric == true {
+		header += "\tHit Rank\tIs Chimeric"
+	}
+
+	if o.HasVariants == true {
+		header += "\tVariant"
+	}
+
+	if o.HasGlycans == true {
+		header += "\tGlycan Composition\tGlycosite\tOxonium Ions"
+	}
+
+	header += "\tIs Unique\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins\tProForma"
 
-	if brand == "tmt" {
-		switch channels {
+	if o.Brand == "tmt" {
+		switch o.Channels {
 		case 6:
 			header += "\tIs Used\tPurity\tChannel 126\tChannel 127N\tChannel 128C\tChannel 129N\tChannel 130C\tChannel 131"
 		case 10:
@@ -172,8 +254,8 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 		default:
 			header += ""
 		}
-	} else if brand == "itraq" {
-		switch channels {
+	} else if o.Brand == "itraq" {
+		switch o.Channels {
 		case 4:
 			header += "\tIs Used\tPurity\tChannel 114\tChannel 115\tChannel 116\tChannel 117"
 		case 8:
@@ -249,7 +331,7 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			i.Massdiff,
 		)
 
-		if isComet == true {
+		if o.IsComet == true {
 			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
 				line,
 				i.Xcorr,
@@ -274,15 +356,46 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			strings.Join(obs, ", "),
 		)
 
-		if hasLoc == true {
+		if hasShifts == true {
+			line = fmt.Sprintf("%s\t%s",
+				line,
+				i.MassShift,
+			)
+		}
+
+		for _, j := range o.PTMs {
 			line = fmt.Sprintf("%s\t%d\t%s",
 				line,
-				i.LocalizedPTMSites["STY:79.966331"],
-				i.LocalizedPTMMassDiff["STY:79.966331"],
+				i.LocalizedPTMSites[j],
+				i.LocalizedPTMMassDiff[j],
+			)
+		}
+
+		if o.IsChimeric == true {
+			line = fmt.Sprintf("%s\t%d\t%t",
+				line,
+				i.HitRank,
+				i.IsChimeric,
+			)
+		}
+
+		if o.HasVariants == true {
+			line = fmt.Sprintf("%s\t%s",
+				line,
+				i.Variant,
+			)
+		}
+
+		if o.HasGlycans == true {
+			line = fmt.Sprintf("%s\t%s\t%s\t%t",
+				line,
+				i.GlycanComposition,
+				i.Glycosites,
+				i.HasOxonium,
 			)
 		}
 
-		line = fmt.Sprintf("%s\t%t\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+		line = fmt.Sprintf("%s\t%t\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
 			line,
 			i.IsUnique,
 			i.Protein,
@@ -292,9 +405,10 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			i.ProteinDescription,
 			strings.Join(mappedGenes, ", "),
 			strings.Join(mappedProteins, ", "),
+			i.Modifications.ProForma(i.Peptide),
 		)
 
-		switch channels {
+		switch o.Channels {
 		case 4:
 			line = fmt.Sprintf("%s\t%t\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
 				line,
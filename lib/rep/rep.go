@@ -14,16 +14,21 @@ import (
 
 // Evidence ...
 type Evidence struct {
-	Decoys          bool
-	Parameters      SearchParametersEvidence
-	PSM             PSMEvidenceList
-	Ions            IonEvidenceList
-	Peptides        PeptideEvidenceList
-	Proteins        ProteinEvidenceList
-	Mods            mod.Modifications
-	Modifications   ModificationEvidence
-	CombinedProtein CombinedProteinEvidenceList
-	CombinedPeptide CombinedPeptideEvidenceList
+	Decoys           bool
+	Digested         bool
+	Parameters       SearchParametersEvidence
+	PSM              PSMEvidenceList
+	Ions             IonEvidenceList
+	Peptides         PeptideEvidenceList
+	ModifiedPeptides ModifiedPeptideEvidenceList
+	Proteins         ProteinEvidenceList
+	Mods             mod.Modifications
+	Modifications    ModificationEvidence
+	CombinedProtein  CombinedProteinEvidenceList
+	CombinedPeptide  CombinedPeptideEvidenceList
+	Genes            GeneEvidenceList
+	Crosslinks       CrosslinkEvidenceList
+	ResiduePairs     ResiduePairEvidenceList
 }
 
 // SearchParametersEvidence ...
@@ -125,6 +130,7 @@ type PSMEvidence struct {
 	EntryName                        string
 	GeneName                         string
 	ModifiedPeptide                  string
+	ModifiedForm                     string
 	MappedProteins                   map[string]int
 	MappedGenes                      map[string]int
 	AssumedCharge                    uint8
@@ -154,6 +160,14 @@ type PSMEvidence struct {
 	IsDecoy                          bool
 	IsUnique                         bool
 	IsURazor                         bool
+	SearchEngines                    []string
+	ConflictingEngines               []string
+	IsChimeric                       bool
+	Variant                          string
+	MassShift                        string
+	GlycanComposition                string
+	Glycosites                       string
+	HasOxonium                       bool
 	Labels                           iso.Labels
 	Modifications                    mod.Modifications
 }
@@ -194,7 +208,7 @@ type IonEvidence struct {
 	EntryName                string
 	ProteinDescription       string
 	Labels                   iso.Labels
-	PhosphoLabels            iso.Labels
+	ModLabels                map[string]iso.Labels
 	Modifications            mod.Modifications
 }
 
@@ -222,9 +236,10 @@ type PeptideEvidence struct {
 	Probability            float64
 	ModifiedObservations   int
 	UnModifiedObservations int
+	IsProteotypic          bool
 	IsDecoy                bool
 	Labels                 iso.Labels
-	PhosphoLabels          iso.Labels
+	ModLabels              map[string]iso.Labels
 	Modifications          mod.Modifications
 }
 
@@ -235,6 +250,36 @@ func (a PeptideEvidenceList) Len() int           { return len(a) }
 func (a PeptideEvidenceList) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a PeptideEvidenceList) Less(i, j int) bool { return a[i].Sequence < a[j].Sequence }
 
+// ModifiedPeptideEvidence groups all valid info about a modified form of a peptide
+type ModifiedPeptideEvidence struct {
+	ModifiedSequence   string
+	Sequence           string
+	ChargeState        map[uint8]uint8
+	Spectra            map[string]uint8
+	Protein            string
+	ProteinID          string
+	GeneName           string
+	EntryName          string
+	ProteinDescription string
+	MappedProteins     map[string]int
+	MappedGenes        map[string]int
+	Spc                int
+	Intensity          float64
+	Probability        float64
+	IsDecoy            bool
+	Labels             iso.Labels
+	Modifications      mod.Modifications
+}
+
+// ModifiedPeptideEvidenceList ...
+type ModifiedPeptideEvidenceList []ModifiedPeptideEvidence
+
+func (a ModifiedPeptideEvidenceList) Len() int      { return len(a) }
+func (a ModifiedPeptideEvidenceList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ModifiedPeptideEvidenceList) Less(i, j int) bool {
+	return a[i].ModifiedSequence < a[j].ModifiedSequence
+}
+
 // ProteinEvidence ...
 type ProteinEvidence struct {
 	OriginalHeader         string
@@ -263,14 +308,14 @@ type ProteinEvidence struct {
 	URazorIntensity        float64 // Unique + razor
 	Probability            float64
 	TopPepProb             float64
+	TheoreticalPeptides    int
 	IsDecoy                bool
 	IsContaminant          bool
 	TotalLabels            iso.Labels
 	UniqueLabels           iso.Labels
 	URazorLabels           iso.Labels // Unique + razor
-	PhosphoTotalLabels     iso.Labels
-	PhosphoUniqueLabels    iso.Labels
-	PhosphoURazorLabels    iso.Labels // Unique + razor
+	ModUniqueLabels        map[string]iso.Labels
+	ModURazorLabels        map[string]iso.Labels // Unique + razor
 	Modifications          mod.Modifications
 }
 
@@ -281,6 +326,32 @@ func (a ProteinEvidenceList) Len() int           { return len(a) }
 func (a ProteinEvidenceList) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a ProteinEvidenceList) Less(i, j int) bool { return a[i].ProteinGroup < a[j].ProteinGroup }
 
+// GeneEvidence groups all protein entries and isoforms that share the same gene
+type GeneEvidence struct {
+	GeneName          string
+	Proteins          map[string]uint8
+	PeptideIons       map[string]bool // ion form and its uniqueness to the gene
+	StrippedPeptides  map[string]uint8
+	SupportingSpectra map[string]int
+	UniqueSpectra     map[string]int
+	Probability       float64
+	TopPepProb        float64
+	TotalSpC          int
+	UniqueSpC         int
+	TotalIntensity    float64
+	UniqueIntensity   float64
+	IsDecoy           bool
+	TotalLabels       iso.Labels
+	UniqueLabels      iso.Labels
+}
+
+// GeneEvidenceList list
+type GeneEvidenceList []GeneEvidence
+
+func (a GeneEvidenceList) Len() int           { return len(a) }
+func (a GeneEvidenceList) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a GeneEvidenceList) Less(i, j int) bool { return a[i].GeneName < a[j].GeneName }
+
 // CombinedProteinEvidence represents all combined proteins detected
 type CombinedProteinEvidence struct {
 	GroupNumber            uint32
@@ -344,7 +415,8 @@ func (a CombinedPeptideEvidenceList) Less(i, j int) bool { return a[i].Sequence
 
 // ModificationEvidence represents the list of modifications and the mod bins
 type ModificationEvidence struct {
-	MassBins []MassBin
+	MassBins   []MassBin
+	MassShifts []MassShift
 }
 
 // MassBin represents each bin from the mass distribution
@@ -373,8 +445,12 @@ func Run(m met.Data) {
 	var repo = New()
 	repo.RestoreGranular()
 
+	// the theoretical peptides from the in-silico digestion are reported when the database was digested
+	repo.UpdateLayersWithDigestion()
+
 	var isComet bool
 	var hasLoc bool
+	var isChimeric bool
 	var isoBrand string
 	var isoChannels int
 
@@ -386,6 +462,10 @@ func Run(m met.Data) {
 		hasLoc = true
 	}
 
+	if m.Filter.Ranks > 1 {
+		isChimeric = true
+	}
+
 	if m.Quantify.Brand == "tmt" {
 		isoBrand = "tmt"
 	} else if m.Quantify.Brand == "itraq" {
@@ -405,8 +485,33 @@ func Run(m met.Data) {
 
 	logrus.Info("Creating reports")
 
+	if len(m.Database.Hash) > 0 {
+		logrus.Info("Database checksum (SHA-256) ", m.Database.Hash)
+	}
+
+	// Metadata
+	repo.MetadataReport(m.Version, m.Database)
+
 	// PSM
-	repo.MetaPSMReport(isoBrand, isoChannels, m.Report.Decoys, isComet, hasLoc)
+	hasVariants := len(m.Database.Variants) > 0
+
+	var ptms []string
+	if hasLoc == true {
+		ptms = repo.LocalizedModifications(m.PTMProphet.Mods)
+	}
+
+	hasGlycans := len(m.Filter.Glycan) > 0
+
+	repo.MetaPSMReport(PSMReportOptions{
+		Brand:       isoBrand,
+		Channels:    isoChannels,
+		HasDecoys:   m.Report.Decoys,
+		IsComet:     isComet,
+		PTMs:        ptms,
+		IsChimeric:  isChimeric,
+		HasVariants: hasVariants,
+		HasGlycans:  hasGlycans,
+	})
 
 	// Ion
 	repo.MetaIonReport(isoBrand, isoChannels, m.Report.Decoys)
@@ -414,16 +519,30 @@ func Run(m met.Data) {
 	// Peptide
 	repo.MetaPeptideReport(isoBrand, isoChannels, m.Report.Decoys)
 
+	// Modified Peptide
+	if len(repo.ModifiedPeptides) > 0 {
+		repo.MetaModifiedPeptideReport(isoBrand, isoChannels, m.Report.Decoys)
+	}
+
 	// Protein
 	if len(m.Filter.Pox) > 0 || m.Filter.Inference == true {
 		repo.MetaProteinReport(isoBrand, isoChannels, m.Report.Decoys, m.Filter.Razor, m.Quantify.Unique)
 		repo.ProteinFastaReport(m.Report.Decoys)
 	}
 
+	// Gene
+	if len(repo.Genes) > 0 {
+		repo.MetaGeneReport(isoBrand, isoChannels, m.Report.Decoys, m.Quantify.Unique)
+	}
+
 	// Modifications
 	if len(repo.Modifications.MassBins) > 0 {
 		repo.ModificationReport()
 
+		if len(repo.Modifications.MassShifts) > 0 {
+			repo.MassShiftReport()
+		}
+
 		if m.PTMProphet.InputFiles != nil || len(m.PTMProphet.InputFiles) > 0 {
 			repo.PSMLocalizationReport(m.Filter.Tag, m.Filter.Razor, m.Report.Decoys)
 		}
@@ -431,6 +550,22 @@ func Run(m met.Data) {
 		repo.PlotMassHist()
 	}
 
+	// Modification sites
+	if hasLoc == true {
+		repo.SiteReport(isoBrand, isoChannels, m.Report.Decoys, ptms)
+	}
+
+	// Crosslinks
+	if len(repo.Crosslinks) > 0 {
+		repo.CrosslinkReport(m.Report.Decoys)
+		repo.ResiduePairReport(m.Report.Decoys)
+	}
+
+	// Search engines
+	if len(m.Filter.Combine) > 0 {
+		repo.SearchEngineReport(m.Report.Decoys)
+	}
+
 	// MSstats
 	if m.Report.MSstats == true {
 		repo.MetaMSstatsReport(isoBrand, isoChannels, m.Report.Decoys)
@@ -438,7 +573,7 @@ func Run(m met.Data) {
 
 	// MzID
 	if m.Report.MZID == true {
-		repo.MzIdentMLReport(m.Version, m.Database.Annot)
+		repo.MzIdentMLReport(m.Version, m.Database.Annot, m.Database.Hash)
 	}
 
 	return
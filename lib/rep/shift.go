@@ -0,0 +1,433 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/msg"
+	"philosopher/lib/obo"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// shiftTolerance is the mass tolerance in Da used to annotate the mass shift peaks
+	shiftTolerance = 0.02
+
+	// minShiftPSMs is the minimum number of PSMs on a histogram peak
+	minShiftPSMs = 5
+
+	// maxShiftAnnotations is the number of annotations reported for each peak
+	maxShiftAnnotations = 5
+)
+
+// MassShift is a peak from the delta mass histogram of an open search
+type MassShift struct {
+	Mass        float64
+	LowerMass   float64
+	HigherMass  float64
+	PSMs        int
+	Peptides    int
+	Annotations []string
+	Enrichment  []ResidueEnrichment
+}
+
+// ResidueEnrichment is the ratio between the residue frequency on a mass shift and on all PSMs
+type ResidueEnrichment struct {
+	Residue string
+	Ratio   float64
+}
+
+// shiftAnnotation is a candidate explanation for a mass shift
+type shiftAnnotation struct {
+	Name  string
+	Error float64
+}
+
+// AssembleMassShifts detects the peaks on the delta mass histogram, annotates them with UniMod
+// entries, their pairwise combinations and isotope errors, and scores the residues carrying them
+func (evi *Evidence) AssembleMassShifts() {
+
+	bins := evi.Modifications.MassBins
+	if len(bins) == 0 {
+		return
+	}
+
+	// the unmodified peak is used to correct the systematic mass error
+	var zeroBinMassDeviation float64
+	for _, i := range bins {
+		if i.MassCenter == 0 {
+			zeroBinMassDeviation = i.AverageMass
+		}
+	}
+
+	o := obo.NewUniModOntology()
+	terms := shiftTerms(o.Terms)
+
+	var psmIndex = make(map[string]int)
+	for i := range evi.PSM {
+		psmIndex[evi.PSM[i].Spectrum+"#"+evi.PSM[i].Peptide] = i
+	}
+
+	background := residueFrequencies(evi.PSM, 0, false)
+
+	var shifts []MassShift
+
+	peaks := shiftPeaks(bins, minShiftPSMs)
+	windows := shiftWindows(bins, peaks)
+
+	for n := range peaks {
+
+		w := windows[n]
+
+		var psms PSMEvidenceList
+		for _, i := range w {
+			psms = append(psms, bins[i].ObservedMods...)
+		}
+
+		var ms MassShift
+		var peptides = make(map[string]uint8)
+		var total float64
+
+		for _, i := range psms {
+			total += i.Massdiff - zeroBinMassDeviation
+			peptides[i.Peptide] = 0
+		}
+
+		ms.Mass = total / float64(len(psms))
+		ms.LowerMass = bins[w[0]].LowerMass
+		ms.HigherMass = bins[w[len(w)-1]].HigherRight
+		ms.PSMs = len(psms)
+		ms.Peptides = len(peptides)
+
+		for _, i := range annotateShift(ms.Mass, terms, shiftTolerance) {
+			ms.Annotations = append(ms.Annotations, i.Name)
+		}
+
+		ms.Enrichment = residueEnrichment(residueFrequencies(psms, ms.Mass, true), background)
+
+		label := fmt.Sprintf("%.4f", ms.Mass)
+		if len(ms.Annotations) > 0 {
+			label = fmt.Sprintf("%s %s", label, ms.Annotations[0])
+		}
+
+		for _, i := range psms {
+			idx, ok := psmIndex[i.Spectrum+"#"+i.Peptide]
+			if ok && len(evi.PSM[idx].MassShift) == 0 {
+				evi.PSM[idx].MassShift = label
+			}
+		}
+
+		shifts = append(shifts, ms)
+	}
+
+	evi.Modifications.MassShifts = shifts
+
+	logrus.Info("Found ", len(shifts), " mass shifts on the delta mass distribution")
+
+	return
+}
+
+// shiftPeaks returns the bins that are local maxima of the observed PSM counts, the unmodified
+// bin is not reported
+func shiftPeaks(bins []MassBin, minPSMs int) []int {
+
+	var peaks []int
+
+	for i := range bins {
+
+		n := len(bins[i].ObservedMods)
+		if n < minPSMs || bins[i].MassCenter == 0 {
+			continue
+		}
+
+		if i > 0 && len(bins[i-1].ObservedMods) >= n {
+			continue
+		}
+
+		if i < len(bins)-1 && len(bins[i+1].ObservedMods) > n {
+			continue
+		}
+
+		peaks = append(peaks, i)
+	}
+
+	return peaks
+}
+
+// shiftWindows returns the bins pooled on each peak, the peak and its neighbours. A bin between two
+// peaks is pooled only on the most populated one and the unmodified bin is never pooled
+func shiftWindows(bins []MassBin, peaks []int) [][]int {
+
+	var owner = make(map[int]int)
+	for n, p := range peaks {
+		owner[p] = n
+	}
+
+	for n, p := range peaks {
+		for _, i := range []int{p - 1, p + 1} {
+
+			if i < 0 || i >= len(bins) || bins[i].MassCenter == 0 {
+				continue
+			}
+
+			if o, ok := owner[i]; ok {
+				if peaks[o] == i || len(bins[peaks[o]].ObservedMods) >= len(bins[p].ObservedMods) {
+					continue
+				}
+			}
+
+			owner[i] = n
+		}
+	}
+
+	windows := make([][]int, len(peaks))
+	for i := range bins {
+		if n, ok := owner[i]; ok {
+			windows[n] = append(windows[n], i)
+		}
+	}
+
+	return windows
+}
+
+// shiftTerms removes the UniMod entries without mass and the duplicated names, and sorts them by mass
+func shiftTerms(terms []obo.Term) []obo.Term {
+
+	var list []obo.Term
+	var seen = make(map[string]uint8)
+
+	for _, i := range terms {
+		if i.MonoIsotopicMass == 0 || len(i.Name) == 0 {
+			continue
+		}
+		if _, ok := seen[i.Name]; ok {
+			continue
+		}
+		seen[i.Name] = 0
+		list = append(list, i)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].MonoIsotopicMass < list[j].MonoIsotopicMass })
+
+	return list
+}
+
+// annotateShift matches the mass against single UniMod entries, pairs of entries and both with
+// a +/- 1 Da isotope error, the terms must be sorted by mass
+func annotateShift(mass float64, terms []obo.Term, tol float64) []shiftAnnotation {
+
+	var candidates []shiftAnnotation
+
+	for _, iso := range []int{0, 1, -1} {
+
+		target := mass - float64(iso)*bio.C13
+
+		var suffix string
+		if iso != 0 {
+			suffix = fmt.Sprintf(" (%+d isotope)", iso)
+
+			if math.Abs(target) <= tol {
+				candidates = append(candidates, shiftAnnotation{Name: fmt.Sprintf("Isotope error (%+d)", iso), Error: math.Abs(target)})
+			}
+		}
+
+		// single modifications
+		for _, i := range termsInRange(terms, target-tol, target+tol) {
+			candidates = append(candidates, shiftAnnotation{
+				Name:  i.Name + suffix,
+				Error: math.Abs(target - i.MonoIsotopicMass),
+			})
+		}
+
+		// pairs of modifications, each pair is listed once
+		for i := range terms {
+			rest := target - terms[i].MonoIsotopicMass
+			for _, j := range termsInRange(terms, rest-tol, rest+tol) {
+				if j.MonoIsotopicMass < terms[i].MonoIsotopicMass || (j.MonoIsotopicMass == terms[i].MonoIsotopicMass && j.Name < terms[i].Name) {
+					continue
+				}
+				candidates = append(candidates, shiftAnnotation{
+					Name:  terms[i].Name + " + " + j.Name + suffix,
+					Error: math.Abs(rest - j.MonoIsotopicMass),
+				})
+			}
+		}
+	}
+
+	// single entries are preferred over combinations with the same error
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Error != candidates[j].Error {
+			return candidates[i].Error < candidates[j].Error
+		}
+		return strings.Count(candidates[i].Name, " + ") < strings.Count(candidates[j].Name, " + ")
+	})
+
+	if len(candidates) > maxShiftAnnotations {
+		candidates = candidates[:maxShiftAnnotations]
+	}
+
+	return candidates
+}
+
+// termsInRange returns the terms with masses inside the interval
+func termsInRange(terms []obo.Term, lower, higher float64) []obo.Term {
+
+	i := sort.Search(len(terms), func(i int) bool { return terms[i].MonoIsotopicMass >= lower })
+
+	var j = i
+	for j < len(terms) && terms[j].MonoIsotopicMass <= higher {
+		j++
+	}
+
+	return terms[i:j]
+}
+
+// residueFrequencies counts the residues of the PSMs, when localized the shift is distributed by the
+// site probabilities, otherwise every residue on the peptide has the same weight
+func residueFrequencies(psms PSMEvidenceList, mass float64, localized bool) map[string]float64 {
+
+	var freq = make(map[string]float64)
+
+	for _, i := range psms {
+
+		if i.IsDecoy || len(i.Peptide) == 0 {
+			continue
+		}
+
+		if localized {
+			sites := shiftLocalization(i, mass)
+			var sum float64
+			for _, j := range sites {
+				sum += j.Probability
+			}
+			if sum > 0 {
+				for _, j := range sites {
+					freq[string(j.Residue)] += j.Probability / sum
+				}
+				continue
+			}
+		}
+
+		for _, j := range i.Peptide {
+			freq[string(j)] += 1 / float64(len(i.Peptide))
+		}
+	}
+
+	return freq
+}
+
+// shiftLocalization returns the localization of the mass shift on the PSM, given by the PTMProphet
+// mass difference mode or by a localized modification with the same mass
+func shiftLocalization(psm PSMEvidence, mass float64) []localizedSite {
+
+	for k, v := range psm.LocalizedPTMMassDiff {
+
+		if strings.Contains(strings.ToLower(k), "massdiff") {
+			return parseLocalization(v)
+		}
+
+		m, e := strconv.ParseFloat(strings.TrimLeft(strings.TrimPrefix(k, "PTMProphet_"), "ABCDEFGHIJKLMNOPQRSTUVWXYZnc"), 64)
+		if e == nil && math.Abs(m-mass) <= shiftTolerance {
+			return parseLocalization(v)
+		}
+	}
+
+	return nil
+}
+
+// residueEnrichment compares the residue frequencies on a mass shift to the background, the
+// enriched residues are sorted by ratio
+func residueEnrichment(freq, background map[string]float64) []ResidueEnrichment {
+
+	var total, bgTotal float64
+	for _, v := range freq {
+		total += v
+	}
+	for _, v := range background {
+		bgTotal += v
+	}
+
+	var list []ResidueEnrichment
+	if total == 0 || bgTotal == 0 {
+		return list
+	}
+
+	for k, v := range freq {
+		bg, ok := background[k]
+		if !ok || bg == 0 {
+			continue
+		}
+		ratio := (v / total) / (bg / bgTotal)
+		if ratio > 1 {
+			list = append(list, ResidueEnrichment{Residue: k, Ratio: ratio})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Ratio != list[j].Ratio {
+			return list[i].Ratio > list[j].Ratio
+		}
+		return list[i].Residue < list[j].Residue
+	})
+
+	return list
+}
+
+// MassShiftReport creates the TSV mass shift report
+func (evi *Evidence) MassShiftReport() {
+
+	output := fmt.Sprintf("%s%smass-shift.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	// create result file
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create mass shift report"), "error")
+	}
+	defer file.Close()
+
+	_, e = io.WriteString(file, "Mass Shift\tLower Mass\tHigher Mass\tPSMs\tPeptides\tAnnotations\tEnriched Residues\n")
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	for _, i := range evi.Modifications.MassShifts {
+
+		var enriched []string
+		for j, k := range i.Enrichment {
+			if j == 3 {
+				break
+			}
+			enriched = append(enriched, fmt.Sprintf("%s(%.2f)", k.Residue, k.Ratio))
+		}
+
+		line := fmt.Sprintf("%.4f\t%.4f\t%.4f\t%d\t%d\t%s\t%s\n",
+			i.Mass,
+			i.LowerMass,
+			i.HigherMass,
+			i.PSMs,
+			i.Peptides,
+			strings.Join(i.Annotations, "; "),
+			strings.Join(enriched, ", "),
+		)
+
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
@@ -0,0 +1,164 @@
+package rep
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"philosopher/lib/obo"
+)
+
+var shiftTestTerms = shiftTerms([]obo.Term{
+	{Name: "Phospho", MonoIsotopicMass: 79.966331},
+	{Name: "Oxidation", MonoIsotopicMass: 15.994915},
+	{Name: "Dioxidation", MonoIsotopicMass: 31.989829},
+	{Name: "Acetyl", MonoIsotopicMass: 42.010565},
+	{Name: "Acetyl", MonoIsotopicMass: 42.010565},
+	{Name: "Unknown", MonoIsotopicMass: 0},
+})
+
+func TestAnnotateShift(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		mass  float64
+		first string
+		count int
+	}{
+		{"single entry", 79.9663, "Phospho", 1},
+		{"pair of entries", 95.9612, "Oxidation + Phospho", 1},
+		{"single entry preferred over pair", 31.989829, "Dioxidation", 2},
+		{"isotope error on an entry", 80.9697, "Phospho (+1 isotope)", 1},
+		{"isotope error alone", 1.0034, "Isotope error (+1)", 1},
+		{"unknown", 500.1234, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got := annotateShift(tt.mass, shiftTestTerms, shiftTolerance)
+
+			if len(got) != tt.count {
+				t.Fatalf("annotateShift() = %v, want %d annotations", got, tt.count)
+			}
+
+			if tt.count > 0 && got[0].Name != tt.first {
+				t.Errorf("annotateShift() first = %v, want %v", got[0].Name, tt.first)
+			}
+		})
+	}
+}
+
+// testBins creates one bin per count, the bin on the zero position is the unmodified bin
+func testBins(counts []int, zero int) []MassBin {
+
+	bins := make([]MassBin, len(counts))
+
+	for i, n := range counts {
+		bins[i].MassCenter = float64(i - zero)
+		bins[i].LowerMass = float64(i-zero) - 0.5
+		bins[i].HigherRight = float64(i-zero) + 0.5
+		bins[i].ObservedMods = make(PSMEvidenceList, n)
+	}
+
+	return bins
+}
+
+func TestShiftPeaks(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		counts []int
+		zero   int
+		want   []int
+	}{
+		{"local maxima", []int{2, 6, 3, 10, 4, 5, 7, 1}, 3, []int{1, 6}},
+		{"plateau is reported once", []int{0, 6, 6, 0}, -1, []int{1}},
+		{"unmodified bin is not a peak", []int{0, 9, 0}, 1, nil},
+		{"below the minimum", []int{0, 4, 0}, -1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shiftPeaks(testBins(tt.counts, tt.zero), minShiftPSMs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shiftPeaks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShiftWindows(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		counts []int
+		zero   int
+		peaks  []int
+		want   [][]int
+	}{
+		{"isolated peak", []int{1, 6, 2}, -1, []int{1}, [][]int{{0, 1, 2}}},
+		{"shared bin goes to the larger peak", []int{0, 6, 2, 9, 1}, -1, []int{1, 3}, [][]int{{0, 1}, {2, 3, 4}}},
+		{"unmodified bin is not pooled", []int{20, 8, 2}, 0, []int{1}, [][]int{{1, 2}}},
+		{"histogram edges", []int{7, 1, 6}, -1, []int{0, 2}, [][]int{{0, 1}, {2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shiftWindows(testBins(tt.counts, tt.zero), tt.peaks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shiftWindows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResidueEnrichment(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		freq       map[string]float64
+		background map[string]float64
+		want       []ResidueEnrichment
+	}{
+		{
+			"enriched residues by ratio",
+			map[string]float64{"S": 2, "T": 1, "K": 1},
+			map[string]float64{"S": 1, "T": 1, "K": 2, "A": 4},
+			[]ResidueEnrichment{{"S", 4}, {"T", 2}},
+		},
+		{
+			"ties by residue",
+			map[string]float64{"T": 1, "S": 1},
+			map[string]float64{"S": 1, "T": 1, "A": 2},
+			[]ResidueEnrichment{{"S", 2}, {"T", 2}},
+		},
+		{
+			"residues without background are skipped",
+			map[string]float64{"X": 1, "S": 1},
+			map[string]float64{"S": 1, "A": 3},
+			[]ResidueEnrichment{{"S", 2}},
+		},
+		{
+			"no frequencies",
+			map[string]float64{},
+			map[string]float64{"S": 1},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got := residueEnrichment(tt.freq, tt.background)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("residueEnrichment() = %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i].Residue != tt.want[i].Residue || math.Abs(got[i].Ratio-tt.want[i].Ratio) > 1e-9 {
+					t.Errorf("residueEnrichment() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
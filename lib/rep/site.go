@@ -0,0 +1,429 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/dat"
+	"philosopher/lib/iso"
+	"philosopher/lib/mod"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+)
+
+// SiteEvidence is a localized modification site on a protein sequence
+type SiteEvidence struct {
+	Protein      string
+	ProteinID    string
+	GeneName     string
+	Modification string
+	Residue      string
+	Position     int
+	Window       string
+	Probability  float64
+	Multiplicity [3]int
+	Spc          int
+	Intensity    float64
+	Labels       iso.Labels
+}
+
+// SiteEvidenceList is a list of SiteEvidence
+type SiteEvidenceList []SiteEvidence
+
+func (a SiteEvidenceList) Len() int      { return len(a) }
+func (a SiteEvidenceList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a SiteEvidenceList) Less(i, j int) bool {
+	if a[i].Protein != a[j].Protein {
+		return a[i].Protein < a[j].Protein
+	}
+	if a[i].Position != a[j].Position {
+		return a[i].Position < a[j].Position
+	}
+	return a[i].Modification < a[j].Modification
+}
+
+// localizedSite is a residue and its localization probability on the peptide
+type localizedSite struct {
+	Position    int
+	Residue     byte
+	Probability float64
+}
+
+// parseLocalization reads the PTMProphet peptide string, the probabilities follow the residues
+// they refer to and the positions are 1-based on the stripped peptide
+func parseLocalization(ptmPeptide string) []localizedSite {
+
+	var sites []localizedSite
+	var position int
+	var residue byte
+
+	for i := 0; i < len(ptmPeptide); i++ {
+
+		c := ptmPeptide[i]
+
+		switch {
+		case c >= 'A' && c <= 'Z':
+			position++
+			residue = c
+		case c == '[':
+			// mass annotations are not part of the localization
+			end := strings.IndexByte(ptmPeptide[i:], ']')
+			if end < 0 {
+				return sites
+			}
+			i += end
+		case c == '(':
+			end := strings.IndexByte(ptmPeptide[i:], ')')
+			if end < 0 {
+				return sites
+			}
+			prob, e := strconv.ParseFloat(ptmPeptide[i+1:i+end], 64)
+			if e == nil && position > 0 {
+				sites = append(sites, localizedSite{Position: position, Residue: residue, Probability: prob})
+			}
+			i += end
+		}
+	}
+
+	return sites
+}
+
+// assignSites selects the most probable positions for the number of modifications carried by the
+// peptide, the probabilities on PTMProphet strings add up to the number of modifications
+func assignSites(sites []localizedSite) []localizedSite {
+
+	var sum float64
+	for _, i := range sites {
+		sum += i.Probability
+	}
+
+	n := int(math.Round(sum))
+	if n < 1 {
+		n = 1
+	}
+
+	ranked := make([]localizedSite, len(sites))
+	copy(ranked, sites)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Probability > ranked[j].Probability
+	})
+
+	if n < len(ranked) {
+		ranked = ranked[:n]
+	}
+
+	return ranked
+}
+
+// sequenceWindow returns the residues around a 1-based position padded with _ at the termini
+func sequenceWindow(seq string, position, flank int) string {
+
+	var window strings.Builder
+
+	for i := position - 1 - flank; i <= position-1+flank; i++ {
+		if i < 0 || i >= len(seq) {
+			window.WriteByte('_')
+		} else {
+			window.WriteByte(seq[i])
+		}
+	}
+
+	return window.String()
+}
+
+// LocalizedModifications returns the localization keys for the PTMProphet modification definitions,
+// the modifications found on the PSMs are used when no definition is given
+func (evi Evidence) LocalizedModifications(mods string) []string {
+
+	keys := mod.PTMProphetKeys(mods)
+	if len(keys) > 0 {
+		return keys
+	}
+
+	var found = make(map[string]uint8)
+	for _, i := range evi.PSM {
+		for k := range i.LocalizedPTMSites {
+			found[k] = 0
+		}
+	}
+
+	for k := range found {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// AssembleSiteReport maps the localized modifications from the PSMs to their protein positions
+func (evi Evidence) AssembleSiteReport(hasDecoys bool, ptms []string) SiteEvidenceList {
+
+	var dtb dat.Base
+	dtb.Restore()
+
+	var records = make(map[string]dat.Record)
+	for _, j := range dtb.Records {
+		records[j.PartHeader] = j
+	}
+
+	return evi.assembleSites(records, hasDecoys, ptms)
+}
+
+// assembleSites maps the localized modifications to every protein and every position where the
+// peptide is found. The site intensity is the sum of the most intense PSM from each peptide ion,
+// so redundant PSMs from the same precursor are not counted more than once
+func (evi Evidence) assembleSites(records map[string]dat.Record, hasDecoys bool, ptms []string) SiteEvidenceList {
+
+	var siteMap = make(map[string]SiteEvidence)
+	var ionIntensity = make(map[string]map[string]float64)
+
+	for _, i := range evi.PSM {
+
+		if i.IsDecoy && !hasDecoys {
+			continue
+		}
+
+		var proteins = []string{i.Protein}
+		for k := range i.MappedProteins {
+			if k != i.Protein {
+				proteins = append(proteins, k)
+			}
+		}
+		sort.Strings(proteins[1:])
+
+		for _, ptm := range ptms {
+
+			ptmPeptide, ok := i.LocalizedPTMMassDiff[ptm]
+			if !ok {
+				continue
+			}
+
+			sites := parseLocalization(ptmPeptide)
+			if len(sites) == 0 {
+				continue
+			}
+
+			assigned := assignSites(sites)
+
+			multiplicity := len(assigned)
+			if multiplicity > 3 {
+				multiplicity = 3
+			}
+
+			for _, protein := range proteins {
+
+				rec, ok := records[protein]
+				if !ok || (rec.IsDecoy && !hasDecoys) {
+					continue
+				}
+
+				for _, offset := range peptideOffsets(rec.Sequence, i.Peptide) {
+					for _, j := range assigned {
+
+						position := offset + j.Position
+						key := fmt.Sprintf("%s#%d#%s", protein, position, ptm)
+
+						site, ok := siteMap[key]
+						if !ok {
+							site.Protein = protein
+							site.ProteinID = rec.ID
+							site.GeneName = rec.GeneNames
+							site.Modification = mod.PTMName(ptm)
+							site.Residue = string(j.Residue)
+							site.Position = position
+							site.Window = sequenceWindow(rec.Sequence, position, 7)
+							ionIntensity[key] = make(map[string]float64)
+						}
+
+						if j.Probability > site.Probability {
+							site.Probability = j.Probability
+						}
+
+						if i.Intensity > ionIntensity[key][i.IonForm] {
+							ionIntensity[key][i.IonForm] = i.Intensity
+						}
+
+						site.Multiplicity[multiplicity-1]++
+						site.Spc++
+						site.Labels.Add(i.Labels)
+
+						siteMap[key] = site
+					}
+				}
+			}
+		}
+	}
+
+	var list SiteEvidenceList
+	for k, v := range siteMap {
+		for _, i := range ionIntensity[k] {
+			v.Intensity += i
+		}
+		list = append(list, v)
+	}
+
+	sort.Sort(list)
+
+	return list
+}
+
+// peptideOffsets returns every 0-based position where the peptide is found on the protein sequence
+func peptideOffsets(seq, peptide string) []int {
+
+	var offsets []int
+
+	if len(peptide) == 0 {
+		return offsets
+	}
+
+	for start := 0; start < len(seq); {
+		i := strings.Index(seq[start:], peptide)
+		if i < 0 {
+			break
+		}
+		offsets = append(offsets, start+i)
+		start += i + 1
+	}
+
+	return offsets
+}
+
+// modifiedLabelKeys lists the modifications quantified on any of the given label roll-ups
+func modifiedLabelKeys(labels ...map[string]iso.Labels) []string {
+
+	var found = make(map[string]uint8)
+	for _, i := range labels {
+		for k := range i {
+			found[k] = 0
+		}
+	}
+
+	var keys []string
+	for k := range found {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// modifiedLabelsHeader returns the channel columns for each modification, such as Phospho Channel 126
+func modifiedLabelsHeader(ptms []string, ref iso.Labels, channels int) string {
+
+	var header string
+
+	names := labelsToNames(ref)
+	for _, i := range ptms {
+		for j := 0; j < channels && j < len(names); j++ {
+			header += fmt.Sprintf("\t%s %s", mod.PTMName(i), names[j])
+		}
+	}
+
+	return header
+}
+
+// modifiedLabelsLine returns the channel intensities for each modification, the modifications
+// not found on the entry are reported with zero intensities
+func modifiedLabelsLine(ptms []string, labels map[string]iso.Labels, channels int) string {
+
+	var line string
+
+	for _, i := range ptms {
+		intensities := labelsToIntensities(labels[i])
+		for j := 0; j < channels && j < len(intensities); j++ {
+			line += fmt.Sprintf("\t%.4f", intensities[j])
+		}
+	}
+
+	return line
+}
+
+// SiteReport creates the TSV modification site report
+func (evi Evidence) SiteReport(brand string, channels int, hasDecoys bool, ptms []string) {
+
+	output := fmt.Sprintf("%s%ssite.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	sites := evi.AssembleSiteReport(hasDecoys, ptms)
+
+	// create result file
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create site report"), "error")
+	}
+	defer file.Close()
+
+	header := "Protein\tProtein ID\tGene\tModification\tResidue\tPosition\tSequence Window\tBest Localization Probability\tSpectral Count\tSingly Modified\tDoubly Modified\t3+ Modified\tIntensity"
+
+	// the channel names are taken from the first quantified spectrum, the rows report the same
+	// channel columns written on the header
+	var columns int
+	if len(brand) > 0 {
+		for _, i := range evi.PSM {
+			if i.Labels.IsUsed {
+				labelNames := labelsToNames(i.Labels)
+				for j := 0; j < channels && j < len(labelNames); j++ {
+					header += "\t" + labelNames[j]
+					columns++
+				}
+				break
+			}
+		}
+	}
+
+	header += "\n"
+
+	_, e = io.WriteString(file, header)
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	for _, i := range sites {
+
+		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%d\t%s\t%.4f\t%d\t%d\t%d\t%d\t%6.f",
+			i.Protein,
+			i.ProteinID,
+			i.GeneName,
+			i.Modification,
+			i.Residue,
+			i.Position,
+			i.Window,
+			i.Probability,
+			i.Spc,
+			i.Multiplicity[0],
+			i.Multiplicity[1],
+			i.Multiplicity[2],
+			i.Intensity,
+		)
+
+		if columns > 0 {
+			intensities := labelsToIntensities(i.Labels)
+			for j := 0; j < columns; j++ {
+				var v float64
+				if j < len(intensities) {
+					v = intensities[j]
+				}
+				line = fmt.Sprintf("%s\t%.4f", line, v)
+			}
+		}
+
+		line += "\n"
+
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
@@ -0,0 +1,143 @@
+package rep
+
+import (
+	"reflect"
+	"testing"
+
+	"philosopher/lib/dat"
+)
+
+func TestParseLocalization(t *testing.T) {
+
+	tests := []struct {
+		name string
+		in   string
+		want []localizedSite
+	}{
+		{"single", "PEPS(1.000)K", []localizedSite{{4, 'S', 1}}},
+		{"ambiguous", "S(0.250)PEPT(0.750)K", []localizedSite{{1, 'S', 0.25}, {5, 'T', 0.75}}},
+		{"masses are skipped", "n[43]M[147]S(0.900)T(0.100)K", []localizedSite{{2, 'S', 0.9}, {3, 'T', 0.1}}},
+		{"unlocalized", "PEPTIDE", nil},
+		{"truncated", "PEPS(0.5", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLocalization(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLocalization() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignSites(t *testing.T) {
+
+	tests := []struct {
+		name string
+		in   []localizedSite
+		want []localizedSite
+	}{
+		{"one of two", []localizedSite{{1, 'S', 0.25}, {5, 'T', 0.75}}, []localizedSite{{5, 'T', 0.75}}},
+		{"two of three", []localizedSite{{1, 'S', 0.9}, {3, 'S', 0.2}, {6, 'Y', 0.9}}, []localizedSite{{1, 'S', 0.9}, {6, 'Y', 0.9}}},
+		{"low probabilities keep one site", []localizedSite{{2, 'S', 0.2}, {4, 'T', 0.1}}, []localizedSite{{2, 'S', 0.2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignSites(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assignSites() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequenceWindow(t *testing.T) {
+
+	tests := []struct {
+		seq      string
+		position int
+		flank    int
+		want     string
+	}{
+		{"PEPTIDE", 4, 2, "EPTID"},
+		{"PEPTIDE", 1, 2, "__PEP"},
+		{"PEPTIDE", 7, 2, "IDE__"},
+		{"PEPTIDE", 4, 0, "T"},
+	}
+
+	for _, tt := range tests {
+		if got := sequenceWindow(tt.seq, tt.position, tt.flank); got != tt.want {
+			t.Errorf("sequenceWindow(%q, %d, %d) = %q, want %q", tt.seq, tt.position, tt.flank, got, tt.want)
+		}
+	}
+}
+
+func TestPeptideOffsets(t *testing.T) {
+
+	tests := []struct {
+		seq     string
+		peptide string
+		want    []int
+	}{
+		{"MPEPSKPEPSK", "PEPSK", []int{1, 6}},
+		{"AAAA", "AA", []int{0, 1, 2}},
+		{"PEPTIDE", "KR", nil},
+		{"PEPTIDE", "", nil},
+	}
+
+	for _, tt := range tests {
+		if got := peptideOffsets(tt.seq, tt.peptide); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("peptideOffsets(%q, %q) = %v, want %v", tt.seq, tt.peptide, got, tt.want)
+		}
+	}
+}
+
+func TestAssembleSites(t *testing.T) {
+
+	records := map[string]dat.Record{
+		"sp|P1|A": {ID: "P1", PartHeader: "sp|P1|A", Sequence: "MPEPSKPEPSK"},
+		"sp|P2|B": {ID: "P2", PartHeader: "sp|P2|B", Sequence: "GGPEPSK"},
+	}
+
+	ptm := "STY:79.966331"
+	psm := func(spectrum, ion string, intensity float64) PSMEvidence {
+		return PSMEvidence{
+			Spectrum:             spectrum,
+			Peptide:              "PEPSK",
+			IonForm:              ion,
+			Protein:              "sp|P1|A",
+			MappedProteins:       map[string]int{"sp|P2|B": 0},
+			Intensity:            intensity,
+			LocalizedPTMMassDiff: map[string]string{ptm: "PEPS(1.000)K"},
+		}
+	}
+
+	var evi Evidence
+	evi.PSM = PSMEvidenceList{
+		psm("run.1.1.2", "PEPSK#2", 100),
+		psm("run.2.2.2", "PEPSK#2", 300),
+		psm("run.3.3.3", "PEPSK#3", 50),
+	}
+
+	sites := evi.assembleSites(records, false, []string{ptm})
+
+	// the repeated sequence on P1 and the alternative protein P2 are all reported
+	var positions []int
+	for _, i := range sites {
+		positions = append(positions, i.Position)
+	}
+
+	if !reflect.DeepEqual(positions, []int{5, 10, 6}) {
+		t.Fatalf("assembleSites() positions = %v", positions)
+	}
+
+	for _, i := range sites {
+		if i.Spc != 3 || i.Multiplicity[0] != 3 {
+			t.Errorf("assembleSites() %s %d spc = %d, multiplicity = %v", i.Protein, i.Position, i.Spc, i.Multiplicity)
+		}
+		// the most intense PSM from each ion is summed
+		if i.Intensity != 350 {
+			t.Errorf("assembleSites() %s %d intensity = %v, want 350", i.Protein, i.Position, i.Intensity)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"philosopher/lib/dat"
+	"philosopher/lib/dig"
 	"philosopher/lib/id"
 )
 
@@ -145,6 +146,21 @@ func (evi *Evidence) UpdateIonStatus(decoyTag string) {
 
 	}
 
+	for i := range evi.ModifiedPeptides {
+
+		v, ok := uniqueSeqMap[evi.ModifiedPeptides[i].Sequence]
+		if ok {
+			evi.ModifiedPeptides[i].MappedProteins[evi.ModifiedPeptides[i].Protein] = 0
+			delete(evi.ModifiedPeptides[i].MappedProteins, v)
+			evi.ModifiedPeptides[i].Protein = v
+		}
+
+		if strings.Contains(v, decoyTag) {
+			evi.ModifiedPeptides[i].IsDecoy = true
+		}
+
+	}
+
 	return
 }
 
@@ -262,6 +278,51 @@ func (evi *Evidence) UpdateLayerswithDatabase(decoyTag string) {
 		}
 	}
 
+	for i := range evi.ModifiedPeptides {
+
+		id := evi.ModifiedPeptides[i].Protein
+		if evi.ModifiedPeptides[i].IsDecoy {
+			id = strings.Replace(id, decoyTag, "", 1)
+		}
+
+		evi.ModifiedPeptides[i].ProteinID = proteinIDMap[id]
+		evi.ModifiedPeptides[i].EntryName = entryNameMap[id]
+		evi.ModifiedPeptides[i].GeneName = geneMap[id]
+		evi.ModifiedPeptides[i].ProteinDescription = descriptionMap[id]
+
+		// update mapped genes
+		for k := range evi.ModifiedPeptides[i].MappedProteins {
+			if !strings.Contains(k, decoyTag) {
+				evi.ModifiedPeptides[i].MappedGenes[geneMap[k]] = 0
+			}
+		}
+	}
+
+	return
+}
+
+// UpdateLayersWithDigestion adds the theoretical peptide counts to the proteins and the proteotypic
+// status to the peptides using the peptide index from the in-silico digestion
+func (evi *Evidence) UpdateLayersWithDigestion() {
+
+	var idx dig.Index
+	if !idx.Restore() {
+		return
+	}
+
+	evi.Digested = true
+
+	for i := range evi.Proteins {
+		evi.Proteins[i].TheoreticalPeptides = idx.Theoretical[evi.Proteins[i].ProteinID]
+	}
+
+	for i := range evi.Peptides {
+		j, ok := idx.Lookup[evi.Peptides[i].Sequence]
+		if ok {
+			evi.Peptides[i].IsProteotypic = idx.Peptides[j].IsProteotypic
+		}
+	}
+
 	return
 }
 
@@ -82,7 +82,7 @@ type Protein struct {
 	Annotation                      Annotation                 `xml:"annotation"`
 	IndistinguishableProtein        []IndistinguishableProtein `xml:"indistinguishable_protein"`
 	Peptide                         []Peptide                  `xml:"peptide"`
-	TopPepProb                      float64
+	TopPepProb                      float64                    `xml:"-"`
 	//Confidence                      float64                    `xml:"confidence,attr"`
 }
 
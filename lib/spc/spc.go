@@ -100,3 +100,26 @@ func (p *ProtXML) Parse(f string) {
 
 	return
 }
+
+// Write saves the protein summary on a protXML file
+func (p *ProtXML) Write(f string) {
+
+	file, e := os.Create(f)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+	defer file.Close()
+
+	file.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(file)
+	enc.Indent("", "  ")
+
+	if e := enc.Encode(p.ProteinSummary); e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	p.Name = filepath.Base(f)
+
+	return
+}
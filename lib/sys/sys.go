@@ -148,6 +148,12 @@ func IonBin() string {
 	return p
 }
 
+// ModPepBin file
+func ModPepBin() string {
+	p := fmt.Sprintf("%s%smpep.bin", MetaDir(), string(filepath.Separator))
+	return p
+}
+
 // ProBin file
 func ProBin() string {
 	p := fmt.Sprintf("%s%spro.bin", MetaDir(), string(filepath.Separator))
@@ -214,12 +220,48 @@ func EvIonBin() string {
 	return p
 }
 
+// EvModifiedPeptideBin file
+func EvModifiedPeptideBin() string {
+	p := fmt.Sprintf("%s%sev.mpep.bin", MetaDir(), string(filepath.Separator))
+	return p
+}
+
+// EvGeneBin file
+func EvGeneBin() string {
+	p := fmt.Sprintf("%s%sev.gene.bin", MetaDir(), string(filepath.Separator))
+	return p
+}
+
+// EvCrosslinkBin file
+func EvCrosslinkBin() string {
+	p := fmt.Sprintf("%s%sev.xl.bin", MetaDir(), string(filepath.Separator))
+	return p
+}
+
+// EvResiduePairBin file
+func EvResiduePairBin() string {
+	p := fmt.Sprintf("%s%sev.rp.bin", MetaDir(), string(filepath.Separator))
+	return p
+}
+
 // DBBin file
 func DBBin() string {
 	p := fmt.Sprintf("%s%sdb.bin", MetaDir(), string(filepath.Separator))
 	return p
 }
 
+// DigBin file
+func DigBin() string {
+	p := fmt.Sprintf("%s%sdig.bin", MetaDir(), string(filepath.Separator))
+	return p
+}
+
+// LocBin file
+func LocBin() string {
+	p := fmt.Sprintf("%s%sloc.bin", MetaDir(), string(filepath.Separator))
+	return p
+}
+
 // MODBin file
 func MODBin() string {
 	p := fmt.Sprintf("%s%smod.bin", MetaDir(), string(filepath.Separator))
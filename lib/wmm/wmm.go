@@ -41,11 +41,27 @@ func writeDatabase(d met.Database, text string) string {
 
 	text = fmt.Sprintf("A protein database file was downloaded from UniProt %s (PMID:30395287) using the proteome ID %s on %s.", dbFlavor, d.ID, d.TimeStamp)
 
+	if len(d.Hash) > 0 {
+		text = fmt.Sprintf("%s The SHA-256 checksum of the database was %s.", text, d.Hash)
+	}
+
 	if d.Crap == true {
 		text = fmt.Sprintf("%s A list of 153 common contaminants was also added to the database.", text)
 	}
 
-	text = fmt.Sprintf("%s Decoy entries were generated by reversing the protein sequences and adding the %s prefix to their headers.", text, d.Tag)
+	var decoyMethod string
+	switch d.Decoy {
+	case "pseudo":
+		decoyMethod = fmt.Sprintf("pseudo-reversing the protein sequences while keeping the %s cleavage sites in place", d.Enz)
+	case "shuffle":
+		decoyMethod = fmt.Sprintf("shuffling the residues between the %s cleavage sites (random seed %d), discarding shuffled peptides identical to target peptides", d.Enz, d.Seed)
+	case "debruijn":
+		decoyMethod = fmt.Sprintf("de Bruijn shuffling the residues between the %s cleavage sites (random seed %d), preserving the dipeptide composition", d.Enz, d.Seed)
+	default:
+		decoyMethod = "reversing the protein sequences"
+	}
+
+	text = fmt.Sprintf("%s Decoy entries were generated by %s and adding the %s prefix to their headers.", text, decoyMethod, d.Tag)
 
 	// appending new line before returning
 	text = text + "\n"
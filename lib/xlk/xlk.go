@@ -0,0 +1,682 @@
+// Package xlk (Crosslinks) imports crosslinked peptide identifications and estimates their FDR
+package xlk
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"philosopher/lib/dat"
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/psi"
+	"philosopher/lib/rep"
+
+	"github.com/sirupsen/logrus"
+)
+
+// csvColumns maps the accepted CSV header names, without spaces, dashes or underscores, to the fields
+var csvColumns = map[string]string{
+	"spectrum":         "spectrum",
+	"run":              "run",
+	"scan":             "scan",
+	"charge":           "charge",
+	"precursorcharge":  "charge",
+	"peptide1":         "peptide1",
+	"peptide2":         "peptide2",
+	"linkpos1":         "link1",
+	"linkpos2":         "link2",
+	"peptidelink1":     "link1",
+	"peptidelink2":     "link2",
+	"protein1":         "protein1",
+	"protein2":         "protein2",
+	"accession1":       "protein1",
+	"accession2":       "protein2",
+	"peppos1":          "start1",
+	"peppos2":          "start2",
+	"peptideposition1": "start1",
+	"peptideposition2": "start2",
+	"isdecoy1":         "decoy1",
+	"isdecoy2":         "decoy2",
+	"decoy1":           "decoy1",
+	"decoy2":           "decoy2",
+	"crosslinker":      "crosslinker",
+	"score":            "score",
+	"matchscore":       "score",
+}
+
+// Run imports the crosslinked identifications, estimates the FDR for the intra- and inter-protein
+// links and creates the crosslink and residue pair reports
+func Run(m met.Data) met.Data {
+
+	if len(m.Crosslink.Tag) == 0 {
+		m.Crosslink.Tag = m.Database.Tag
+	}
+
+	var csms rep.CrosslinkEvidenceList
+
+	if len(m.Crosslink.Csv) > 0 {
+		csms = append(csms, ReadCSV(m.Crosslink.Csv, m.Crosslink.Tag)...)
+	}
+
+	if len(m.Crosslink.Mzid) > 0 {
+		csms = append(csms, ReadMzIdentML(m.Crosslink.Mzid, m.Crosslink.Tag, m.Crosslink.Score)...)
+	}
+
+	if len(csms) == 0 {
+		msg.NoPSMFound(errors.New("No crosslinked spectrum matches were found"), "fatal")
+	}
+
+	logrus.Info("Imported ", len(csms), " crosslinked spectrum matches")
+
+	var dtb dat.Base
+	dtb.Restore()
+	if len(dtb.Records) > 0 {
+		MapProteinPositions(csms, dtb.Records)
+	} else {
+		logrus.Warning("Database data not available, the protein positions are taken from the input files")
+	}
+
+	evi := filterCrosslinks(csms, m.Crosslink.FDR, m.Crosslink.LowScore)
+
+	logCounts(evi)
+
+	rep.SerializeEVCrosslinks(&evi)
+
+	evi.CrosslinkReport(false)
+	evi.ResiduePairReport(false)
+
+	return m
+}
+
+// filterCrosslinks estimates the CSM and the residue pair FDR independently. The residue pairs are
+// built from all imported CSMs with the best score of each pair, and each level keeps the matches
+// under its own cutoff
+func filterCrosslinks(csms rep.CrosslinkEvidenceList, fdr float64, ascending bool) rep.Evidence {
+
+	var evi rep.Evidence
+
+	CrosslinkFDR(csms, ascending)
+
+	evi.Crosslinks = csms
+	evi.AssembleResiduePairs(ascending)
+	ResiduePairFDR(evi.ResiduePairs, ascending)
+
+	var filtered rep.CrosslinkEvidenceList
+	for _, i := range csms {
+		if i.QValue <= fdr {
+			filtered = append(filtered, i)
+		}
+	}
+	evi.Crosslinks = filtered
+
+	var pairs rep.ResiduePairEvidenceList
+	for _, i := range evi.ResiduePairs {
+		if i.QValue <= fdr {
+			pairs = append(pairs, i)
+		}
+	}
+	evi.ResiduePairs = pairs
+
+	return evi
+}
+
+// logCounts prints the number of target matches and residue pairs for each link type
+func logCounts(evi rep.Evidence) {
+
+	var csms = make(map[bool]int)
+	var pairs = make(map[bool]int)
+
+	for _, i := range evi.Crosslinks {
+		if i.DecoyClass() == "TT" {
+			csms[i.IsIntraProtein]++
+		}
+	}
+
+	for _, i := range evi.ResiduePairs {
+		if i.DecoyClass == "TT" {
+			pairs[i.IsIntraProtein]++
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"intra": csms[true],
+		"inter": csms[false],
+	}).Info("Crosslinked spectrum matches")
+
+	logrus.WithFields(logrus.Fields{
+		"intra": pairs[true],
+		"inter": pairs[false],
+	}).Info("Residue pairs")
+
+	return
+}
+
+// ReadCSV reads crosslinked identifications from a comma or tab separated file with one match per line,
+// the columns are identified by the header and the decoys are taken from the decoy columns when present
+// or from the protein names otherwise
+func ReadCSV(f, decoyTag string) rep.CrosslinkEvidenceList {
+
+	b, e := ioutil.ReadFile(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	reader := csv.NewReader(strings.NewReader(string(b)))
+	reader.FieldsPerRecord = -1
+	if firstLine := strings.SplitN(string(b), "\n", 2)[0]; strings.Contains(firstLine, "\t") {
+		reader.Comma = '\t'
+	}
+
+	records, e := reader.ReadAll()
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	if len(records) < 1 {
+		msg.ReadFile(errors.New("The crosslink file is empty"), "fatal")
+	}
+
+	var columns = make(map[string]int)
+	for i, j := range records[0] {
+		name := strings.ToLower(strings.TrimSpace(j))
+		name = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name)
+		if v, ok := csvColumns[name]; ok {
+			columns[v] = i
+		}
+	}
+
+	for _, i := range []string{"peptide1", "peptide2", "link1", "link2", "protein1", "protein2", "score"} {
+		if _, ok := columns[i]; !ok {
+			msg.ReadFile(fmt.Errorf("The crosslink file is missing the %s column", i), "fatal")
+		}
+	}
+
+	get := func(r []string, c string) string {
+		i, ok := columns[c]
+		if !ok || i >= len(r) {
+			return ""
+		}
+		return strings.TrimSpace(r[i])
+	}
+
+	var list rep.CrosslinkEvidenceList
+
+	for _, r := range records[1:] {
+
+		if len(r) == 0 || (len(r) == 1 && len(strings.TrimSpace(r[0])) == 0) {
+			continue
+		}
+
+		var c rep.CrosslinkEvidence
+
+		c.Crosslinker = get(r, "crosslinker")
+		c.Scan, _ = strconv.Atoi(get(r, "scan"))
+
+		z, _ := strconv.Atoi(get(r, "charge"))
+		c.AssumedCharge = uint8(z)
+
+		score, e := strconv.ParseFloat(get(r, "score"), 64)
+		if e != nil {
+			continue
+		}
+		c.Score = score
+
+		c.Spectrum = get(r, "spectrum")
+		if len(c.Spectrum) == 0 {
+			c.Spectrum = fmt.Sprintf("%s.%05d.%05d.%d", get(r, "run"), c.Scan, c.Scan, c.AssumedCharge)
+		}
+
+		for i, j := range []string{"1", "2"} {
+
+			ch := newChain(get(r, "peptide"+j), strings.Split(get(r, "protein"+j), ";"), decoyTag)
+			ch.PeptidePosition, _ = strconv.Atoi(get(r, "link"+j))
+
+			if start, e := strconv.Atoi(strings.Split(get(r, "start"+j), ";")[0]); e == nil && start > 0 {
+				ch.ProteinPosition = start + ch.PeptidePosition - 1
+			}
+
+			if v := strings.ToLower(get(r, "decoy"+j)); len(v) > 0 {
+				ch.IsDecoy = v == "true" || v == "1" || v == "yes"
+			}
+
+			c.Chains[i] = ch
+		}
+
+		c.IsIntraProtein = IsIntraProtein(c, decoyTag)
+
+		list = append(list, c)
+	}
+
+	return list
+}
+
+// newChain creates a chain from the peptide, that may include modifications, and the protein list
+func newChain(peptide string, proteins []string, decoyTag string) rep.CrosslinkChain {
+
+	var ch rep.CrosslinkChain
+
+	ch.ModifiedPeptide = peptide
+	ch.MappedProteins = make(map[string]int)
+
+	// only the upper case letters are residues, modifications are written in lower case or in brackets
+	var depth int
+	var seq strings.Builder
+	for _, i := range peptide {
+		switch {
+		case i == '[' || i == '(':
+			depth++
+		case i == ']' || i == ')':
+			depth--
+		case depth == 0 && unicode.IsUpper(i):
+			seq.WriteRune(i)
+		}
+	}
+	ch.Peptide = seq.String()
+
+	ch.IsDecoy = true
+	for _, i := range proteins {
+		i = strings.TrimSpace(i)
+		if len(i) == 0 {
+			continue
+		}
+		if len(ch.Protein) == 0 {
+			ch.Protein = i
+		}
+		ch.MappedProteins[i]++
+		if len(decoyTag) == 0 || !strings.HasPrefix(i, decoyTag) {
+			ch.IsDecoy = false
+		}
+	}
+
+	if len(ch.MappedProteins) == 0 {
+		ch.IsDecoy = false
+	}
+
+	return ch
+}
+
+// IsIntraProtein reports if both chains can come from the same protein, the decoy tags are removed
+// so the target and decoy versions of a protein are considered the same protein
+func IsIntraProtein(c rep.CrosslinkEvidence, decoyTag string) bool {
+
+	var proteins = make(map[string]uint8)
+	for i := range c.Chains[0].MappedProteins {
+		proteins[strings.TrimPrefix(i, decoyTag)] = 0
+	}
+
+	for i := range c.Chains[1].MappedProteins {
+		if _, ok := proteins[strings.TrimPrefix(i, decoyTag)]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadMzIdentML reads the crosslinked identifications from a mzIdentML 1.2 file, the two peptides of a match
+// share the same cross-link spectrum identification item value and carry the cross-link donor and acceptor
+// modifications. The score is the cvParam with the given name or accession, or the first numeric one
+func ReadMzIdentML(f, decoyTag, scoreName string) rep.CrosslinkEvidenceList {
+
+	var xml psi.MzIdentML
+	xml.Parse(f)
+
+	var sources = make(map[string]string)
+	for _, i := range xml.DataCollection.Inputs.SpectraData {
+		base := filepath.Base(strings.Replace(i.Location, "\\", "/", -1))
+		sources[i.ID] = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	var dbSequences = make(map[string]string)
+	for _, i := range xml.SequenceCollection.DBSequence {
+		dbSequences[i.ID] = i.Accession
+	}
+
+	var peptides = make(map[string]psi.Peptide)
+	for _, i := range xml.SequenceCollection.Peptide {
+		peptides[i.ID] = i
+	}
+
+	var evidences = make(map[string]psi.PeptideEvidence)
+	for _, i := range xml.SequenceCollection.PeptideEvidence {
+		evidences[i.ID] = i
+	}
+
+	var list rep.CrosslinkEvidenceList
+
+	for _, i := range xml.DataCollection.AnalysisData.SpectrumIdentificationList {
+		for _, j := range i.SpectrumIdentificationResult {
+
+			// pair the identification items by the cross-link spectrum identification item value
+			var pairs = make(map[string][]psi.SpectrumIdentificationItem)
+			var order []string
+
+			for _, k := range j.SpectrumIdentificationItem {
+				for _, p := range k.CVParam {
+					if p.Accession == "MS:1002511" {
+						if _, ok := pairs[p.Value]; !ok {
+							order = append(order, p.Value)
+						}
+						pairs[p.Value] = append(pairs[p.Value], k)
+					}
+				}
+			}
+
+			for _, k := range order {
+
+				// mono-links and loop-links have a single peptide and are not crosslinks
+				if len(pairs[k]) != 2 {
+					continue
+				}
+
+				var c rep.CrosslinkEvidence
+				var valid = true
+
+				for x, sii := range pairs[k] {
+
+					pep, ok := peptides[sii.PeptideRef]
+					if !ok {
+						valid = false
+						break
+					}
+
+					ch, crosslinker := mzidChain(pep, sii, evidences, dbSequences, decoyTag)
+					if ch.PeptidePosition == 0 {
+						valid = false
+						break
+					}
+
+					if len(crosslinker) > 0 {
+						c.Crosslinker = crosslinker
+					}
+
+					c.Chains[x] = ch
+
+					if x == 0 {
+						c.AssumedCharge = sii.ChargeState
+						c.Score = mzidScore(sii, scoreName)
+					}
+				}
+
+				if !valid {
+					continue
+				}
+
+				c.Scan = scanFromSpectrumID(j.SpectrumID)
+				c.Spectrum = fmt.Sprintf("%s.%05d.%05d.%d", sources[j.SpectraDataRef], c.Scan, c.Scan, c.AssumedCharge)
+				c.IsIntraProtein = IsIntraProtein(c, decoyTag)
+
+				list = append(list, c)
+			}
+		}
+	}
+
+	return list
+}
+
+// mzidChain creates the chain from a mzIdentML peptide, the link position is the location of the cross-link
+// donor or acceptor modification and the crosslinker name is taken from the donor modification
+func mzidChain(pep psi.Peptide, sii psi.SpectrumIdentificationItem, evidences map[string]psi.PeptideEvidence, dbSequences map[string]string, decoyTag string) (rep.CrosslinkChain, string) {
+
+	var proteins []string
+	var starts []int
+	var decoys int
+
+	for _, i := range sii.PeptideEvidenceRef {
+		evi, ok := evidences[i.PeptideEvidenceRef]
+		if !ok {
+			continue
+		}
+		proteins = append(proteins, dbSequences[evi.DBSequenceRef])
+		start, _ := strconv.Atoi(evi.Start)
+		starts = append(starts, start)
+		if evi.IsDecoy == "true" {
+			decoys++
+		}
+	}
+
+	ch := newChain(strings.TrimSpace(pep.PeptideSequence.Value), proteins, decoyTag)
+	ch.ModifiedPeptide = ""
+
+	// the peptide evidences flag the decoys even when the proteins are not tagged
+	if len(proteins) > 0 && decoys == len(proteins) {
+		ch.IsDecoy = true
+	}
+
+	var crosslinker string
+
+	for _, i := range pep.Modification {
+
+		var isLink, isDonor bool
+		var name string
+
+		for _, j := range i.CVParam {
+			switch j.Accession {
+			case "MS:1002509":
+				isLink, isDonor = true, true
+			case "MS:1002510":
+				isLink = true
+			default:
+				name = j.Name
+			}
+		}
+
+		if !isLink {
+			continue
+		}
+
+		location, _ := strconv.Atoi(i.Location)
+
+		// the terminal locations are placed on the first and last residues
+		if location < 1 {
+			location = 1
+		} else if location > len(ch.Peptide) {
+			location = len(ch.Peptide)
+		}
+		ch.PeptidePosition = location
+
+		if isDonor {
+			crosslinker = name
+			if len(crosslinker) == 0 {
+				crosslinker = fmt.Sprintf("%.4f", i.MonoIsotopicMassDelta)
+			}
+		}
+	}
+
+	if len(starts) > 0 && starts[0] > 0 && ch.PeptidePosition > 0 {
+		ch.ProteinPosition = starts[0] + ch.PeptidePosition - 1
+	}
+
+	return ch, crosslinker
+}
+
+// mzidScore returns the numeric value of the score cvParam or userParam
+func mzidScore(sii psi.SpectrumIdentificationItem, scoreName string) float64 {
+
+	for _, i := range sii.CVParam {
+
+		if i.Accession == "MS:1002511" {
+			continue
+		}
+
+		if len(scoreName) > 0 && i.Name != scoreName && i.Accession != scoreName {
+			continue
+		}
+
+		if v, e := strconv.ParseFloat(i.Value, 64); e == nil {
+			return v
+		}
+	}
+
+	for _, i := range sii.UserParam {
+		if len(scoreName) > 0 && i.Name != scoreName {
+			continue
+		}
+		if v, e := strconv.ParseFloat(i.Value, 64); e == nil {
+			return v
+		}
+	}
+
+	return 0
+}
+
+// scanFromSpectrumID extracts the scan number from the native spectrum identifiers
+func scanFromSpectrumID(s string) int {
+
+	for _, i := range []string{`scan=(\d+)`, `index=(\d+)`} {
+		m := regexp.MustCompile(i).FindStringSubmatch(s)
+		if m != nil {
+			n, _ := strconv.Atoi(m[1])
+			return n
+		}
+	}
+
+	return 0
+}
+
+// MapProteinPositions locates the peptides on the protein sequences to find the linked protein positions
+// when they were not given on the input files
+func MapProteinPositions(csms rep.CrosslinkEvidenceList, records []dat.Record) {
+
+	var sequences = make(map[string]string)
+	for _, i := range records {
+		sequences[i.PartHeader] = i.Sequence
+		sequences[i.ID] = i.Sequence
+	}
+
+	for i := range csms {
+		for j := range csms[i].Chains {
+
+			ch := &csms[i].Chains[j]
+			if ch.ProteinPosition > 0 {
+				continue
+			}
+
+			seq, ok := sequences[ch.Protein]
+			if !ok {
+				continue
+			}
+
+			if offset := strings.Index(seq, ch.Peptide); offset >= 0 {
+				ch.ProteinPosition = offset + ch.PeptidePosition
+			}
+		}
+	}
+
+	return
+}
+
+// CrosslinkFDR assigns the q-values to the crosslinked spectrum matches, the intra- and inter-protein
+// links are estimated separately
+func CrosslinkFDR(csms rep.CrosslinkEvidenceList, ascending bool) {
+
+	for _, intra := range []bool{true, false} {
+
+		var index []int
+		var scores []float64
+		var classes []string
+
+		for i, j := range csms {
+			if j.IsIntraProtein == intra {
+				index = append(index, i)
+				scores = append(scores, j.Score)
+				classes = append(classes, j.DecoyClass())
+			}
+		}
+
+		for i, q := range QValues(scores, classes, ascending) {
+			csms[index[i]].QValue = q
+		}
+	}
+
+	return
+}
+
+// ResiduePairFDR assigns the q-values to the residue pairs, the intra- and inter-protein links are
+// estimated separately
+func ResiduePairFDR(pairs rep.ResiduePairEvidenceList, ascending bool) {
+
+	for _, intra := range []bool{true, false} {
+
+		var index []int
+		var scores []float64
+		var classes []string
+
+		for i, j := range pairs {
+			if j.IsIntraProtein == intra {
+				index = append(index, i)
+				scores = append(scores, j.Score)
+				classes = append(classes, j.DecoyClass)
+			}
+		}
+
+		for i, q := range QValues(scores, classes, ascending) {
+			pairs[index[i]].QValue = q
+		}
+	}
+
+	return
+}
+
+// QValues estimates the FDR at each score threshold as (TD - DD) / TT, counting the target-target,
+// target-decoy and decoy-decoy matches above the threshold, and converts it to q-values
+func QValues(scores []float64, classes []string, ascending bool) []float64 {
+
+	var order = make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if ascending {
+			return scores[order[i]] < scores[order[j]]
+		}
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	var fdr = make([]float64, len(scores))
+	var tt, td, dd float64
+
+	for i := 0; i < len(order); {
+
+		// matches with the same score share the same threshold
+		j := i
+		for j < len(order) && scores[order[j]] == scores[order[i]] {
+			switch classes[order[j]] {
+			case "TT":
+				tt++
+			case "TD":
+				td++
+			case "DD":
+				dd++
+			}
+			j++
+		}
+
+		value := math.Max(td-dd, 0) / math.Max(tt, 1)
+		for k := i; k < j; k++ {
+			fdr[order[k]] = value
+		}
+
+		i = j
+	}
+
+	// the q-value is the lowest FDR at which the match is accepted
+	var q = make([]float64, len(scores))
+	min := math.Inf(1)
+	for i := len(order) - 1; i >= 0; i-- {
+		min = math.Min(min, fdr[order[i]])
+		q[order[i]] = min
+	}
+
+	return q
+}
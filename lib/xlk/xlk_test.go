@@ -0,0 +1,225 @@
+package xlk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"philosopher/lib/dat"
+	"philosopher/lib/rep"
+)
+
+func TestQValues(t *testing.T) {
+
+	scores := []float64{10, 9, 8, 7, 6, 5}
+	classes := []string{"TT", "TT", "TD", "TT", "DD", "TD"}
+
+	// FDR = (TD - DD) / TT at each threshold: 0, 0, 1/2, 1/3, 0/3, 1/3
+	want := []float64{0, 0, 0, 0, 0, 0.3333}
+
+	got := QValues(scores, classes, false)
+	for i := range want {
+		if got[i] < want[i]-0.0001 || got[i] > want[i]+0.0001 {
+			t.Errorf("QValues() = %v, want %v", got, want)
+			break
+		}
+	}
+
+	// e-values are ranked from the lowest to the highest
+	got = QValues([]float64{0.001, 0.01, 0.1}, []string{"TT", "TD", "TT"}, true)
+	if got[0] != 0 || got[1] != 0.5 || got[2] != 0.5 {
+		t.Errorf("QValues() ascending = %v", got)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "xlk")
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "crosslinks.csv")
+	content := "Run,Scan,Charge,Peptide1,Peptide2,LinkPos1,LinkPos2,Protein1,Protein2,Score,Crosslinker\n" +
+		"run1,100,3,PEPKcmIDE,AKLLR,4,2,sp|P1|A;sp|P2|B,sp|P2|B,12.5,DSS\n" +
+		"run1,101,3,TTKR,AKLLR,3,2,rev_sp|P3|C,sp|P4|D,4.2,DSS\n" +
+		"run1,102,4,TTKR,AKLLR,3,2,rev_sp|P3|C,rev_sp|P4|D,not a score,DSS\n"
+	ioutil.WriteFile(f, []byte(content), 0644)
+
+	list := ReadCSV(f, "rev_")
+	if len(list) != 2 {
+		t.Fatalf("ReadCSV() = %d matches, want 2", len(list))
+	}
+
+	c := list[0]
+	if c.Spectrum != "run1.00100.00100.3" || c.Chains[0].Peptide != "PEPKIDE" || c.Chains[0].PeptidePosition != 4 || c.Crosslinker != "DSS" {
+		t.Errorf("ReadCSV() = %+v", c)
+	}
+
+	if !c.IsIntraProtein || c.DecoyClass() != "TT" {
+		t.Errorf("ReadCSV() intra = %v, class = %v", c.IsIntraProtein, c.DecoyClass())
+	}
+
+	if list[1].IsIntraProtein || list[1].DecoyClass() != "TD" {
+		t.Errorf("ReadCSV() intra = %v, class = %v", list[1].IsIntraProtein, list[1].DecoyClass())
+	}
+}
+
+func TestReadMzIdentML(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "xlk")
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "crosslinks.mzid")
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<MzIdentML id="test" version="1.2.0">
+  <SequenceCollection>
+    <DBSequence id="dbseq_P1" accession="sp|P1|A" searchDatabase_ref="db"/>
+    <DBSequence id="dbseq_P2" accession="rev_sp|P2|B" searchDatabase_ref="db"/>
+    <Peptide id="pep_1">
+      <PeptideSequence>PEPKIDE</PeptideSequence>
+      <Modification location="4" monoisotopicMassDelta="138.068">
+        <cvParam accession="XLMOD:02001" cvRef="XLMOD" name="DSS"/>
+        <cvParam accession="MS:1002509" cvRef="PSI-MS" name="cross-link donor" value="1"/>
+      </Modification>
+    </Peptide>
+    <Peptide id="pep_2">
+      <PeptideSequence>AKLLR</PeptideSequence>
+      <Modification location="2" monoisotopicMassDelta="0">
+        <cvParam accession="MS:1002510" cvRef="PSI-MS" name="cross-link acceptor" value="1"/>
+      </Modification>
+    </Peptide>
+    <PeptideEvidence id="pe_1" peptide_ref="pep_1" dBSequence_ref="dbseq_P1" start="10" end="16" isDecoy="false"/>
+    <PeptideEvidence id="pe_2" peptide_ref="pep_2" dBSequence_ref="dbseq_P2" start="20" end="24" isDecoy="true"/>
+  </SequenceCollection>
+  <DataCollection>
+    <Inputs>
+      <SpectraData id="sd_1" location="C:\data\run1.mzML"/>
+    </Inputs>
+    <AnalysisData>
+      <SpectrumIdentificationList id="sil_1">
+        <SpectrumIdentificationResult id="sir_1" spectrumID="controllerType=0 controllerNumber=1 scan=250" spectraData_ref="sd_1">
+          <SpectrumIdentificationItem id="sii_1_1" chargeState="3" peptide_ref="pep_1" rank="1" passThreshold="true">
+            <PeptideEvidenceRef peptideEvidence_ref="pe_1"/>
+            <cvParam accession="MS:1002511" cvRef="PSI-MS" name="cross-link spectrum identification item" value="1"/>
+            <cvParam accession="MS:1002681" cvRef="PSI-MS" name="OpenPepXL:score" value="0.85"/>
+          </SpectrumIdentificationItem>
+          <SpectrumIdentificationItem id="sii_1_2" chargeState="3" peptide_ref="pep_2" rank="1" passThreshold="true">
+            <PeptideEvidenceRef peptideEvidence_ref="pe_2"/>
+            <cvParam accession="MS:1002511" cvRef="PSI-MS" name="cross-link spectrum identification item" value="1"/>
+            <cvParam accession="MS:1002681" cvRef="PSI-MS" name="OpenPepXL:score" value="0.85"/>
+          </SpectrumIdentificationItem>
+        </SpectrumIdentificationResult>
+      </SpectrumIdentificationList>
+    </AnalysisData>
+  </DataCollection>
+</MzIdentML>
+`
+	ioutil.WriteFile(f, []byte(content), 0644)
+
+	list := ReadMzIdentML(f, "rev_", "")
+	if len(list) != 1 {
+		t.Fatalf("ReadMzIdentML() = %d matches, want 1", len(list))
+	}
+
+	c := list[0]
+	if c.Spectrum != "run1.00250.00250.3" || c.Score != 0.85 || c.Crosslinker != "DSS" {
+		t.Errorf("ReadMzIdentML() = %+v", c)
+	}
+
+	if c.Chains[0].ProteinPosition != 13 || c.Chains[1].ProteinPosition != 21 || c.Chains[1].PeptidePosition != 2 {
+		t.Errorf("ReadMzIdentML() positions = %+v", c.Chains)
+	}
+
+	if c.DecoyClass() != "TD" || c.IsIntraProtein {
+		t.Errorf("ReadMzIdentML() class = %v, intra = %v", c.DecoyClass(), c.IsIntraProtein)
+	}
+}
+
+func TestResiduePairs(t *testing.T) {
+
+	chain := func(peptide, protein string, link int) rep.CrosslinkChain {
+		return rep.CrosslinkChain{Peptide: peptide, Protein: protein, PeptidePosition: link, MappedProteins: map[string]int{protein: 1}}
+	}
+
+	csms := rep.CrosslinkEvidenceList{
+		{Spectrum: "run1.00100.00100.3", Score: 10, Chains: [2]rep.CrosslinkChain{chain("PEPKIDE", "P1", 4), chain("AKLLR", "P2", 2)}},
+		{Spectrum: "run1.00101.00101.3", Score: 12, Chains: [2]rep.CrosslinkChain{chain("AKLLR", "P2", 2), chain("PEPKIDE", "P1", 4)}},
+		{Spectrum: "run1.00102.00102.3", Score: 8, Chains: [2]rep.CrosslinkChain{chain("PEPKIDE", "P1", 4), chain("MKR", "P1", 2)}},
+	}
+
+	records := []dat.Record{
+		{PartHeader: "P1", Sequence: "MKRAAPEPKIDEGG"},
+		{PartHeader: "P2", Sequence: "GGAKLLRS"},
+	}
+
+	MapProteinPositions(csms, records)
+
+	for i := range csms {
+		csms[i].IsIntraProtein = IsIntraProtein(csms[i], "rev_")
+	}
+
+	if csms[0].Chains[0].ProteinPosition != 9 || csms[0].Chains[1].ProteinPosition != 4 || !csms[2].IsIntraProtein {
+		t.Errorf("MapProteinPositions() = %+v", csms)
+	}
+
+	var evi rep.Evidence
+	evi.Crosslinks = csms
+	evi.AssembleResiduePairs(false)
+
+	if len(evi.ResiduePairs) != 2 {
+		t.Fatalf("AssembleResiduePairs() = %d pairs, want 2", len(evi.ResiduePairs))
+	}
+
+	rp := evi.ResiduePairs[0]
+	if rp.Proteins != [2]string{"P1", "P1"} || rp.Positions != [2]int{2, 9} || rp.Residues != [2]string{"K", "K"} {
+		t.Errorf("AssembleResiduePairs() = %+v", rp)
+	}
+
+	rp = evi.ResiduePairs[1]
+	if rp.Proteins != [2]string{"P1", "P2"} || rp.Score != 12 || len(rp.Spectra) != 2 || rp.IsIntraProtein {
+		t.Errorf("AssembleResiduePairs() = %+v", rp)
+	}
+}
+
+func TestFilterCrosslinks(t *testing.T) {
+
+	csm := func(spectrum string, score float64, protein string, isDecoy bool) rep.CrosslinkEvidence {
+		a := rep.CrosslinkChain{Peptide: "PEPKIDE", Protein: "P0", PeptidePosition: 4, ProteinPosition: 10}
+		b := rep.CrosslinkChain{Peptide: "AKLLR", Protein: protein, PeptidePosition: 2, ProteinPosition: 20, IsDecoy: isDecoy}
+		return rep.CrosslinkEvidence{Spectrum: spectrum, Score: score, Chains: [2]rep.CrosslinkChain{a, b}}
+	}
+
+	// the decoy pair has three redundant spectra that fail the CSM cutoff, but a single residue pair
+	// that is accepted once the target pairs under it are counted
+	csms := rep.CrosslinkEvidenceList{
+		csm("run1.1.1.3", 10, "X", false),
+		csm("run1.2.2.3", 9, "Y", false),
+		csm("run1.3.3.3", 8, "rev_D", true),
+		csm("run1.4.4.3", 7.9, "rev_D", true),
+		csm("run1.5.5.3", 7.8, "rev_D", true),
+		csm("run1.6.6.3", 7, "W1", false),
+		csm("run1.7.7.3", 6.9, "W2", false),
+		csm("run1.8.8.3", 6.8, "W3", false),
+		csm("run1.9.9.3", 6.7, "W4", false),
+	}
+
+	evi := filterCrosslinks(csms, 0.2, false)
+
+	if len(evi.Crosslinks) != 2 {
+		t.Errorf("filterCrosslinks() = %d CSMs, want 2", len(evi.Crosslinks))
+	}
+
+	var decoy bool
+	for _, i := range evi.ResiduePairs {
+		if i.DecoyClass == "TD" {
+			decoy = true
+			if i.QValue < 0.16 || i.QValue > 0.17 || len(i.Spectra) != 3 {
+				t.Errorf("filterCrosslinks() decoy pair = %+v", i)
+			}
+		}
+	}
+
+	if !decoy || len(evi.ResiduePairs) != 7 {
+		t.Errorf("filterCrosslinks() = %d residue pairs, decoy pair found = %v", len(evi.ResiduePairs), decoy)
+	}
+}